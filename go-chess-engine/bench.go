@@ -0,0 +1,68 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go-chess-engine/chess"
+	"go-chess-engine/config"
+	"go-chess-engine/engine"
+	"os"
+	"time"
+)
+
+// benchPositions are the positions searched by "bench": openings,
+// middlegames with tactics and endgames.
+var benchPositions = []string{
+	chess.StartFEN,
+	"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+	"r1bqkb1r/pppp1ppp/2n2n2/4p2Q/2B1P3/8/PPPP1PPP/RNB1K1NR w KQkq - 4 4",
+	"rnbq1k1r/pp1Pbppp/2p5/8/2B5/8/PPP1NnPP/RNBQK2R w KQ - 1 8",
+	"r4rk1/1pp1qppp/p1np1n2/2b1p1B1/2B1P1b1/P1NP1N2/1PP1QPPP/R4RK1 w - - 0 10",
+	"8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1",
+	"8/8/1p6/p1p2k2/P1P5/1P3K2/8/8 w - - 0 1",
+	"6k1/5ppp/8/8/8/8/5PPP/3R2K1 w - - 0 1",
+}
+
+// benchDepth is the default depth of "bench". Changing it, or the
+// positions, changes the signature.
+const benchDepth = 5
+
+// benchCommand implements "bench [-depth N]": it searches every bench
+// position to a fixed depth on the configured board. The moves and node
+// counts on standard output, ending with the total "Nodes searched", only
+// change when the search does, so they serve as a signature to compare
+// commits by; the time and speed go to standard error.
+func benchCommand(fs *flag.FlagSet) func(config.Config, []string) int {
+	depth := fs.Int("depth", benchDepth, "search depth in plies")
+	return func(cfg config.Config, args []string) int {
+		if len(args) > 0 {
+			fs.Usage()
+			return exitUsage
+		}
+		// A plain engine: no book, tablebases or time limit to vary the search.
+		e := engine.New()
+		e.Depth = *depth
+		var nodes int
+		e.OnInfo = func(info engine.Info) { nodes = info.Nodes }
+
+		total := 0
+		start := time.Now()
+		for i, fen := range benchPositions {
+			nodes = 0
+			b, err := chess.NewBoard(cfg.BoardRepresentation, fen)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return exitFailure
+			}
+			m := e.FindBestMove(b)
+			fmt.Printf("Position %d/%d: bestmove %s, %d nodes\n", i+1, len(benchPositions), chess.FormatMove(m), nodes)
+			total += nodes
+		}
+		elapsed := time.Since(start)
+
+		fmt.Printf("Nodes searched: %d\n", total)
+		fmt.Fprintf(os.Stderr, "Total time (ms): %d\n", elapsed.Milliseconds())
+		fmt.Fprintf(os.Stderr, "Nodes/second: %.0f\n", float64(total)/elapsed.Seconds())
+		return exitOK
+	}
+}
@@ -0,0 +1,202 @@
+// Package book reads opening books in the Polyglot .bin format.
+package book
+
+import (
+	"encoding/binary"
+	"fmt"
+	"go-chess-engine/chess"
+	"io"
+	"math/rand"
+	"os"
+	"sort"
+)
+
+// entrySize is the size of one book record on disk, in bytes.
+const entrySize = 16
+
+// Entry is one record of a Polyglot book. Records are stored big-endian
+// and sorted by Key, so all moves of a position are next to each other.
+type Entry struct {
+	Key    uint64
+	Move   uint16
+	Weight uint16
+	Learn  uint32
+}
+
+// Book is an opening book held in memory.
+type Book struct {
+	entries []Entry
+}
+
+// Open loads a Polyglot book from a file.
+func Open(path string) (*Book, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Read(f)
+}
+
+// Read loads a Polyglot book from r.
+func Read(r io.Reader) (*Book, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(data)%entrySize != 0 {
+		return nil, fmt.Errorf("book: size %d is not a multiple of %d bytes", len(data), entrySize)
+	}
+
+	bk := &Book{entries: make([]Entry, len(data)/entrySize)}
+	for i := range bk.entries {
+		rec := data[i*entrySize:]
+		bk.entries[i] = Entry{
+			Key:    binary.BigEndian.Uint64(rec[0:8]),
+			Move:   binary.BigEndian.Uint16(rec[8:10]),
+			Weight: binary.BigEndian.Uint16(rec[10:12]),
+			Learn:  binary.BigEndian.Uint32(rec[12:16]),
+		}
+	}
+	// Books should already be sorted, but don't rely on the tool that wrote it.
+	sort.SliceStable(bk.entries, func(i, j int) bool { return bk.entries[i].Key < bk.entries[j].Key })
+	return bk, nil
+}
+
+// Len returns the number of entries in the book.
+func (bk *Book) Len() int {
+	return len(bk.entries)
+}
+
+// Entries returns all book entries stored for the position on b.
+func (bk *Book) Entries(b chess.Board) []Entry {
+	key := Key(b)
+	i := sort.Search(len(bk.entries), func(i int) bool { return bk.entries[i].Key >= key })
+	j := i
+	for j < len(bk.entries) && bk.entries[j].Key == key {
+		j++
+	}
+	return bk.entries[i:j]
+}
+
+// Pick chooses a book move for the position on b at random, weighted by the
+// entry weights. It returns false if the position is not in the book.
+func (bk *Book) Pick(b chess.Board) (chess.Move, bool) {
+	var moves []chess.Move
+	var weights []int
+	total := 0
+	for _, e := range bk.Entries(b) {
+		m, ok := DecodeMove(b, e.Move)
+		if !ok || e.Weight == 0 {
+			continue
+		}
+		moves = append(moves, m)
+		weights = append(weights, int(e.Weight))
+		total += int(e.Weight)
+	}
+	if total == 0 {
+		return chess.Move{}, false
+	}
+
+	r := rand.Intn(total)
+	for i, w := range weights {
+		if r < w {
+			return moves[i], true
+		}
+		r -= w
+	}
+	return moves[len(moves)-1], true
+}
+
+// DecodeMove converts a Polyglot move into the matching legal move on b.
+//
+// Polyglot packs a move as to-square (bits 0-5), from-square (bits 6-11)
+// and promotion piece (bits 12-14: 1=knight ... 4=queen). Castling is
+// written as the king capturing its own rook, e.g. e1h1 for O-O, as on a
+// Chess960 board.
+func DecodeMove(b chess.Board, pm uint16) (chess.Move, bool) {
+	to := int(pm & 0x3f)
+	from := int(pm>>6) & 0x3f
+	promo := int(pm>>12) & 0x7
+
+	king := b.PieceAt(from)
+	if (king == chess.WhiteKing || king == chess.BlackKing) && !b.Chess960() {
+		switch {
+		case from == 4 && to == 7, from == 60 && to == 63:
+			to = from + 2
+		case from == 4 && to == 0, from == 60 && to == 56:
+			to = from - 2
+		}
+	}
+
+	for _, m := range b.GenerateLegalMoves() {
+		if m.From != from || m.To != to {
+			continue
+		}
+		if promotionCode(m.Promotion) == promo {
+			return m, true
+		}
+	}
+	return chess.Move{}, false
+}
+
+func promotionCode(p chess.Piece) int {
+	switch p {
+	case chess.WhiteKnight, chess.BlackKnight:
+		return 1
+	case chess.WhiteBishop, chess.BlackBishop:
+		return 2
+	case chess.WhiteRook, chess.BlackRook:
+		return 3
+	case chess.WhiteQueen, chess.BlackQueen:
+		return 4
+	}
+	return 0
+}
+
+// Key computes the Polyglot hash of the position on b.
+func Key(b chess.Board) uint64 {
+	var key uint64
+
+	for sq := 0; sq < 64; sq++ {
+		piece := b.PieceAt(sq)
+		if piece == chess.Empty {
+			continue
+		}
+		key ^= random64[64*pieceKind(piece)+sq]
+	}
+
+	pos := b.Position()
+	for i, right := range []chess.CastlingRights{chess.WhiteKingside, chess.WhiteQueenside, chess.BlackKingside, chess.BlackQueenside} {
+		if pos.Castling&right != 0 {
+			key ^= random64[768+i]
+		}
+	}
+
+	// The en passant file only counts if a pawn can actually capture there.
+	if pos.EnPassant != chess.NoSquare {
+		file := pos.EnPassant % 8
+		pawn, rank := chess.WhitePawn, 4
+		if b.SideToMove() == chess.Black {
+			pawn, rank = chess.BlackPawn, 3
+		}
+		if (file > 0 && b.PieceAt(rank*8+file-1) == pawn) ||
+			(file < 7 && b.PieceAt(rank*8+file+1) == pawn) {
+			key ^= random64[772+file]
+		}
+	}
+
+	if b.SideToMove() == chess.White {
+		key ^= random64[780]
+	}
+	return key
+}
+
+// pieceKind maps a piece to the Polyglot numbering, which interleaves the
+// colours: black pawn 0, white pawn 1, black knight 2, ... white king 11.
+func pieceKind(p chess.Piece) int {
+	if p.Color() == chess.White {
+		return 2*int(p-chess.WhitePawn) + 1
+	}
+	return 2 * int(p-chess.BlackPawn)
+}
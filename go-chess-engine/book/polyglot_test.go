@@ -0,0 +1,147 @@
+package book
+
+import (
+	"bytes"
+	"encoding/binary"
+	"go-chess-engine/chess"
+	"strings"
+	"testing"
+)
+
+// The reference keys published with the Polyglot book format.
+var keyTests = []struct {
+	moves string
+	key   uint64
+}{
+	{"", 0x463b96181691fc9c},
+	{"e2e4", 0x823c9b50fd114196},
+	{"e2e4 d7d5", 0x0756b94461c50fb0},
+	{"e2e4 d7d5 e4e5", 0x662fafb965db29d4},
+	{"e2e4 d7d5 e4e5 f7f5", 0x22a48b5a8e47ff78},
+	{"e2e4 d7d5 e4e5 f7f5 e1e2", 0x652a607ca3f242c1},
+	{"e2e4 d7d5 e4e5 f7f5 e1e2 e8f7", 0x00fdd303c946bdd9},
+	{"a2a4 b7b5 h2h4 b5b4 c2c4", 0x3c8123ea7b067637},
+	{"a2a4 b7b5 h2h4 b5b4 c2c4 b4c3 a1a3", 0x5c3f9b829b279560},
+}
+
+func TestKey(t *testing.T) {
+	for _, rep := range []string{"array", "bitboard"} {
+		for _, tt := range keyTests {
+			b, err := chess.NewBoard(rep, chess.StartFEN)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, s := range strings.Fields(tt.moves) {
+				b.ApplyMove(chess.ParseMove(s))
+			}
+			if got := Key(b); got != tt.key {
+				t.Errorf("%s: Key after %q = %016x, want %016x", rep, tt.moves, got, tt.key)
+			}
+		}
+	}
+}
+
+// encodeMove packs a move the way Polyglot does.
+func encodeMove(from, to string, promo int) uint16 {
+	return uint16(promo<<12 | square(from)<<6 | square(to))
+}
+
+func square(name string) int {
+	return int(name[1]-'1')*8 + int(name[0]-'a')
+}
+
+// writeBook encodes entries in the .bin format, deliberately unsorted.
+func writeBook(entries []Entry) []byte {
+	var buf bytes.Buffer
+	for i := len(entries) - 1; i >= 0; i-- {
+		binary.Write(&buf, binary.BigEndian, entries[i])
+	}
+	return buf.Bytes()
+}
+
+func TestReadAndPick(t *testing.T) {
+	start := chess.NewArrayBoard(chess.StartFEN)
+	afterE4 := chess.NewArrayBoard(chess.StartFEN)
+	afterE4.ApplyMove(chess.ParseMove("e2e4"))
+
+	data := writeBook([]Entry{
+		{Key: Key(start), Move: encodeMove("e2", "e4", 0), Weight: 1},
+		{Key: Key(start), Move: encodeMove("d2", "d4", 0), Weight: 0},
+		{Key: Key(afterE4), Move: encodeMove("c7", "c5", 0), Weight: 10},
+	})
+	bk, err := Read(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bk.Len() != 3 {
+		t.Errorf("Len = %d, want 3", bk.Len())
+	}
+	if n := len(bk.Entries(start)); n != 2 {
+		t.Errorf("start position has %d entries, want 2", n)
+	}
+
+	// Entries with weight 0 are never played.
+	for i := 0; i < 20; i++ {
+		if m, ok := bk.Pick(start); !ok || chess.FormatMove(m) != "e2e4" {
+			t.Fatalf("Pick(start) = %s, %v, want e2e4", chess.FormatMove(m), ok)
+		}
+	}
+	if m, ok := bk.Pick(afterE4); !ok || chess.FormatMove(m) != "c7c5" {
+		t.Errorf("Pick(1.e4) = %s, %v, want c7c5", chess.FormatMove(m), ok)
+	}
+	afterE4.ApplyMove(chess.ParseMove("c7c5"))
+	if _, ok := bk.Pick(afterE4); ok {
+		t.Error("Pick found a move for a position that is not in the book")
+	}
+
+	if _, err := Read(bytes.NewReader(data[:len(data)-1])); err == nil {
+		t.Error("Read accepted a truncated book")
+	}
+}
+
+func TestDecodeMove(t *testing.T) {
+	tests := []struct {
+		fen      string
+		chess960 bool
+		move     uint16
+		want     string // "" if the move is not legal
+	}{
+		{chess.StartFEN, false, encodeMove("g1", "f3", 0), "g1f3"},
+		{chess.StartFEN, false, encodeMove("e2", "e5", 0), ""},
+		// Castling is written as the king taking its own rook.
+		{"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", false, encodeMove("e1", "h1", 0), "e1g1"},
+		{"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", false, encodeMove("e1", "a1", 0), "e1c1"},
+		{"r3k2r/8/8/8/8/8/8/R3K2R b KQkq - 0 1", false, encodeMove("e8", "h8", 0), "e8g8"},
+		{"r3k2r/8/8/8/8/8/8/R3K2R b KQkq - 0 1", false, encodeMove("e8", "a8", 0), "e8c8"},
+		// A king that merely moves to a corner is not castling.
+		{"8/8/8/8/8/8/6k1/4K2R w K - 0 1", false, encodeMove("e1", "h1", 0), ""},
+		{"k7/8/8/8/8/8/8/4K3 w - - 0 1", false, encodeMove("e1", "f1", 0), "e1f1"},
+		// In Chess960 the king-takes-rook notation is used as it is.
+		{"r3k2r/8/8/8/8/8/8/1R2K1R1 w GB - 0 1", true, encodeMove("e1", "g1", 0), "e1g1"},
+		{"r3k2r/8/8/8/8/8/8/1R2K1R1 w GB - 0 1", true, encodeMove("e1", "b1", 0), "e1b1"},
+		// Promotions: 1 knight, 2 bishop, 3 rook, 4 queen.
+		{"8/P6k/8/8/8/8/8/K7 w - - 0 1", false, encodeMove("a7", "a8", 4), "a7a8q"},
+		{"8/P6k/8/8/8/8/8/K7 w - - 0 1", false, encodeMove("a7", "a8", 1), "a7a8n"},
+		{"8/P6k/8/8/8/8/8/K7 w - - 0 1", false, encodeMove("a7", "a8", 0), ""},
+		{"k7/8/8/8/8/8/1p6/2N4K b - - 0 1", false, encodeMove("b2", "c1", 3), "b2c1r"},
+		{"k7/8/8/8/8/8/1p6/2N4K b - - 0 1", false, encodeMove("b2", "c1", 2), "b2c1b"},
+	}
+	for _, tt := range tests {
+		p := chess.MustParseFEN(tt.fen)
+		p.Chess960 = tt.chess960
+		for _, rep := range []string{"array", "bitboard"} {
+			b, err := chess.NewBoardFromPosition(rep, p)
+			if err != nil {
+				t.Fatal(err)
+			}
+			m, ok := DecodeMove(b, tt.move)
+			got := ""
+			if ok {
+				got = chess.FormatMove(m)
+			}
+			if got != tt.want {
+				t.Errorf("%s: DecodeMove(%s, %04x) = %q, want %q", rep, tt.fen, tt.move, got, tt.want)
+			}
+		}
+	}
+}
@@ -1,91 +1,63 @@
 package chess
 
-import "strings"
-
 // ArrayBoard is our original implementation using a simple array.
 type ArrayBoard struct {
-	Board           [64]Piece
-	sideToMove      Color
+	Board [64]Piece
+	gameState
 	whiteKingSquare int
 	blackKingSquare int
-	// New fields for castling rights
-	whiteKingsideCastle  bool
-	whiteQueensideCastle bool
-	blackKingsideCastle  bool
-	blackQueensideCastle bool
 }
 
 // NewArrayBoard creates a new board from a FEN string.
 func NewArrayBoard(fen string) *ArrayBoard {
-	b := &ArrayBoard{}
-	fields := strings.Fields(fen)
-
-	// 1. Piece placement (and find kings)
-	rank, file := 7, 0
-	for _, char := range fields[0] {
-		if char == '/' {
-			rank--
-			file = 0
-		} else if char >= '1' && char <= '8' {
-			file += int(char - '0')
-		} else {
-			square := rank*8 + file
-			piece := pieceFromChar(char)
-			b.Board[square] = piece
-			if piece == WhiteKing {
-				b.whiteKingSquare = square
-			} else if piece == BlackKing {
-				b.blackKingSquare = square
-			}
-			file++
-		}
-	}
-
-	// 2. Side to move
-	if fields[1] == "w" {
-		b.sideToMove = White
-	} else {
-		b.sideToMove = Black
-	}
+	return NewArrayBoardFromPosition(MustParseFEN(fen))
+}
 
-	// 3. Parse castling rights
-	if len(fields) > 2 {
-		for _, char := range fields[2] {
-			switch char {
-			case 'K':
-				b.whiteKingsideCastle = true
-			case 'Q':
-				b.whiteQueensideCastle = true
-			case 'k':
-				b.blackKingsideCastle = true
-			case 'q':
-				b.blackQueensideCastle = true
-			}
+// NewArrayBoardFromPosition creates a new board holding p.
+func NewArrayBoardFromPosition(p Position) *ArrayBoard {
+	b := &ArrayBoard{Board: p.Pieces, gameState: stateOf(p)}
+	for sq, piece := range p.Pieces {
+		if piece == WhiteKing {
+			b.whiteKingSquare = sq
+		} else if piece == BlackKing {
+			b.blackKingSquare = sq
 		}
 	}
-	// Note: en passant and move counters are still ignored for now.
 	return b
 }
 
 // --- Methods to satisfy the Board interface ---
 
-// Replace your existing ApplyMove with this new version.
 func (b *ArrayBoard) ApplyMove(m Move) {
 	piece := b.Board[m.From]
+	captured := b.Board[m.To]
 
 	// --- Handle the actual move ---
-	// If it's a castling move (king moving two squares), we must also move the rook.
-	if (piece == WhiteKing || piece == BlackKing) && dist(m.From%8, m.To%8) == 2 {
-		// Kingside castle
-		if m.To%8 > m.From%8 {
-			rookFrom, rookTo := m.To+1, m.To-1
-			b.Board[rookTo] = b.Board[rookFrom]
-			b.Board[rookFrom] = Empty
-		} else { // Queenside castle
-			rookFrom, rookTo := m.To-2, m.To+1
-			b.Board[rookTo] = b.Board[rookFrom]
-			b.Board[rookFrom] = Empty
+	// A castle moves the king and the rook to their castled squares. Both
+	// are lifted first, as in Chess960 either may land where the other stood.
+	if right, ok := b.castleRight(m, piece); ok {
+		kingTo, rookTo := castleTargets(right)
+		rookFrom := b.castlingRooks[right]
+		rook := b.Board[rookFrom]
+		b.Board[m.From], b.Board[rookFrom] = Empty, Empty
+		b.Board[kingTo], b.Board[rookTo] = piece, rook
+		if piece == WhiteKing {
+			b.whiteKingSquare = kingTo
+		} else {
+			b.blackKingSquare = kingTo
+		}
+		b.gameState.update(m, piece, Empty)
+		return
+	}
+
+	// An en passant capture takes the pawn beside the destination square.
+	if (piece == WhitePawn || piece == BlackPawn) && m.To == b.enPassant && m.From%8 != m.To%8 {
+		victim := m.To - 8
+		if piece == BlackPawn {
+			victim = m.To + 8
 		}
+		captured = b.Board[victim]
+		b.Board[victim] = Empty
 	}
 
 	// Standard piece placement (including promotion)
@@ -105,55 +77,47 @@ func (b *ArrayBoard) ApplyMove(m Move) {
 		b.blackKingSquare = m.To
 	}
 
-	// 2. Revoke castling rights if a king or rook moves for the first time
-	if piece == WhiteKing {
-		b.whiteKingsideCastle = false
-		b.whiteQueensideCastle = false
-	} else if piece == BlackKing {
-		b.blackKingsideCastle = false
-		b.blackQueensideCastle = false
-	} else if m.From == 0 { // a1 rook
-		b.whiteQueensideCastle = false
-	} else if m.From == 7 { // h1 rook
-		b.whiteKingsideCastle = false
-	} else if m.From == 56 { // a8 rook
-		b.blackQueensideCastle = false
-	} else if m.From == 63 { // h8 rook
-		b.blackKingsideCastle = false
-	}
-
-	// 3. Switch side to move
-	if b.sideToMove == White {
-		b.sideToMove = Black
-	} else {
-		b.sideToMove = White
-	}
+	// 2. Castling rights, en passant square, clocks and side to move
+	b.gameState.update(m, piece, captured)
 }
 
 func (b *ArrayBoard) SideToMove() Color {
 	return b.sideToMove
 }
 
+func (b *ArrayBoard) PieceAt(sq int) Piece {
+	return b.Board[sq]
+}
+
+func (b *ArrayBoard) Clone() Board {
+	c := *b
+	return &c
+}
+
+func (b *ArrayBoard) Position() Position {
+	p := Position{Pieces: b.Board}
+	b.gameState.fill(&p)
+	return p
+}
+
+func (b *ArrayBoard) ToFEN() string {
+	return b.Position().FEN()
+}
+
 func (b *ArrayBoard) IsCheckmate() bool {
-	if len(b.GenerateLegalMoves()) == 0 {
-		kingSq := b.blackKingSquare
-		if b.sideToMove == White {
-			kingSq = b.whiteKingSquare
-		}
-		return b.isSquareAttacked(kingSq, oppositeColor(b.sideToMove))
-	}
-	return false
+	return len(b.GenerateLegalMoves()) == 0 && b.InCheck()
 }
 
 func (b *ArrayBoard) IsStalemate() bool {
-	if len(b.GenerateLegalMoves()) == 0 {
-		kingSq := b.blackKingSquare
-		if b.sideToMove == White {
-			kingSq = b.whiteKingSquare
-		}
-		return !b.isSquareAttacked(kingSq, oppositeColor(b.sideToMove))
+	return len(b.GenerateLegalMoves()) == 0 && !b.InCheck()
+}
+
+func (b *ArrayBoard) InCheck() bool {
+	kingSq := b.blackKingSquare
+	if b.sideToMove == White {
+		kingSq = b.whiteKingSquare
 	}
-	return false
+	return b.isSquareAttacked(kingSq, oppositeColor(b.sideToMove))
 }
 
 // --- Move Generation Logic (Moved from movegen.go) ---
@@ -184,6 +148,15 @@ func (b *ArrayBoard) GenerateLegalMoves() []Move {
 	return legalMoves
 }
 
+func (b *ArrayBoard) PseudoLegalMoves(c Color) []Move {
+	if c == b.sideToMove {
+		return b.generatePseudoLegalMoves()
+	}
+	other := *b
+	other.sideToMove, other.enPassant = c, NoSquare
+	return other.generatePseudoLegalMoves()
+}
+
 func (b *ArrayBoard) isSquareAttacked(sq int, byColor Color) bool {
 	if byColor == White {
 		if sq/8 > 0 {
@@ -289,24 +262,15 @@ func (b *ArrayBoard) generatePseudoLegalMoves() []Move {
 
 func (b *ArrayBoard) generateSlidingMoves(from int, directions []int) []Move {
 	var moves []Move
-	isRook := b.Board[from] == WhiteRook || b.Board[from] == BlackRook
 	for _, dir := range directions {
 		prevSquare := from
 		for {
 			to := prevSquare + dir
-			if to < 0 || to >= 64 {
+			// Every step changes the file by at most one; more means the
+			// move wrapped around the edge of the board.
+			if to < 0 || to >= 64 || dist(to%8, prevSquare%8) > 1 {
 				break
 			}
-			rankDist, fileDist := dist(to/8, prevSquare/8), dist(to%8, prevSquare%8)
-			if isRook {
-				if rankDist > 1 || fileDist > 1 || (rankDist == 1 && fileDist == 1) {
-					break
-				}
-			} else {
-				if rankDist != 1 || fileDist != 1 {
-					break
-				}
-			}
 			targetPiece := b.Board[to]
 			if targetPiece == Empty {
 				moves = append(moves, Move{From: from, To: to})
@@ -353,6 +317,8 @@ func (b *ArrayBoard) generatePawnMoves(from int) []Move {
 		if targetPiece != Empty && targetPiece.Color() != b.sideToMove {
 			isPromotion := (to / 8) == promotionRank
 			b.addPawnMove(&moves, from, to, isPromotion)
+		} else if targetPiece == Empty && to == b.enPassant {
+			b.addPawnMove(&moves, from, to, false)
 		}
 	}
 	return moves
@@ -412,73 +378,14 @@ func (b *ArrayBoard) generateKingMoves(from int) []Move {
 
 	// 2. Castling Moves
 	opponentColor := oppositeColor(b.sideToMove)
-	// Don't generate castling moves if the king is currently in check
-	if b.isSquareAttacked(from, opponentColor) {
-		return moves
-	}
-
-	if b.sideToMove == White {
-		// Kingside (O-O)
-		if b.whiteKingsideCastle && b.Board[5] == Empty && b.Board[6] == Empty {
-			if !b.isSquareAttacked(5, opponentColor) && !b.isSquareAttacked(6, opponentColor) {
-				moves = append(moves, Move{From: from, To: 6})
-			}
-		}
-		// Queenside (O-O-O)
-		if b.whiteQueensideCastle && b.Board[1] == Empty && b.Board[2] == Empty && b.Board[3] == Empty {
-			if !b.isSquareAttacked(2, opponentColor) && !b.isSquareAttacked(3, opponentColor) {
-				moves = append(moves, Move{From: from, To: 2})
-			}
-		}
-	} else { // Black's turn
-		// Kingside (O-O)
-		if b.blackKingsideCastle && b.Board[61] == Empty && b.Board[62] == Empty {
-			if !b.isSquareAttacked(61, opponentColor) && !b.isSquareAttacked(62, opponentColor) {
-				moves = append(moves, Move{From: from, To: 62})
-			}
-		}
-		// Queenside (O-O-O)
-		if b.blackQueensideCastle && b.Board[57] == Empty && b.Board[58] == Empty && b.Board[59] == Empty {
-			if !b.isSquareAttacked(58, opponentColor) && !b.isSquareAttacked(59, opponentColor) {
-				moves = append(moves, Move{From: from, To: 58})
-			}
-		}
-	}
+	b.castlingMoves(&moves, from,
+		func(sq int) bool { return b.Board[sq] == Empty },
+		func(sq int) bool { return b.isSquareAttacked(sq, opponentColor) })
 	return moves
 }
 
 // --- Helper functions ---
 
-func pieceFromChar(c rune) Piece {
-	switch c {
-	case 'p':
-		return BlackPawn
-	case 'n':
-		return BlackKnight
-	case 'b':
-		return BlackBishop
-	case 'r':
-		return BlackRook
-	case 'q':
-		return BlackQueen
-	case 'k':
-		return BlackKing
-	case 'P':
-		return WhitePawn
-	case 'N':
-		return WhiteKnight
-	case 'B':
-		return WhiteBishop
-	case 'R':
-		return WhiteRook
-	case 'Q':
-		return WhiteQueen
-	case 'K':
-		return WhiteKing
-	}
-	return Empty
-}
-
 func oppositeColor(c Color) Color {
 	if c == White {
 		return Black
@@ -1,49 +1,34 @@
 package chess
 
-import (
-	"strings"
-)
-
 // Bitboard is the new implementation using bitboards.
 type Bitboard struct {
-	byPiece         [13]bitboard
-	byColor         [2]bitboard
-	sideToMove      Color
+	byPiece [13]bitboard
+	byColor [2]bitboard
+	gameState
 	whiteKingSquare int
 	blackKingSquare int
 }
 
 // NewBitboard creates a bitboard representation from a FEN string.
 func NewBitboard(fen string) *Bitboard {
-	b := &Bitboard{}
-	fields := strings.Fields(fen)
-	rank, file := 7, 0
-	for _, char := range fields[0] {
-		if char == '/' {
-			rank--
-			file = 0
-		} else if char >= '1' && char <= '8' {
-			file += int(char - '0')
-		} else {
-			sq := rank*8 + file
-			piece := pieceFromChar(char)
-			b.byPiece[piece].setBit(sq)
-			if piece.Color() != NoColor {
-				b.byColor[piece.Color()].setBit(sq)
-			}
-			if piece == WhiteKing {
-				b.whiteKingSquare = sq
-			}
-			if piece == BlackKing {
-				b.blackKingSquare = sq
-			}
-			file++
+	return NewBitboardFromPosition(MustParseFEN(fen))
+}
+
+// NewBitboardFromPosition creates a bitboard holding p.
+func NewBitboardFromPosition(p Position) *Bitboard {
+	b := &Bitboard{gameState: stateOf(p)}
+	for sq, piece := range p.Pieces {
+		if piece == Empty {
+			continue
+		}
+		b.byPiece[piece].setBit(sq)
+		b.byColor[piece.Color()].setBit(sq)
+		if piece == WhiteKing {
+			b.whiteKingSquare = sq
+		}
+		if piece == BlackKing {
+			b.blackKingSquare = sq
 		}
-	}
-	if fields[1] == "w" {
-		b.sideToMove = White
-	} else {
-		b.sideToMove = Black
 	}
 	return b
 }
@@ -52,6 +37,10 @@ func NewBitboard(fen string) *Bitboard {
 
 func (b *Bitboard) ApplyMove(m Move) {
 	movingPiece, _ := b.pieceAt(m.From)
+	if right, ok := b.castleRight(m, movingPiece); ok {
+		b.applyCastle(m, movingPiece, right)
+		return
+	}
 	moveMask := bitboard((1 << m.From) | (1 << m.To))
 	isCapture := b.byColor[oppositeColor(b.sideToMove)].getBit(m.To)
 	var capturedPiece Piece
@@ -64,6 +53,15 @@ func (b *Bitboard) ApplyMove(m Move) {
 		b.byPiece[capturedPiece].clearBit(m.To)
 		b.byColor[oppositeColor(b.sideToMove)].clearBit(m.To)
 	}
+	if (movingPiece == WhitePawn || movingPiece == BlackPawn) && m.To == b.enPassant && m.From%8 != m.To%8 {
+		victim := m.To - 8
+		if movingPiece == BlackPawn {
+			victim = m.To + 8
+		}
+		capturedPiece, _ = b.pieceAt(victim)
+		b.byPiece[capturedPiece].clearBit(victim)
+		b.byColor[oppositeColor(b.sideToMove)].clearBit(victim)
+	}
 	if m.Promotion != Empty {
 		b.byPiece[movingPiece].clearBit(m.To)
 		b.byPiece[m.Promotion].setBit(m.To)
@@ -74,7 +72,30 @@ func (b *Bitboard) ApplyMove(m Move) {
 	if movingPiece == BlackKing {
 		b.blackKingSquare = m.To
 	}
-	b.sideToMove = oppositeColor(b.sideToMove)
+	b.gameState.update(m, movingPiece, capturedPiece)
+}
+
+// applyCastle plays the castle m of king with the given right. The king
+// and rook are removed before being placed, as in Chess960 either may land
+// where the other stood.
+func (b *Bitboard) applyCastle(m Move, king Piece, right int) {
+	kingTo, rookTo := castleTargets(right)
+	rookFrom := b.castlingRooks[right]
+	rook, _ := b.pieceAt(rookFrom)
+	b.byPiece[king].clearBit(m.From)
+	b.byPiece[rook].clearBit(rookFrom)
+	b.byColor[b.sideToMove].clearBit(m.From)
+	b.byColor[b.sideToMove].clearBit(rookFrom)
+	b.byPiece[king].setBit(kingTo)
+	b.byPiece[rook].setBit(rookTo)
+	b.byColor[b.sideToMove].setBit(kingTo)
+	b.byColor[b.sideToMove].setBit(rookTo)
+	if king == WhiteKing {
+		b.whiteKingSquare = kingTo
+	} else {
+		b.blackKingSquare = kingTo
+	}
+	b.gameState.update(m, king, Empty)
 }
 
 func (b *Bitboard) GenerateLegalMoves() []Move {
@@ -96,9 +117,38 @@ func (b *Bitboard) GenerateLegalMoves() []Move {
 	return legalMoves
 }
 
+func (b *Bitboard) PseudoLegalMoves(c Color) []Move {
+	if c == b.sideToMove {
+		return b.generatePseudoLegalMoves()
+	}
+	other := *b
+	other.sideToMove, other.enPassant = c, NoSquare
+	return other.generatePseudoLegalMoves()
+}
+
 func (b *Bitboard) SideToMove() Color { return b.sideToMove }
+func (b *Bitboard) PieceAt(sq int) Piece {
+	p, _ := b.pieceAt(sq)
+	return p
+}
+func (b *Bitboard) Clone() Board {
+	c := *b
+	return &c
+}
+
+func (b *Bitboard) Position() Position {
+	var p Position
+	for sq := 0; sq < 64; sq++ {
+		p.Pieces[sq], _ = b.pieceAt(sq)
+	}
+	b.gameState.fill(&p)
+	return p
+}
+
+func (b *Bitboard) ToFEN() string     { return b.Position().FEN() }
 func (b *Bitboard) IsCheckmate() bool { return len(b.GenerateLegalMoves()) == 0 && b.isKingInCheck() }
 func (b *Bitboard) IsStalemate() bool { return len(b.GenerateLegalMoves()) == 0 && !b.isKingInCheck() }
+func (b *Bitboard) InCheck() bool     { return b.isKingInCheck() }
 func (b *Bitboard) isKingInCheck() bool {
 	kingSq := b.blackKingSquare
 	if b.sideToMove == White {
@@ -220,7 +270,7 @@ func (b *Bitboard) generatePseudoLegalMoves() []Move {
 	b.generatePawnMoves(&moves, ^occupied, enemyPieces)
 	b.generateKnightMoves(&moves, myPieces)
 	b.generateSlidingMoves(&moves, occupied, myPieces)
-	b.generateKingMoves(&moves, myPieces)
+	b.generateKingMoves(&moves, myPieces, occupied)
 	return moves
 }
 
@@ -262,7 +312,7 @@ func (b *Bitboard) generateSlidingMoves(moves *[]Move, occupied, myPieces bitboa
 		for _, dir := range bishopDirections {
 			for i := 1; ; i++ {
 				to := from + dir*i
-				if to < 0 || to >= 64 || dist(to%8, (to-dir*i)%8) != i {
+				if to < 0 || to >= 64 || dist(to%8, (to-dir)%8) != 1 {
 					break
 				}
 				if myPieces.getBit(to) {
@@ -278,7 +328,7 @@ func (b *Bitboard) generateSlidingMoves(moves *[]Move, occupied, myPieces bitboa
 	}
 }
 
-func (b *Bitboard) generateKingMoves(moves *[]Move, myPieces bitboard) {
+func (b *Bitboard) generateKingMoves(moves *[]Move, myPieces, occupied bitboard) {
 	from := b.whiteKingSquare
 	if b.sideToMove == Black {
 		from = b.blackKingSquare
@@ -291,10 +341,17 @@ func (b *Bitboard) generateKingMoves(moves *[]Move, myPieces bitboard) {
 			}
 		}
 	}
+	opponent := oppositeColor(b.sideToMove)
+	b.castlingMoves(moves, from,
+		func(sq int) bool { return !occupied.getBit(sq) },
+		func(sq int) bool { return b.isSquareAttacked(sq, opponent) })
 }
 
 func (b *Bitboard) generatePawnMoves(moves *[]Move, empty, enemy bitboard) {
-	// ... (unchanged)
+	// A pawn may capture onto the en passant square as if a piece stood there.
+	if b.enPassant != NoSquare {
+		enemy.setBit(b.enPassant)
+	}
 	var pawns, singlePush, doublePush bitboard
 	if b.sideToMove == White {
 		pawns = b.byPiece[WhitePawn]
@@ -302,24 +359,24 @@ func (b *Bitboard) generatePawnMoves(moves *[]Move, empty, enemy bitboard) {
 		doublePush = ((singlePush & Rank3) << 8) & empty
 		for singlePush != 0 {
 			to := singlePush.lsb()
-			*moves = append(*moves, Move{From: to - 8, To: to})
+			b.addPawnMove(moves, to-8, to)
 			singlePush.clearBit(to)
 		}
 		for doublePush != 0 {
 			to := doublePush.lsb()
-			*moves = append(*moves, Move{From: to - 16, To: to})
+			b.addPawnMove(moves, to-16, to)
 			doublePush.clearBit(to)
 		}
 		capturesWest := (pawns << 7) & enemy & ^FileH
 		capturesEast := (pawns << 9) & enemy & ^FileA
 		for capturesWest != 0 {
 			to := capturesWest.lsb()
-			*moves = append(*moves, Move{From: to - 7, To: to})
+			b.addPawnMove(moves, to-7, to)
 			capturesWest.clearBit(to)
 		}
 		for capturesEast != 0 {
 			to := capturesEast.lsb()
-			*moves = append(*moves, Move{From: to - 9, To: to})
+			b.addPawnMove(moves, to-9, to)
 			capturesEast.clearBit(to)
 		}
 	} else {
@@ -328,29 +385,45 @@ func (b *Bitboard) generatePawnMoves(moves *[]Move, empty, enemy bitboard) {
 		doublePush = ((singlePush & Rank6) >> 8) & empty
 		for singlePush != 0 {
 			to := singlePush.lsb()
-			*moves = append(*moves, Move{From: to + 8, To: to})
+			b.addPawnMove(moves, to+8, to)
 			singlePush.clearBit(to)
 		}
 		for doublePush != 0 {
 			to := doublePush.lsb()
-			*moves = append(*moves, Move{From: to + 16, To: to})
+			b.addPawnMove(moves, to+16, to)
 			doublePush.clearBit(to)
 		}
 		capturesWest := (pawns >> 9) & enemy & ^FileH
 		capturesEast := (pawns >> 7) & enemy & ^FileA
 		for capturesWest != 0 {
 			to := capturesWest.lsb()
-			*moves = append(*moves, Move{From: to + 9, To: to})
+			b.addPawnMove(moves, to+9, to)
 			capturesWest.clearBit(to)
 		}
 		for capturesEast != 0 {
 			to := capturesEast.lsb()
-			*moves = append(*moves, Move{From: to + 7, To: to})
+			b.addPawnMove(moves, to+7, to)
 			capturesEast.clearBit(to)
 		}
 	}
 }
 
+// addPawnMove appends the pawn move from-to, as the four possible
+// promotions when it reaches the last rank.
+func (b *Bitboard) addPawnMove(moves *[]Move, from, to int) {
+	if to/8 != 0 && to/8 != 7 {
+		*moves = append(*moves, Move{From: from, To: to})
+		return
+	}
+	promotions := []Piece{WhiteQueen, WhiteRook, WhiteBishop, WhiteKnight}
+	if b.sideToMove == Black {
+		promotions = []Piece{BlackQueen, BlackRook, BlackBishop, BlackKnight}
+	}
+	for _, p := range promotions {
+		*moves = append(*moves, Move{From: from, To: to, Promotion: p})
+	}
+}
+
 func (b *Bitboard) generateKnightMoves(moves *[]Move, myPieces bitboard) {
 	// ... (unchanged)
 	knights := b.byPiece[WhiteKnight]
@@ -1,123 +1,59 @@
 package chess
 
-import "strings"
-
-// Board is an interface that defines the behavior of a chess board representation.
+// Board is an interface that defines the behavior of a chess board
+// representation. ArrayBoard and Bitboard implement it; code outside this
+// package, such as the engine, the book and the tablebases, only sees a
+// position through these methods.
 type Board interface {
+	// ApplyMove plays m, which must be legal in the position.
 	ApplyMove(m Move)
+	// GenerateLegalMoves returns every legal move of the side to move.
 	GenerateLegalMoves() []Move
+	// PseudoLegalMoves returns the moves c's pieces could make if it were
+	// c's turn, including those that leave c's king in check. Only the
+	// side to move can capture en passant.
+	PseudoLegalMoves(c Color) []Move
+	// SideToMove returns the color whose turn it is.
 	SideToMove() Color
+	// IsCheckmate reports whether the side to move is in check and has no
+	// legal move.
 	IsCheckmate() bool
+	// IsStalemate reports whether the side to move is not in check and has
+	// no legal move.
 	IsStalemate() bool
-	// We will add more methods here as needed, e.g., ToFEN()
+	// InCheck reports whether the side to move is in check.
+	InCheck() bool
+	// PieceAt returns the piece standing on a square (Empty if none).
+	PieceAt(sq int) Piece
+	// Clone returns an independent copy of the board, so a search can
+	// try moves without disturbing the original position.
+	Clone() Board
+	// CastlingRights returns the castling moves still allowed to either side.
+	CastlingRights() CastlingRights
+	// EnPassantSquare is the square skipped by a double pawn step on the
+	// last move, or NoSquare.
+	EnPassantSquare() int
+	// HalfmoveClock counts the plies since the last capture or pawn move.
+	HalfmoveClock() int
+	// FullmoveNumber starts at 1 and grows after every Black move.
+	FullmoveNumber() int
+	// Captured returns the piece the last move captured, Empty if none or
+	// if no move has been applied.
+	Captured() Piece
+	// Chess960 reports whether castling moves are written as the king
+	// capturing its own rook, as in Chess960.
+	Chess960() bool
+	// Position returns the complete position, independent of the representation.
+	Position() Position
+	// ToFEN describes the position as a FEN string.
+	ToFEN() string
 }
 
+// Both representations implement the whole interface.
+var (
+	_ Board = (*ArrayBoard)(nil)
+	_ Board = (*Bitboard)(nil)
+)
+
 // These are constants for FEN parsing
 const StartFEN = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
-
-// State holds all information about the current state of the game.
-type State struct {
-	Board           [64]Piece
-	SideToMove      Color
-	whiteKingSquare int
-	blackKingSquare int
-	// Castling rights, en passant target, etc., would go here.
-}
-
-// New creates a new game state from the standard starting position.
-func New() *State {
-	return MustParseFEN("rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1")
-}
-
-// ApplyMove updates the board state by making a move.
-func (s *State) ApplyMove(m Move) {
-	piece := s.Board[m.From]
-
-	// Handle promotions
-	if m.Promotion != Empty {
-		s.Board[m.To] = m.Promotion // Place the new piece
-	} else {
-		s.Board[m.To] = piece // Just move the existing piece
-	}
-	s.Board[m.From] = Empty
-
-	// Update king's position if it moved
-	if piece == WhiteKing {
-		s.whiteKingSquare = m.To
-	} else if piece == BlackKing {
-		s.blackKingSquare = m.To
-	}
-
-	if s.SideToMove == White {
-		s.SideToMove = Black
-	} else {
-		s.SideToMove = White
-	}
-}
-
-// MustParseFEN is a helper that panics if the FEN is invalid.
-func MustParseFEN(fen string) *State {
-	s := &State{}
-	fields := strings.Fields(fen)
-
-	// Piece placement
-	rank, file := 7, 0
-	for _, char := range fields[0] {
-		if char == '/' {
-			rank--
-			file = 0
-		} else if char >= '1' && char <= '8' {
-			file += int(char - '0')
-		} else {
-			square := rank*8 + file
-			piece := pieceFromChar(char)
-			s.Board[square] = piece
-			// Find and store king positions
-			if piece == WhiteKing {
-				s.whiteKingSquare = square
-			} else if piece == BlackKing {
-				s.blackKingSquare = square
-			}
-			file++
-		}
-	}
-
-	// Active color
-	if fields[1] == "w" {
-		s.SideToMove = White
-	} else {
-		s.SideToMove = Black
-	}
-	return s
-}
-
-// ... (pieceFromChar function remains the same)
-func pieceFromChar2(c rune) Piece {
-	switch c {
-	case 'p':
-		return BlackPawn
-	case 'n':
-		return BlackKnight
-	case 'b':
-		return BlackBishop
-	case 'r':
-		return BlackRook
-	case 'q':
-		return BlackQueen
-	case 'k':
-		return BlackKing
-	case 'P':
-		return WhitePawn
-	case 'N':
-		return WhiteKnight
-	case 'B':
-		return WhiteBishop
-	case 'R':
-		return WhiteRook
-	case 'Q':
-		return WhiteQueen
-	case 'K':
-		return WhiteKing
-	}
-	return Empty
-}
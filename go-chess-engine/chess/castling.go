@@ -0,0 +1,88 @@
+package chess
+
+// Castling works the same in standard chess and Chess960: the king ends on
+// the g- or c-file and the rook next to it on the f- or d-file, wherever
+// both started. The rights are indexed by their bit in CastlingRights, so
+// 0 and 1 are White's kingside and queenside, 2 and 3 Black's.
+
+// standardRooks are the castling rooks of standard chess.
+var standardRooks = [4]int{7, 0, 63, 56}
+
+// castleTargets returns where the king and the rook stand after castling
+// with right i.
+func castleTargets(i int) (kingTo, rookTo int) {
+	rank := 0
+	if i >= 2 {
+		rank = 56
+	}
+	if i%2 == 0 {
+		return rank + 6, rank + 5
+	}
+	return rank + 2, rank + 3
+}
+
+// castleRight reports whether m, a move of piece, castles and with which
+// right. A castle is a king move of two files, or onto its own rook in
+// Chess960.
+func (s *gameState) castleRight(m Move, piece Piece) (int, bool) {
+	i := 0
+	switch piece {
+	case WhiteKing:
+	case BlackKing:
+		i = 2
+	default:
+		return 0, false
+	}
+	if m.To%8 < m.From%8 {
+		i++
+	}
+	if s.chess960 {
+		return i, s.castling&(1<<i) != 0 && m.To == s.castlingRooks[i]
+	}
+	return i, dist(m.From%8, m.To%8) == 2
+}
+
+// castlingMoves appends the castles of the side to move, whose king is on
+// king. empty reports whether a square is empty and attacked whether the
+// opponent attacks it. The king must not be in check, pass an attacked
+// square or land on one, and only the king and the rook may stand between
+// their start and destination squares.
+func (s *gameState) castlingMoves(moves *[]Move, king int, empty, attacked func(sq int) bool) {
+	first := 0
+	if s.sideToMove == Black {
+		first = 2
+	}
+	for i := first; i < first+2; i++ {
+		if s.castling&(1<<i) == 0 {
+			continue
+		}
+		rook := s.castlingRooks[i]
+		kingTo, rookTo := castleTargets(i)
+		ok := true
+		for sq := min(king, kingTo, rook, rookTo); sq <= max(king, kingTo, rook, rookTo) && ok; sq++ {
+			ok = sq == king || sq == rook || empty(sq)
+		}
+		for sq := min(king, kingTo); sq <= max(king, kingTo) && ok; sq++ {
+			ok = !attacked(sq)
+		}
+		if !ok {
+			continue
+		}
+		if s.chess960 {
+			*moves = append(*moves, Move{From: king, To: rook})
+		} else {
+			*moves = append(*moves, Move{From: king, To: kingTo})
+		}
+	}
+}
+
+// IsCastling reports whether the move m on b castles. Whether it is on the
+// kingside follows from m.To being on a higher file than m.From.
+func IsCastling(b Board, m Move) bool {
+	piece, target := b.PieceAt(m.From), b.PieceAt(m.To)
+	if piece != WhiteKing && piece != BlackKing {
+		return false
+	}
+	ownRook := (piece == WhiteKing && target == WhiteRook) || (piece == BlackKing && target == BlackRook)
+	return ownRook || dist(m.From%8, m.To%8) == 2
+}
@@ -1,23 +1,30 @@
 package chess
 
-import (
-	"go-chess-engine/config"
-	"log"
-)
+import "fmt"
 
-// NewBoardFromConfig is a factory function that creates a board
-// based on the global application configuration.
-func NewBoardFromConfig(fen string) Board {
-	rep := config.AppConfig.BoardRepresentation
-	log.Printf("INFO: Creating new board with representation: '%s'", rep)
+// NewBoard is a factory function that creates a board of the given
+// representation, "array" or "bitboard". It fails on a malformed FEN, an
+// impossible position or an unknown representation.
+func NewBoard(representation, fen string) (Board, error) {
+	p, err := ParseFEN(fen)
+	if err != nil {
+		return nil, err
+	}
+	return NewBoardFromPosition(representation, p)
+}
 
-	switch rep {
+// NewBoardFromPosition creates a board of the given representation holding
+// p. It fails unless p passes Validate, so that both representations only
+// ever see positions they agree on.
+func NewBoardFromPosition(representation string, p Position) (Board, error) {
+	if err := p.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid position: %v", err)
+	}
+	switch representation {
 	case "bitboard":
-		return NewBitboard(fen)
+		return NewBitboardFromPosition(p), nil
 	case "array":
-		return NewArrayBoard(fen)
-	default:
-		log.Printf("WARN: Unknown board representation '%s'. Defaulting to 'array'.", rep)
-		return NewArrayBoard(fen)
+		return NewArrayBoardFromPosition(p), nil
 	}
+	return nil, fmt.Errorf("unknown board representation %q", representation)
 }
@@ -0,0 +1,35 @@
+package chess
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNewBoard(t *testing.T) {
+	tests := []struct{ fen, err string }{
+		{StartFEN, ""},
+		{"bqnb1rkr/pp3ppp/3ppn2/2p5/5P2/P2P4/NPP1P1PP/BQ1BNRKR w HFhf - 2 9", ""},
+		{"8/8/8", "fen"},
+		// Both boards would play on without a king, each in its own way.
+		{"8/8/8/8/8/8/8/K7 w - - 0 1", "want one king per side, have 1 white and 0 black"},
+		{"4k3/8/8/8/8/8/8/8 b - - 0 1", "want one king per side, have 0 white and 1 black"},
+		{"4k3/8/8/8/8/8/8/K3K3 w - - 0 1", "want one king per side, have 2 white and 1 black"},
+		{"4k3/8/8/8/8/8/8/4K2P w - - 0 1", "pawn on h1"},
+		{"4k3/8/8/8/8/8/8/R3K3 w - - 0 1", ""},
+		{"4k3/8/8/8/8/8/8/4R1K1 w - - 0 1", "the side not to move is in check"},
+	}
+	for _, rep := range []string{"array", "bitboard"} {
+		for _, tt := range tests {
+			_, err := NewBoard(rep, tt.fen)
+			switch {
+			case tt.err == "" && err != nil:
+				t.Errorf("%s: NewBoard(%s): %v", rep, tt.fen, err)
+			case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+				t.Errorf("%s: NewBoard(%s) error %v, want %q", rep, tt.fen, err, tt.err)
+			}
+		}
+	}
+	if _, err := NewBoard("list", StartFEN); err == nil {
+		t.Error("NewBoard accepted an unknown representation")
+	}
+}
@@ -0,0 +1,247 @@
+package chess
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ParseFEN reads a position from a FEN string. The castling, en passant
+// and clock fields may be left out and default to "- - 0 1".
+//
+// Castling rights may be given as "KQkq", which refers to the outermost
+// rook on either side of the king (X-FEN), or by the files of the rooks,
+// e.g. "HAha" (Shredder-FEN), which Chess960 needs when a king has two
+// rooks on the same side.
+func ParseFEN(fen string) (Position, error) {
+	p := Position{EnPassant: NoSquare, FullmoveNumber: 1}
+	fields := strings.Fields(fen)
+	if len(fields) < 2 || len(fields) > 6 {
+		return p, fmt.Errorf("fen %q: want 2 to 6 fields", fen)
+	}
+
+	// 1. Piece placement
+	ranks := strings.Split(fields[0], "/")
+	if len(ranks) != 8 {
+		return p, fmt.Errorf("fen %q: want 8 ranks", fen)
+	}
+	for i, row := range ranks {
+		rank, file := 7-i, 0
+		for _, char := range row {
+			if char >= '1' && char <= '8' {
+				file += int(char - '0')
+				continue
+			}
+			piece := pieceFromChar(char)
+			if piece == Empty || file > 7 {
+				return p, fmt.Errorf("fen %q: bad rank %q", fen, row)
+			}
+			p.Pieces[rank*8+file] = piece
+			file++
+		}
+		if file != 8 {
+			return p, fmt.Errorf("fen %q: bad rank %q", fen, row)
+		}
+	}
+
+	// 2. Side to move
+	switch fields[1] {
+	case "w":
+		p.SideToMove = White
+	case "b":
+		p.SideToMove = Black
+	default:
+		return p, fmt.Errorf("fen %q: bad side to move %q", fen, fields[1])
+	}
+
+	// 3. Castling rights
+	p.CastlingRooks = standardRooks
+	if len(fields) > 2 && fields[2] != "-" {
+		for _, char := range fields[2] {
+			if err := p.addCastlingRight(char); err != nil {
+				return p, fmt.Errorf("fen %q: %v", fen, err)
+			}
+		}
+	}
+
+	// 4. En passant square
+	if len(fields) > 3 && fields[3] != "-" {
+		sq, ok := parseSquare(fields[3])
+		if !ok {
+			return p, fmt.Errorf("fen %q: bad en passant square %q", fen, fields[3])
+		}
+		p.EnPassant = sq
+	}
+
+	// 5. and 6. Move counters
+	if len(fields) > 4 {
+		n, err := strconv.Atoi(fields[4])
+		if err != nil || n < 0 {
+			return p, fmt.Errorf("fen %q: bad halfmove clock %q", fen, fields[4])
+		}
+		p.HalfmoveClock = n
+	}
+	if len(fields) > 5 {
+		n, err := strconv.Atoi(fields[5])
+		if err != nil || n < 1 {
+			return p, fmt.Errorf("fen %q: bad fullmove number %q", fen, fields[5])
+		}
+		p.FullmoveNumber = n
+	}
+	return p, nil
+}
+
+// MustParseFEN is a helper that panics if the FEN is invalid.
+func MustParseFEN(fen string) Position {
+	p, err := ParseFEN(fen)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+// FEN describes the position as a FEN string.
+func (p Position) FEN() string {
+	var sb strings.Builder
+	for rank := 7; rank >= 0; rank-- {
+		emptyRun := 0
+		for file := 0; file < 8; file++ {
+			piece := p.Pieces[rank*8+file]
+			if piece == Empty {
+				emptyRun++
+				continue
+			}
+			if emptyRun > 0 {
+				sb.WriteByte(byte('0' + emptyRun))
+				emptyRun = 0
+			}
+			sb.WriteByte(pieceToChar(piece))
+		}
+		if emptyRun > 0 {
+			sb.WriteByte(byte('0' + emptyRun))
+		}
+		if rank > 0 {
+			sb.WriteByte('/')
+		}
+	}
+
+	if p.SideToMove == White {
+		sb.WriteString(" w ")
+	} else {
+		sb.WriteString(" b ")
+	}
+	sb.WriteString(p.castlingField())
+	if p.EnPassant == NoSquare {
+		sb.WriteString(" -")
+	} else {
+		sb.WriteString(" " + indexToSquare(p.EnPassant))
+	}
+	fmt.Fprintf(&sb, " %d %d", p.HalfmoveClock, p.FullmoveNumber)
+	return sb.String()
+}
+
+// addCastlingRight adds the right written as c in a FEN castling field,
+// finding its rook. A right that standard chess cannot castle with makes
+// the position a Chess960 one.
+func (p *Position) addCastlingRight(c rune) error {
+	rank, king, rook, file := 0, WhiteKing, WhiteRook, c
+	if c >= 'a' && c <= 'z' {
+		rank, king, rook, file = 56, BlackKing, BlackRook, c-'a'+'A'
+	}
+	kingSq := NoSquare
+	for sq := rank; sq < rank+8; sq++ {
+		if p.Pieces[sq] == king {
+			kingSq = sq
+		}
+	}
+	if kingSq == NoSquare {
+		return fmt.Errorf("castling right %q without a king on its first rank", c)
+	}
+
+	rookSq := NoSquare
+	switch {
+	case file == 'K':
+		for sq := rank + 7; sq > kingSq && rookSq == NoSquare; sq-- {
+			if p.Pieces[sq] == rook {
+				rookSq = sq
+			}
+		}
+	case file == 'Q':
+		for sq := rank; sq < kingSq && rookSq == NoSquare; sq++ {
+			if p.Pieces[sq] == rook {
+				rookSq = sq
+			}
+		}
+	case file >= 'A' && file <= 'H':
+		if sq := rank + int(file-'A'); p.Pieces[sq] == rook {
+			rookSq = sq
+		}
+	default:
+		return fmt.Errorf("bad castling right %q", c)
+	}
+	if rookSq == NoSquare {
+		return fmt.Errorf("castling right %q without its rook", c)
+	}
+
+	i := 0
+	if rookSq < kingSq {
+		i = 1
+	}
+	if king == BlackKing {
+		i += 2
+	}
+	p.Castling |= 1 << i
+	p.CastlingRooks[i] = rookSq
+	if kingSq != rank+4 || rookSq != standardRooks[i] {
+		p.Chess960 = true
+	}
+	return nil
+}
+
+// castlingField writes the castling rights for a FEN. Chess960 positions
+// use X-FEN: "KQkq" for the outermost rooks and the file otherwise.
+func (p Position) castlingField() string {
+	if !p.Chess960 {
+		return p.Castling.String()
+	}
+	s := ""
+	for i := 0; i < 4; i++ {
+		if p.Castling&(1<<i) == 0 {
+			continue
+		}
+		rook := p.CastlingRooks[i]
+		outermost := true
+		for sq := rook; sq%8 != 0 && sq%8 != 7; {
+			if i%2 == 0 {
+				sq++
+			} else {
+				sq--
+			}
+			outermost = outermost && p.Pieces[sq] != p.Pieces[rook]
+		}
+		c := "KQkq"[i]
+		if !outermost {
+			c = byte('A' + rook%8)
+			if i >= 2 {
+				c = byte('a' + rook%8)
+			}
+		}
+		s += string(c)
+	}
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func pieceFromChar(c rune) Piece {
+	if i := strings.IndexRune(" PNBRQKpnbrqk", c); i > 0 {
+		return Piece(i)
+	}
+	return Empty
+}
+
+// pieceToChar is the inverse of pieceFromChar.
+func pieceToChar(p Piece) byte {
+	return " PNBRQKpnbrqk"[p]
+}
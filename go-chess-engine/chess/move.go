@@ -43,6 +43,9 @@ func (p Piece) Color() Color {
 	return NoColor
 }
 
+// FormatMove writes m in the long algebraic notation of UCI, e.g. "e2e4" or
+// "e7e8q". Castling is the king's move, "e1g1", except on a Chess960 board,
+// whose castling moves are the king capturing its own rook, "e1h1".
 func FormatMove(m Move) string {
 	baseMove := indexToSquare(m.From) + indexToSquare(m.To)
 	if m.Promotion != Empty {
@@ -60,6 +63,8 @@ func FormatMove(m Move) string {
 	return baseMove
 }
 
+// ParseMove reads a move written by FormatMove. It is not checked against
+// a board, so a castle must use the notation of the board it is played on.
 func ParseMove(s string) Move {
 	move := Move{
 		From:      squareToIndex(s[0:2]),
@@ -116,3 +121,8 @@ func indexToSquare(i int) string {
 	rank := '1' + rune(i/8)
 	return string(file) + string(rank)
 }
+
+// SquareName returns the algebraic name of a square index, e.g. "e4" for 28.
+func SquareName(sq int) string {
+	return indexToSquare(sq)
+}
@@ -0,0 +1,21 @@
+package chess
+
+// Perft counts the positions reached by every sequence of depth legal
+// moves from b. Comparing the counts with published ones is the standard
+// check of a move generator.
+func Perft(b Board, depth int) int {
+	if depth == 0 {
+		return 1
+	}
+	moves := b.GenerateLegalMoves()
+	if depth == 1 {
+		return len(moves)
+	}
+	nodes := 0
+	for _, m := range moves {
+		next := b.Clone()
+		next.ApplyMove(m)
+		nodes += Perft(next, depth-1)
+	}
+	return nodes
+}
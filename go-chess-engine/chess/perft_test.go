@@ -0,0 +1,48 @@
+package chess
+
+import "testing"
+
+// perftTests are the standard perft positions and some of the Chess960
+// ones, with the node counts at depth 1, 2, ...
+var perftTests = []struct {
+	name   string
+	fen    string
+	counts []int
+}{
+	{"start", StartFEN, []int{20, 400, 8902, 197281}},
+	{"kiwipete", "r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1", []int{48, 2039, 97862}},
+	{"position 3", "8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1", []int{14, 191, 2812, 43238, 674624}},
+	{"position 4", "r3k2r/Pppp1ppp/1b3nbN/nP6/BBP1P3/q4N2/Pp1P2PP/R2Q1RK1 w kq - 0 1", []int{6, 264, 9467, 422333}},
+	{"position 4 mirrored", "r2q1rk1/pP1p2pp/Q4n2/bbp1p3/Np6/1B3NBn/pPPP1PPP/R3K2R b KQ - 0 1", []int{6, 264, 9467, 422333}},
+	{"position 5", "rnbq1k1r/pp1Pbppp/2p5/8/2B5/8/PPP1NnPP/RNBQK2R w KQ - 1 8", []int{44, 1486, 62379}},
+	{"position 6", "r4rk1/1pp1qppp/p1np1n2/2b1p1B1/2B1P1b1/P1NP1N2/1PP1QPPP/R4RK1 w - - 0 10", []int{46, 2079, 89890}},
+	{"960 #1", "bqnb1rkr/pp3ppp/3ppn2/2p5/5P2/P2P4/NPP1P1PP/BQ1BNRKR w HFhf - 2 9", []int{21, 528, 12189, 326672}},
+	{"960 #2", "2nnrbkr/p1qppppp/8/1ppb4/6PP/3PP3/PPP2P2/BQNNRBKR w HEhe - 1 9", []int{21, 807, 18002}},
+	{"960 #3", "b1q1rrkb/pppppppp/3nn3/8/P7/1PPP4/4PPPP/BQNNRKRB w GE - 1 9", []int{20, 479, 10471, 273318}},
+	{"960 #4", "qbbnnrkr/2pp2pp/p7/1p2pp2/8/P3PP2/1PPP1KPP/QBBNNR1R w hf - 0 9", []int{22, 593, 13440, 382958}},
+	{"960 #5", "1nbbnrkr/p1p1ppp1/3p4/1p3P1p/3Pq2P/8/PPP1P1P1/QNBBNRKR w HFhf - 0 9", []int{28, 1120, 31058}},
+}
+
+func TestPerft(t *testing.T) {
+	// The deepest counts take most of the time; -short stops below them.
+	limit := 1 << 30
+	if testing.Short() {
+		limit = 20000
+	}
+	for _, rep := range []string{"array", "bitboard"} {
+		for _, tt := range perftTests {
+			b, err := NewBoard(rep, tt.fen)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i, want := range tt.counts {
+				if want > limit {
+					break
+				}
+				if got := Perft(b, i+1); got != want {
+					t.Errorf("%s: %s: perft(%d) = %d, want %d", rep, tt.name, i+1, got, want)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package chess
+
+import "fmt"
+
+// NoSquare marks the absence of an en passant square.
+const NoSquare = -1
+
+// CastlingRights is a set of the castling moves still allowed.
+type CastlingRights uint8
+
+const (
+	WhiteKingside CastlingRights = 1 << iota
+	WhiteQueenside
+	BlackKingside
+	BlackQueenside
+)
+
+// String writes the rights as in a FEN, e.g. "KQkq", or "-" for none.
+func (c CastlingRights) String() string {
+	s := ""
+	for i, r := range "KQkq" {
+		if c&(1<<i) != 0 {
+			s += string(r)
+		}
+	}
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// Position is a complete chess position, independent of how a Board
+// stores it. The FEN parser produces one and every representation can be
+// created from and converted back to one.
+type Position struct {
+	Pieces     [64]Piece
+	SideToMove Color
+	Castling   CastlingRights
+	// EnPassant is the square a pawn skipped with its last double step, or
+	// NoSquare. It is set after every double step, capture possible or not.
+	EnPassant      int
+	HalfmoveClock  int
+	FullmoveNumber int
+	// CastlingRooks are the start squares of the rooks the castling rights
+	// refer to, indexed by the bit of the right: h1, a1, h8 and a8 unless
+	// the position is from Chess960.
+	CastlingRooks [4]int
+	// Chess960 writes castling as the king capturing its own rook, e.g.
+	// e1h1 for e1g1. ParseFEN sets it for positions that standard chess
+	// cannot castle in; UCI_Chess960 sets it for every position.
+	Chess960 bool
+}
+
+// gameState is the part of a position besides the pieces. Each board
+// representation embeds it and calls update from ApplyMove, so only the
+// piece bookkeeping differs between them.
+type gameState struct {
+	sideToMove     Color
+	castling       CastlingRights
+	enPassant      int
+	halfmoveClock  int
+	fullmoveNumber int
+	captured       Piece
+	castlingRooks  [4]int
+	chess960       bool
+}
+
+func stateOf(p Position) gameState {
+	return gameState{
+		sideToMove:     p.SideToMove,
+		castling:       p.Castling,
+		enPassant:      p.EnPassant,
+		halfmoveClock:  p.HalfmoveClock,
+		fullmoveNumber: p.FullmoveNumber,
+		castlingRooks:  p.CastlingRooks,
+		chess960:       p.Chess960,
+	}
+}
+
+// fill copies the state into p.
+func (s gameState) fill(p *Position) {
+	p.SideToMove = s.sideToMove
+	p.Castling = s.castling
+	p.EnPassant = s.enPassant
+	p.HalfmoveClock = s.halfmoveClock
+	p.FullmoveNumber = s.fullmoveNumber
+	p.CastlingRooks = s.castlingRooks
+	p.Chess960 = s.chess960
+}
+
+// update advances the state past move m of piece, which captured captured
+// (Empty if nothing).
+func (s *gameState) update(m Move, piece, captured Piece) {
+	// A king move, or a move from or to a castling rook's square, ends
+	// castling there.
+	switch piece {
+	case WhiteKing:
+		s.castling &^= WhiteKingside | WhiteQueenside
+	case BlackKing:
+		s.castling &^= BlackKingside | BlackQueenside
+	}
+	for i, rook := range s.castlingRooks {
+		if m.From == rook || m.To == rook {
+			s.castling &^= 1 << i
+		}
+	}
+
+	s.captured = captured
+	isPawn := piece == WhitePawn || piece == BlackPawn
+	s.enPassant = NoSquare
+	if isPawn && dist(m.From/8, m.To/8) == 2 {
+		s.enPassant = (m.From + m.To) / 2
+	}
+	if isPawn || captured != Empty {
+		s.halfmoveClock = 0
+	} else {
+		s.halfmoveClock++
+	}
+	if s.sideToMove == Black {
+		s.fullmoveNumber++
+	}
+	s.sideToMove = oppositeColor(s.sideToMove)
+}
+
+// The accessors of the Board interface that only depend on the state are
+// shared by every representation through embedding.
+
+func (s *gameState) CastlingRights() CastlingRights { return s.castling }
+func (s *gameState) EnPassantSquare() int           { return s.enPassant }
+func (s *gameState) HalfmoveClock() int             { return s.halfmoveClock }
+func (s *gameState) FullmoveNumber() int            { return s.fullmoveNumber }
+func (s *gameState) Captured() Piece                { return s.captured }
+func (s *gameState) Chess960() bool                 { return s.chess960 }
+
+// Validate checks what ParseFEN does not: that the position could occur in
+// a game. Each side needs one king, pawns cannot stand on the first or last
+// rank, the side that just moved cannot be in check and an en passant
+// square must be behind a pawn that could just have made a double step.
+func (p Position) Validate() error {
+	var kings [2]int
+	for sq, piece := range p.Pieces {
+		switch piece {
+		case WhiteKing, BlackKing:
+			kings[piece.Color()]++
+		case WhitePawn, BlackPawn:
+			if sq/8 == 0 || sq/8 == 7 {
+				return fmt.Errorf("pawn on %s", indexToSquare(sq))
+			}
+		}
+	}
+	if kings != [2]int{1, 1} {
+		return fmt.Errorf("want one king per side, have %d white and %d black", kings[White], kings[Black])
+	}
+
+	if ep := p.EnPassant; ep != NoSquare {
+		rank, pawn, step := 5, BlackPawn, -8
+		if p.SideToMove == Black {
+			rank, pawn, step = 2, WhitePawn, 8
+		}
+		if ep/8 != rank || p.Pieces[ep+step] != pawn || p.Pieces[ep] != Empty || p.Pieces[ep-step] != Empty {
+			return fmt.Errorf("impossible en passant square %s", indexToSquare(ep))
+		}
+	}
+
+	moved := p
+	moved.SideToMove = oppositeColor(p.SideToMove)
+	if NewArrayBoardFromPosition(moved).InCheck() {
+		return fmt.Errorf("the side not to move is in check")
+	}
+	return nil
+}
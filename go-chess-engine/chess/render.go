@@ -0,0 +1,70 @@
+package chess
+
+import (
+	"slices"
+	"strings"
+)
+
+// unicodePieces are the chess symbols, indexed like pieceToChar.
+var unicodePieces = []string{" ", "♙", "♘", "♗", "♖", "♕", "♔", "♟", "♞", "♝", "♜", "♛", "♚"}
+
+// Diagram draws the board as text, rank 8 at the top, with letters for the
+// pieces (upper case for White) or, if unicode is set, chess symbols.
+func Diagram(b Board, unicode bool) string {
+	return diagram(b, unicode, false)
+}
+
+// FlippedDiagram draws the board like Diagram, as seen from Black's side.
+func FlippedDiagram(b Board, unicode bool) string {
+	return diagram(b, unicode, true)
+}
+
+func diagram(b Board, unicode, flipped bool) string {
+	const separator = " +---+---+---+---+---+---+---+---+\n"
+	var sb strings.Builder
+	sb.WriteString(separator)
+	for row := 0; row < 8; row++ {
+		rank := 7 - row
+		if flipped {
+			rank = row
+		}
+		for col := 0; col < 8; col++ {
+			file := col
+			if flipped {
+				file = 7 - col
+			}
+			piece := b.PieceAt(rank*8 + file)
+			symbol := string(pieceToChar(piece))
+			if unicode {
+				symbol = unicodePieces[piece]
+			}
+			sb.WriteString(" | " + symbol)
+		}
+		sb.WriteString(" | " + string(rune('1'+rank)) + "\n")
+		sb.WriteString(separator)
+	}
+	if flipped {
+		sb.WriteString("   h   g   f   e   d   c   b   a\n")
+	} else {
+		sb.WriteString("   a   b   c   d   e   f   g   h\n")
+	}
+	return sb.String()
+}
+
+// Checkers returns the squares of the pieces giving check to the side to
+// move, in increasing order: those with a move onto its king's square.
+func Checkers(b Board) []int {
+	us := b.SideToMove()
+	king := WhiteKing
+	if us == Black {
+		king = BlackKing
+	}
+	var checkers []int
+	for _, m := range b.PseudoLegalMoves(oppositeColor(us)) {
+		if b.PieceAt(m.To) == king && !slices.Contains(checkers, m.From) {
+			checkers = append(checkers, m.From)
+		}
+	}
+	slices.Sort(checkers)
+	return checkers
+}
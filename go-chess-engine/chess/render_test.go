@@ -0,0 +1,45 @@
+package chess
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestCheckers(t *testing.T) {
+	tests := []struct {
+		fen  string
+		want []string
+	}{
+		{StartFEN, nil},
+		{"4k3/8/8/8/8/8/3p4/4K3 w - - 0 1", []string{"d2"}}, // It could capture promoting
+		{"4k3/8/8/8/8/5n2/8/4K3 w - - 0 1", []string{"f3"}},
+		{"4k3/8/8/8/8/8/8/r3K3 w - - 0 1", []string{"a1"}},
+		// A rook behind a blocker gives no check.
+		{"4k3/4r3/8/8/8/8/4P3/4K3 w - - 0 1", nil},
+		// Double check, and a white pawn giving check.
+		{"4k3/4r3/8/8/1b6/8/8/4K3 w - - 0 1", []string{"b4", "e7"}},
+		{"4k3/3P4/8/8/8/8/8/4K3 b - - 0 1", []string{"d7"}},
+		{"rnbqkbnr/pppp1ppp/8/4p3/6P1/5P2/PPPPP2P/RNBQKBNR b KQkq g3 0 2", nil},
+		{"rnb1kbnr/pppp1ppp/8/4p3/6Pq/5P2/PPPPP2P/RNBQKBNR w KQkq - 1 3", []string{"h4"}},
+		{"4k3/8/8/8/8/8/8/4K2R b - - 0 1", nil},
+		{"4k2R/8/8/8/8/8/8/4K3 b - - 0 1", []string{"h8"}},
+	}
+	for _, rep := range []string{"array", "bitboard"} {
+		for _, tt := range tests {
+			b, err := NewBoard(rep, tt.fen)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, sq := range Checkers(b) {
+				got = append(got, SquareName(sq))
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("%s: Checkers(%s) = %v, want %v", rep, tt.fen, got, tt.want)
+			}
+			if b.InCheck() != (len(got) > 0) {
+				t.Errorf("%s: %s: InCheck() = %v with checkers %v", rep, tt.fen, b.InCheck(), got)
+			}
+		}
+	}
+}
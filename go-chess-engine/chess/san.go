@@ -0,0 +1,162 @@
+package chess
+
+import (
+	"fmt"
+	"strings"
+)
+
+// sanLetters maps a piece kind (1 = pawn .. 6 = king) to its SAN letter.
+const sanLetters = " PNBRQK"
+
+// kind returns 1 (pawn) .. 6 (king) regardless of colour.
+func kind(p Piece) int {
+	if p.Color() == Black {
+		return int(p - BlackPawn + 1)
+	}
+	return int(p)
+}
+
+// ParseSAN finds the legal move on b written in Standard Algebraic Notation,
+// e.g. "Nbd7", "exd5", "e8=Q+" or "O-O". Check and annotation suffixes are
+// ignored.
+func ParseSAN(b Board, san string) (Move, error) {
+	s := strings.TrimRight(san, "+#!?")
+	legal := b.GenerateLegalMoves()
+
+	if s == "O-O" || s == "O-O-O" || s == "0-0" || s == "0-0-0" {
+		for _, m := range legal {
+			if !IsCastling(b, m) {
+				continue
+			}
+			if (len(s) == 3) == (m.To%8 > m.From%8) {
+				return m, nil
+			}
+		}
+		return Move{}, fmt.Errorf("illegal castling %q", san)
+	}
+
+	// Strip the promotion piece, then the destination square.
+	promo := 0
+	if i := strings.IndexAny(s, "="); i >= 0 && i+1 < len(s) {
+		promo = strings.IndexByte(sanLetters, s[i+1])
+		s = s[:i]
+	} else if len(s) > 2 && strings.IndexByte("NBRQ", s[len(s)-1]) >= 0 {
+		promo = strings.IndexByte(sanLetters, s[len(s)-1])
+		s = s[:len(s)-1]
+	}
+	if len(s) < 2 {
+		return Move{}, fmt.Errorf("bad move %q", san)
+	}
+	to, ok := parseSquare(s[len(s)-2:])
+	if !ok {
+		return Move{}, fmt.Errorf("bad move %q", san)
+	}
+	s = strings.TrimSuffix(s[:len(s)-2], "x")
+
+	piece := 1
+	if len(s) > 0 && strings.IndexByte("NBRQK", s[0]) >= 0 {
+		piece = strings.IndexByte(sanLetters, s[0])
+		s = s[1:]
+	}
+	fromFile, fromRank := -1, -1
+	for _, c := range s {
+		switch {
+		case c >= 'a' && c <= 'h':
+			fromFile = int(c - 'a')
+		case c >= '1' && c <= '8':
+			fromRank = int(c - '1')
+		default:
+			return Move{}, fmt.Errorf("bad move %q", san)
+		}
+	}
+
+	var found []Move
+	for _, m := range legal {
+		if m.To != to || kind(b.PieceAt(m.From)) != piece {
+			continue
+		}
+		if (fromFile >= 0 && m.From%8 != fromFile) || (fromRank >= 0 && m.From/8 != fromRank) {
+			continue
+		}
+		if (m.Promotion == Empty && promo != 0) || (m.Promotion != Empty && kind(m.Promotion) != promo) {
+			continue
+		}
+		found = append(found, m)
+	}
+	switch len(found) {
+	case 0:
+		return Move{}, fmt.Errorf("illegal move %q", san)
+	case 1:
+		return found[0], nil
+	}
+	return Move{}, fmt.Errorf("ambiguous move %q", san)
+}
+
+// FormatSAN writes the legal move m on b in Standard Algebraic Notation,
+// with a "+" or "#" suffix when it gives check or mate.
+func FormatSAN(b Board, m Move) string {
+	var sb strings.Builder
+	piece := kind(b.PieceAt(m.From))
+	capture := b.PieceAt(m.To) != Empty
+
+	switch {
+	case IsCastling(b, m) && m.To > m.From:
+		sb.WriteString("O-O")
+	case IsCastling(b, m):
+		sb.WriteString("O-O-O")
+	case piece == 1:
+		if capture || m.From%8 != m.To%8 {
+			sb.WriteByte(byte('a' + m.From%8))
+			sb.WriteByte('x')
+		}
+		sb.WriteString(indexToSquare(m.To))
+		if m.Promotion != Empty {
+			sb.WriteByte('=')
+			sb.WriteByte(sanLetters[kind(m.Promotion)])
+		}
+	default:
+		sb.WriteByte(sanLetters[piece])
+		// Disambiguate against other pieces of the same kind reaching m.To.
+		sameFile, sameRank, others := false, false, false
+		for _, o := range b.GenerateLegalMoves() {
+			if o.To != m.To || o.From == m.From || kind(b.PieceAt(o.From)) != piece {
+				continue
+			}
+			others = true
+			sameFile = sameFile || o.From%8 == m.From%8
+			sameRank = sameRank || o.From/8 == m.From/8
+		}
+		if others {
+			if !sameFile {
+				sb.WriteByte(byte('a' + m.From%8))
+			} else if !sameRank {
+				sb.WriteByte(byte('1' + m.From/8))
+			} else {
+				sb.WriteString(indexToSquare(m.From))
+			}
+		}
+		if capture {
+			sb.WriteByte('x')
+		}
+		sb.WriteString(indexToSquare(m.To))
+	}
+
+	after := b.Clone()
+	after.ApplyMove(m)
+	if after.InCheck() {
+		if len(after.GenerateLegalMoves()) == 0 {
+			sb.WriteByte('#')
+		} else {
+			sb.WriteByte('+')
+		}
+	}
+	return sb.String()
+}
+
+// parseSquare is squareToIndex with validation, for untrusted input.
+func parseSquare(s string) (int, bool) {
+	if len(s) != 2 || s[0] < 'a' || s[0] > 'h' || s[1] < '1' || s[1] > '8' {
+		return 0, false
+	}
+	return squareToIndex(s), true
+}
@@ -0,0 +1,122 @@
+package chess
+
+import "testing"
+
+var sanTests = []struct {
+	fen      string
+	chess960 bool
+	move     string // UCI notation
+	san      string
+}{
+	{StartFEN, false, "g1f3", "Nf3"},
+	{StartFEN, false, "e2e4", "e4"},
+	{"rnbqkbnr/ppp1pppp/8/3p4/4P3/8/PPPP1PPP/RNBQKBNR w KQkq d6 0 2", false, "e4d5", "exd5"},
+	{"4k3/8/8/3pP3/8/8/8/4K3 w - d6 0 1", false, "e5d6", "exd6"},
+
+	// Disambiguation by file, by rank and by both, and not for a pinned piece.
+	{"4k3/8/8/8/8/5N2/8/1N2K3 w - - 0 1", false, "b1d2", "Nbd2"},
+	{"4k3/8/8/8/8/5N2/8/1N2K3 w - - 0 1", false, "f3d2", "Nfd2"},
+	{"4k3/8/8/R7/8/8/8/R3K3 w - - 0 1", false, "a1a3", "R1a3"},
+	{"4k3/8/8/R7/8/8/8/R3K3 w - - 0 1", false, "a5a3", "R5a3"},
+	{"4k3/8/8/8/8/Q7/8/Q1Q1K3 w - - 0 1", false, "a1b2", "Qa1b2"},
+	{"4k3/8/8/7b/8/5N2/4K3/1N6 w - - 0 1", false, "b1d2", "Nd2"},
+	{"4k3/8/8/4p3/8/5N2/8/1N2K3 w - - 0 1", false, "f3e5", "Nxe5"},
+
+	// Promotions.
+	{"8/P6k/8/8/8/8/8/K7 w - - 0 1", false, "a7a8q", "a8=Q"},
+	{"8/P6k/8/8/8/8/8/K7 w - - 0 1", false, "a7a8n", "a8=N"},
+	{"1r5k/P7/8/8/8/8/8/K7 w - - 0 1", false, "a7b8q", "axb8=Q+"},
+	{"k7/8/8/8/8/8/1p6/2N4K b - - 0 1", false, "b2c1r", "bxc1=R+"},
+
+	// Castling, in standard chess and in Chess960.
+	{"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", false, "e1g1", "O-O"},
+	{"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", false, "e1c1", "O-O-O"},
+	{"r3k2r/8/8/8/8/8/8/R3K2R b KQkq - 0 1", false, "e8g8", "O-O"},
+	{"5k2/8/8/8/8/8/8/4K2R w K - 0 1", false, "e1g1", "O-O+"},
+	{"r3k2r/8/8/8/8/8/8/1R2K1R1 w GB - 0 1", true, "e1g1", "O-O"},
+	{"r3k2r/8/8/8/8/8/8/1R2K1R1 w GB - 0 1", true, "e1b1", "O-O-O"},
+	{"1k6/8/8/8/8/8/8/RK5R w HA - 0 1", true, "b1a1", "O-O-O"},
+	{"1k6/8/8/8/8/8/8/RK5R w HA - 0 1", true, "b1h1", "O-O"},
+
+	// Check and mate.
+	{"4k3/8/8/8/8/8/8/4K2R w - - 0 1", false, "h1h8", "Rh8+"},
+	{"rnbqkbnr/pppp1ppp/8/4p3/6P1/5P2/PPPPP2P/RNBQKBNR b KQkq g3 0 2", false, "d8h4", "Qh4#"},
+	{"6k1/5ppp/8/8/8/8/8/R3K3 w Q - 0 1", false, "a1a8", "Ra8#"},
+}
+
+func sanBoard(t *testing.T, rep, fen string, chess960 bool) Board {
+	t.Helper()
+	p := MustParseFEN(fen)
+	p.Chess960 = p.Chess960 || chess960
+	b, err := NewBoardFromPosition(rep, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// legalMove finds the legal move written as uci.
+func legalMove(t *testing.T, b Board, uci string) Move {
+	t.Helper()
+	for _, m := range b.GenerateLegalMoves() {
+		if FormatMove(m) == uci {
+			return m
+		}
+	}
+	t.Fatalf("%s: %s is not legal", b.ToFEN(), uci)
+	return Move{}
+}
+
+func TestFormatSAN(t *testing.T) {
+	for _, rep := range []string{"array", "bitboard"} {
+		for _, tt := range sanTests {
+			b := sanBoard(t, rep, tt.fen, tt.chess960)
+			if got := FormatSAN(b, legalMove(t, b, tt.move)); got != tt.san {
+				t.Errorf("%s: FormatSAN(%s, %s) = %s, want %s", rep, tt.fen, tt.move, got, tt.san)
+			}
+		}
+	}
+}
+
+func TestParseSAN(t *testing.T) {
+	for _, rep := range []string{"array", "bitboard"} {
+		for _, tt := range sanTests {
+			b := sanBoard(t, rep, tt.fen, tt.chess960)
+			m, err := ParseSAN(b, tt.san)
+			if err != nil || FormatMove(m) != tt.move {
+				t.Errorf("%s: ParseSAN(%s, %s) = %s, %v, want %s", rep, tt.fen, tt.san, FormatMove(m), err, tt.move)
+			}
+		}
+	}
+
+	// Other spellings people and programs use.
+	variants := []struct{ fen, san, move string }{
+		{StartFEN, "Nf3!?", "g1f3"},
+		{StartFEN, "Ng1f3", "g1f3"},
+		{"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", "0-0", "e1g1"},
+		{"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", "0-0-0", "e1c1"},
+		{"8/P6k/8/8/8/8/8/K7 w - - 0 1", "a8Q", "a7a8q"},
+		{"1r5k/P7/8/8/8/8/8/K7 w - - 0 1", "axb8=Q", "a7b8q"},
+	}
+	for _, v := range variants {
+		b := NewArrayBoard(v.fen)
+		if m, err := ParseSAN(b, v.san); err != nil || FormatMove(m) != v.move {
+			t.Errorf("ParseSAN(%s, %s) = %s, %v, want %s", v.fen, v.san, FormatMove(m), err, v.move)
+		}
+	}
+
+	errors := []struct{ fen, san string }{
+		{"4k3/8/8/8/8/5N2/8/1N2K3 w - - 0 1", "Nd2"}, // Ambiguous
+		{StartFEN, "Ke2"},
+		{StartFEN, "O-O"},
+		{StartFEN, "e5"},
+		{StartFEN, "Zz9"},
+		{StartFEN, "x"},
+		{"8/P6k/8/8/8/8/8/K7 w - - 0 1", "a8"}, // Promotion piece missing
+	}
+	for _, e := range errors {
+		if m, err := ParseSAN(NewArrayBoard(e.fen), e.san); err == nil {
+			t.Errorf("ParseSAN(%s, %s) = %s, want an error", e.fen, e.san, FormatMove(m))
+		}
+	}
+}
@@ -2,59 +2,187 @@ package config
 
 import (
 	"encoding/json"
-	"log"
+	"flag"
+	"fmt"
 	"os"
+	"strconv"
 )
 
+// DefaultFile is the configuration file used when none is given.
+const DefaultFile = "config.json"
+
 // Config holds all application configuration.
 type Config struct {
 	BoardRepresentation string `json:"board_representation"`
+	// OwnBook makes the engine play from the Polyglot book in BookFile.
+	OwnBook  bool   `json:"own_book"`
+	BookFile string `json:"book_file"`
+	// EvalParams is a JSON file of evaluation weights, e.g. from the tuner;
+	// empty means the built-in weights.
+	EvalParams string `json:"eval_params"`
+	// LogFile is appended to; "stderr" logs to standard error. Empty means
+	// uci.log for the UCI engine, which owns standard output, and standard
+	// error for the other commands.
+	LogFile string `json:"log_file"`
+	// LogLevel is "debug" (including the UCI traffic), "info", "warn" or "error".
+	LogLevel string `json:"log_level"`
+	LogJSON  bool   `json:"log_json"`
 }
 
-// AppConfig is the global configuration instance.
-var AppConfig Config
-
-// init runs before main() and loads the configuration from disk.
-func init() {
-	configFile := "config.json"
+// Default returns the configuration used for settings that are not given.
+func Default() Config {
+	return Config{
+		BoardRepresentation: "array", // Default to the stable version
+		LogLevel:            "info",
+	}
+}
 
-	// Attempt to read the file.
-	f, err := os.Open(configFile)
+// Load reads the configuration from a JSON file. Settings missing from the
+// file keep their default values.
+func Load(path string) (Config, error) {
+	cfg := Default()
+	f, err := os.Open(path)
 	if err != nil {
-		// If it doesn't exist, create a default one.
-		log.Println("INFO: config.json not found, creating a default one.")
-		createDefaultConfig(configFile)
-		// Try opening again
-		f, err = os.Open(configFile)
-		if err != nil {
-			log.Fatalf("FATAL: Could not create or open config file: %v", err)
-		}
+		return cfg, err
 	}
 	defer f.Close()
 
-	// Decode the JSON into our AppConfig struct
-	decoder := json.NewDecoder(f)
-	err = decoder.Decode(&AppConfig)
-	if err != nil {
-		log.Fatalf("FATAL: Could not parse config.json: %v", err)
+	if err := json.NewDecoder(f).Decode(&cfg); err != nil {
+		return cfg, fmt.Errorf("config: could not parse %s: %v", path, err)
 	}
-	log.Printf("INFO: Loaded config. Board representation set to '%s'", AppConfig.BoardRepresentation)
+	return cfg, cfg.Validate()
 }
 
-func createDefaultConfig(filename string) {
-	defaultConfig := Config{
-		BoardRepresentation: "array", // Default to the stable version
+// Validate checks that the settings have allowed values.
+func (c Config) Validate() error {
+	switch c.BoardRepresentation {
+	case "array", "bitboard":
+	default:
+		return fmt.Errorf("config: unknown board representation %q", c.BoardRepresentation)
 	}
-	f, err := os.Create(filename)
-	if err != nil {
-		log.Fatalf("FATAL: Could not create default config file: %v", err)
+	switch c.LogLevel {
+	case "debug", "info", "warn", "error":
+	default:
+		return fmt.Errorf("config: unknown log level %q", c.LogLevel)
 	}
-	defer f.Close()
+	return nil
+}
 
-	encoder := json.NewEncoder(f)
-	encoder.SetIndent("", "  ")
-	err = encoder.Encode(defaultConfig)
-	if err != nil {
-		log.Fatalf("FATAL: Could not write to default config file: %v", err)
+// Environment variables overriding the configuration file.
+const (
+	EnvFile                = "CHESS_CONFIG"
+	EnvBoardRepresentation = "CHESS_BOARD_REPRESENTATION"
+	EnvOwnBook             = "CHESS_OWN_BOOK"
+	EnvBookFile            = "CHESS_BOOK_FILE"
+	EnvEvalParams          = "CHESS_EVAL_PARAMS"
+	EnvLogFile             = "CHESS_LOG_FILE"
+	EnvLogLevel            = "CHESS_LOG_LEVEL"
+	EnvLogJSON             = "CHESS_LOG_JSON"
+)
+
+// ApplyEnv overrides settings with the environment variables that are set,
+// looked up with getenv (usually os.Getenv).
+func (c *Config) ApplyEnv(getenv func(string) string) error {
+	if v := getenv(EnvBoardRepresentation); v != "" {
+		c.BoardRepresentation = v
+	}
+	if v := getenv(EnvOwnBook); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("config: %s: %v", EnvOwnBook, err)
+		}
+		c.OwnBook = b
+	}
+	if v := getenv(EnvBookFile); v != "" {
+		c.BookFile = v
 	}
+	if v := getenv(EnvEvalParams); v != "" {
+		c.EvalParams = v
+	}
+	if v := getenv(EnvLogFile); v != "" {
+		c.LogFile = v
+	}
+	if v := getenv(EnvLogLevel); v != "" {
+		c.LogLevel = v
+	}
+	if v := getenv(EnvLogJSON); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("config: %s: %v", EnvLogJSON, err)
+		}
+		c.LogJSON = b
+	}
+	return c.Validate()
+}
+
+// Flags are the command-line flags overriding the configuration, shared
+// by every command of the program.
+type Flags struct {
+	fs       *flag.FlagSet
+	path     *string
+	board    *string
+	ownBook  *bool
+	bookFile *string
+	params   *string
+	logFile  *string
+	logLevel *string
+	logJSON  *bool
+}
+
+// AddFlags defines the configuration flags on fs.
+func AddFlags(fs *flag.FlagSet) *Flags {
+	return &Flags{
+		fs:       fs,
+		path:     fs.String("config", "", "configuration file (default $"+EnvFile+" or "+DefaultFile+")"),
+		board:    fs.String("board", "", "board representation: array or bitboard"),
+		ownBook:  fs.Bool("own-book", false, "play opening moves from the book file"),
+		bookFile: fs.String("book", "", "Polyglot opening book file"),
+		params:   fs.String("eval-params", "", "JSON file of evaluation weights"),
+		logFile:  fs.String("log", "", "log file, or stderr"),
+		logLevel: fs.String("log-level", "", "log level: debug, info, warn or error"),
+		logJSON:  fs.Bool("log-json", false, "log as JSON"),
+	}
+}
+
+// Load builds the configuration from, in increasing priority, the
+// defaults, the configuration file, the environment and the flags. It must
+// be called after the flag set has been parsed.
+//
+// The file is the -config flag, else $CHESS_CONFIG, else DefaultFile; a
+// missing DefaultFile is not an error.
+func (f *Flags) Load(getenv func(string) string) (Config, error) {
+	file, explicit := *f.path, true
+	if file == "" {
+		file = getenv(EnvFile)
+	}
+	if file == "" {
+		file, explicit = DefaultFile, false
+	}
+	cfg, err := Load(file)
+	if err != nil && (explicit || !os.IsNotExist(err)) {
+		return cfg, err
+	}
+	if err := cfg.ApplyEnv(getenv); err != nil {
+		return cfg, err
+	}
+
+	f.fs.Visit(func(fl *flag.Flag) {
+		switch fl.Name {
+		case "board":
+			cfg.BoardRepresentation = *f.board
+		case "own-book":
+			cfg.OwnBook = *f.ownBook
+		case "book":
+			cfg.BookFile = *f.bookFile
+		case "eval-params":
+			cfg.EvalParams = *f.params
+		case "log":
+			cfg.LogFile = *f.logFile
+		case "log-level":
+			cfg.LogLevel = *f.logLevel
+		case "log-json":
+			cfg.LogJSON = *f.logJSON
+		}
+	})
+	return cfg, cfg.Validate()
 }
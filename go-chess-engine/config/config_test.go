@@ -0,0 +1,164 @@
+package config
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeFile writes data to name in a new temporary directory and returns
+// its path.
+func writeFile(t *testing.T, name, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// env returns a getenv function reading from vars.
+func env(vars map[string]string) func(string) string {
+	return func(key string) string { return vars[key] }
+}
+
+func TestLoad(t *testing.T) {
+	partial := Default()
+	partial.BoardRepresentation, partial.OwnBook, partial.BookFile = "bitboard", true, "book.bin"
+
+	tests := []struct {
+		name, data string
+		want       Config
+		err        string
+	}{
+		{"empty object", `{}`, Default(), ""},
+		{"partial", `{"board_representation": "bitboard", "own_book": true, "book_file": "book.bin"}`, partial, ""},
+		{"malformed", `{"board_representation": `, Config{}, "could not parse"},
+		{"wrong type", `{"own_book": "yes"}`, Config{}, "could not parse"},
+		{"bad board", `{"board_representation": "list"}`, Config{}, `unknown board representation "list"`},
+		{"bad log level", `{"log_level": "verbose"}`, Config{}, `unknown log level "verbose"`},
+	}
+	for _, tt := range tests {
+		cfg, err := Load(writeFile(t, "config.json", tt.data))
+		switch {
+		case tt.err != "":
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%s: error %v, want %q", tt.name, err, tt.err)
+			}
+		case err != nil:
+			t.Errorf("%s: %v", tt.name, err)
+		case cfg != tt.want:
+			t.Errorf("%s: %+v, want %+v", tt.name, cfg, tt.want)
+		}
+	}
+
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.json")); !os.IsNotExist(err) {
+		t.Errorf("missing file: error %v, want one for a missing file", err)
+	}
+}
+
+func TestApplyEnv(t *testing.T) {
+	all := Config{
+		BoardRepresentation: "bitboard", OwnBook: true, BookFile: "book.bin", EvalParams: "params.json",
+		LogFile: "stderr", LogLevel: "debug", LogJSON: true,
+	}
+	tests := []struct {
+		name string
+		vars map[string]string
+		want Config
+		err  string
+	}{
+		{"none", nil, Default(), ""},
+		{"all", map[string]string{
+			EnvBoardRepresentation: "bitboard", EnvOwnBook: "true", EnvBookFile: "book.bin",
+			EnvEvalParams: "params.json", EnvLogFile: "stderr", EnvLogLevel: "debug", EnvLogJSON: "1",
+		}, all, ""},
+		{"bad bool", map[string]string{EnvOwnBook: "yes"}, Config{}, EnvOwnBook},
+		{"bad json flag", map[string]string{EnvLogJSON: "on"}, Config{}, EnvLogJSON},
+		{"bad board", map[string]string{EnvBoardRepresentation: "list"}, Config{}, "unknown board representation"},
+		{"bad log level", map[string]string{EnvLogLevel: "loud"}, Config{}, "unknown log level"},
+	}
+	for _, tt := range tests {
+		cfg := Default()
+		err := cfg.ApplyEnv(env(tt.vars))
+		switch {
+		case tt.err != "":
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%s: error %v, want %q", tt.name, err, tt.err)
+			}
+		case err != nil:
+			t.Errorf("%s: %v", tt.name, err)
+		case cfg != tt.want:
+			t.Errorf("%s: %+v, want %+v", tt.name, cfg, tt.want)
+		}
+	}
+}
+
+// TestFlagsLoad checks that flags override the environment, which overrides
+// the file, which overrides the defaults.
+func TestFlagsLoad(t *testing.T) {
+	file := writeFile(t, "engine.json", `{
+		"board_representation": "bitboard",
+		"book_file": "file.bin",
+		"eval_params": "file.json",
+		"log_level": "warn",
+		"own_book": true
+	}`)
+	tests := []struct {
+		name string
+		args []string
+		vars map[string]string
+		want Config
+		err  string
+	}{
+		{"defaults", nil, nil, Default(), ""},
+		{"file", []string{"-config", file}, nil,
+			Config{BoardRepresentation: "bitboard", BookFile: "file.bin", EvalParams: "file.json", LogLevel: "warn", OwnBook: true}, ""},
+		{"file from the environment", nil, map[string]string{EnvFile: file},
+			Config{BoardRepresentation: "bitboard", BookFile: "file.bin", EvalParams: "file.json", LogLevel: "warn", OwnBook: true}, ""},
+		{"environment over file", []string{"-config", file},
+			map[string]string{EnvBookFile: "env.bin", EnvLogLevel: "error", EnvOwnBook: "false"},
+			Config{BoardRepresentation: "bitboard", BookFile: "env.bin", EvalParams: "file.json", LogLevel: "error"}, ""},
+		{"flags over environment", []string{"-config", file, "-book", "flag.bin", "-own-book=true", "-board", "array", "-log", "x.log"},
+			map[string]string{EnvBookFile: "env.bin", EnvLogLevel: "error", EnvOwnBook: "false"},
+			Config{BoardRepresentation: "array", BookFile: "flag.bin", EvalParams: "file.json", LogLevel: "error", OwnBook: true, LogFile: "x.log"}, ""},
+		// The -config flag wins over $CHESS_CONFIG.
+		{"config flag", []string{"-config", file}, map[string]string{EnvFile: "missing.json"},
+			Config{BoardRepresentation: "bitboard", BookFile: "file.bin", EvalParams: "file.json", LogLevel: "warn", OwnBook: true}, ""},
+		{"missing file", []string{"-config", "missing.json"}, nil, Config{}, "missing.json"},
+		{"missing file from the environment", nil, map[string]string{EnvFile: "missing.json"}, Config{}, "missing.json"},
+		{"bad flag value", []string{"-log-level", "loud"}, nil, Config{}, "unknown log level"},
+		{"bad environment", []string{"-board", "array"}, map[string]string{EnvBoardRepresentation: "list"}, Config{}, "unknown board representation"},
+	}
+	// Without a file given, the missing DefaultFile is no error.
+	t.Chdir(t.TempDir())
+	for _, tt := range tests {
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		flags := AddFlags(fs)
+		if err := fs.Parse(tt.args); err != nil {
+			t.Fatal(err)
+		}
+		cfg, err := flags.Load(env(tt.vars))
+		switch {
+		case tt.err != "":
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%s: error %v, want %q", tt.name, err, tt.err)
+			}
+		case err != nil:
+			t.Errorf("%s: %v", tt.name, err)
+		case cfg != tt.want:
+			t.Errorf("%s: %+v, want %+v", tt.name, cfg, tt.want)
+		}
+	}
+
+	// DefaultFile is read when present.
+	if err := os.WriteFile(DefaultFile, []byte(`{"board_representation": "bitboard"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	flags := AddFlags(flag.NewFlagSet("test", flag.ContinueOnError))
+	if cfg, err := flags.Load(env(nil)); err != nil || cfg.BoardRepresentation != "bitboard" {
+		t.Errorf("with %s: %+v, %v, want the bitboard", DefaultFile, cfg, err)
+	}
+}
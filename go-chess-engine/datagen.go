@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"go-chess-engine/config"
+	"go-chess-engine/datagen"
+	"io"
+	"os"
+	"runtime"
+	"time"
+)
+
+// datagenCommand implements "datagen [flags]": it plays engine self-play
+// games with a fixed number of nodes per move and writes their quiet
+// positions, with score and result, as text lines or binary records.
+func datagenCommand(fs *flag.FlagSet) func(config.Config, []string) int {
+	games := fs.Int("games", 100, "number of games")
+	nodes := fs.Int("nodes", 5000, "nodes searched per move")
+	randomPlies := fs.Int("random-plies", 8, "random legal moves played before the engine takes over")
+	seed := fs.Int64("seed", time.Now().UnixNano(), "seed for the random openings")
+	concurrency := fs.Int("concurrency", runtime.NumCPU(), "number of games played at once")
+	maxPlies := fs.Int("max-plies", 400, "adjudicate a draw after this many plies (0 = never)")
+	resignScore := fs.Int("resign-score", 1000, "score in centipawns that adjudicates a win ...")
+	resignPlies := fs.Int("resign-plies", 8, "... when held for this many plies (0 = never)")
+	format := fs.String("format", "text", `output format: "text" (FEN | score | result) or "binary" (32-byte records)`)
+	out := fs.String("out", "", "output file (default standard output)")
+	return func(cfg config.Config, args []string) int {
+		if len(args) > 0 {
+			fs.Usage()
+			return exitUsage
+		}
+		if *format != "text" && *format != "binary" {
+			fmt.Fprintf(os.Stderr, "bad -format %q: want text or binary\n", *format)
+			return exitUsage
+		}
+
+		var w io.Writer = os.Stdout
+		if *out != "" {
+			f, err := os.Create(*out)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return exitFailure
+			}
+			defer f.Close()
+			w = f
+		}
+		bw := bufio.NewWriter(w)
+
+		played := 0
+		var buf []byte
+		count, err := datagen.Run(datagen.Config{
+			Games:       *games,
+			Nodes:       *nodes,
+			RandomPlies: *randomPlies,
+			Seed:        *seed,
+			Board:       cfg.BoardRepresentation,
+			Concurrency: *concurrency,
+			MaxPlies:    *maxPlies,
+			ResignScore: *resignScore,
+			ResignPlies: *resignPlies,
+			OnGame: func(records []datagen.Record) error {
+				for _, r := range records {
+					if *format == "text" {
+						buf = append(buf[:0], r.Text()+"\n"...)
+					} else {
+						var err error
+						if buf, err = r.AppendBinary(buf[:0]); err != nil {
+							return err
+						}
+					}
+					if _, err := bw.Write(buf); err != nil {
+						return err
+					}
+				}
+				played++
+				fmt.Fprintf(os.Stderr, "\rGame %d of %d", played, *games)
+				return nil
+			},
+		})
+		fmt.Fprintln(os.Stderr)
+		if err == nil {
+			err = bw.Flush()
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitFailure
+		}
+		fmt.Fprintf(os.Stderr, "%d positions from %d games, seed %d\n", count, played, *seed)
+		return exitOK
+	}
+}
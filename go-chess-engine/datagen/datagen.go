@@ -0,0 +1,164 @@
+// Package datagen generates training data for the evaluation: positions
+// from engine self-play games, each with the engine's score and the result
+// of its game.
+package datagen
+
+import (
+	"go-chess-engine/chess"
+	"go-chess-engine/engine"
+	"go-chess-engine/game"
+	"math/rand"
+	"sync"
+)
+
+// Config controls the self-play games.
+type Config struct {
+	// Games is the number of games to play.
+	Games int
+	// Nodes limits the engine's search for every move.
+	Nodes int
+	// RandomPlies random legal moves are played from the start position
+	// before the engine takes over, so that games differ. Their positions
+	// are not recorded.
+	RandomPlies int
+	// Seed seeds the openings; game i uses Seed+i, so the data does not
+	// depend on Concurrency.
+	Seed int64
+	// Board is the representation the games are played on.
+	Board string
+	// Concurrency is the number of games played at once.
+	Concurrency int
+
+	// MaxPlies adjudicates a draw after this many plies (0 = no limit).
+	MaxPlies int
+	// A game is adjudicated as won once the engine scores it at least
+	// ResignScore for the same side for ResignPlies plies in a row
+	// (0 = never).
+	ResignScore, ResignPlies int
+
+	// OnGame is called with the records of every finished game, one call
+	// at a time. An error stops the generation.
+	OnGame func(records []Record) error
+}
+
+// Run plays cfg.Games games and returns the number of records passed to
+// cfg.OnGame, stopping at the first error it returns.
+func Run(cfg Config) (int, error) {
+	if _, err := chess.NewBoard(cfg.Board, chess.StartFEN); err != nil {
+		return 0, err
+	}
+	next := make(chan int)
+	var mu sync.Mutex
+	var count int
+	var firstErr error
+	var wg sync.WaitGroup
+	for w := 0; w < max(cfg.Concurrency, 1); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			e := engine.New()
+			e.Depth, e.Nodes = 64, cfg.Nodes
+			for i := range next {
+				records := playGame(cfg, e, rand.New(rand.NewSource(cfg.Seed+int64(i))))
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = cfg.OnGame(records)
+					count += len(records)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for i := 0; i < cfg.Games; i++ {
+		mu.Lock()
+		failed := firstErr != nil
+		mu.Unlock()
+		if failed {
+			break
+		}
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+	return count, firstErr
+}
+
+// playGame plays one game and returns its quiet positions.
+func playGame(cfg Config, e *engine.Engine, rng *rand.Rand) []Record {
+	g := randomOpening(cfg, rng)
+	var records []Record
+	var score int
+	e.OnInfo = func(info engine.Info) {
+		if info.MultiPV == 1 {
+			score = info.Score
+		}
+	}
+
+	// winning is the side the score has favoured for streak plies: 1 for
+	// White, -1 for Black, 0 for neither.
+	result, winning, streak := "", 0, 0
+	for result == "" {
+		if result, _ = g.Outcome(); result != "" {
+			break
+		}
+		b := g.Board()
+		m := e.FindBestMove(b.Clone())
+		white := score
+		if b.SideToMove() == chess.Black {
+			white = -score
+		}
+
+		pos := b.Position()
+		g.Play(m)
+		// Positions in check, with a capture or promotion to play or a mate
+		// on the board have a score the static evaluation cannot match.
+		tactical := g.Board().Captured() != chess.Empty || m.Promotion != chess.Empty
+		if !b.InCheck() && !tactical && !engine.IsMateScore(score) {
+			records = append(records, Record{Position: pos, Score: white})
+		}
+
+		side := 0
+		if cfg.ResignPlies > 0 && white >= cfg.ResignScore {
+			side = 1
+		} else if cfg.ResignPlies > 0 && white <= -cfg.ResignScore {
+			side = -1
+		}
+		if side != winning {
+			winning, streak = side, 0
+		}
+		streak++
+		switch {
+		case winning == 1 && streak >= cfg.ResignPlies:
+			result = "1-0"
+		case winning == -1 && streak >= cfg.ResignPlies:
+			result = "0-1"
+		case cfg.MaxPlies > 0 && len(g.Moves()) >= cfg.MaxPlies:
+			result = "1/2-1/2"
+		}
+	}
+
+	value := map[string]float64{"1-0": 1, "1/2-1/2": 0.5, "0-1": 0}[result]
+	for i := range records {
+		records[i].Result = value
+	}
+	return records
+}
+
+// randomOpening plays cfg.RandomPlies random legal moves from the start
+// position, retrying when the game would end before the engine plays.
+func randomOpening(cfg Config, rng *rand.Rand) *game.Game {
+	for {
+		b, _ := chess.NewBoard(cfg.Board, chess.StartFEN) // Run checked the representation
+		g := game.New(b)
+		for len(g.Moves()) < cfg.RandomPlies {
+			legal := g.Board().GenerateLegalMoves()
+			if len(legal) == 0 {
+				break
+			}
+			g.Play(legal[rng.Intn(len(legal))])
+		}
+		if result, _ := g.Outcome(); result == "" && len(g.Moves()) == cfg.RandomPlies {
+			return g
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package datagen
+
+import (
+	"go-chess-engine/chess"
+	"go-chess-engine/engine"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+var smokeConfig = Config{
+	Nodes:       200,
+	RandomPlies: 6,
+	Board:       "bitboard",
+	MaxPlies:    40,
+	ResignScore: 400,
+	ResignPlies: 4,
+}
+
+// TestPlayGame plays short games and checks their records.
+func TestPlayGame(t *testing.T) {
+	for seed := int64(0); seed < 3; seed++ {
+		records := playSmokeGame(seed)
+		if len(records) == 0 {
+			t.Errorf("seed %d: no records", seed)
+		}
+		for _, r := range records {
+			b := chess.NewBitboardFromPosition(r.Position)
+			switch {
+			case r.Result != records[0].Result || (r.Result != 0 && r.Result != 0.5 && r.Result != 1):
+				t.Errorf("seed %d: %s: result %g in a game with %g", seed, r.Text(), r.Result, records[0].Result)
+			case r.Position.Validate() != nil || b.InCheck():
+				t.Errorf("seed %d: %s is not a quiet legal position", seed, r.Text())
+			case engine.IsMateScore(r.Score):
+				t.Errorf("seed %d: %s has a mate score", seed, r.Text())
+			}
+		}
+		// The opening moves are not recorded.
+		if len(records) > 0 && records[0].Position.FullmoveNumber < 4 {
+			t.Errorf("seed %d: first record %s is within the opening", seed, records[0].Text())
+		}
+		if again := playSmokeGame(seed); !reflect.DeepEqual(again, records) {
+			t.Errorf("seed %d: a second game differs", seed)
+		}
+	}
+}
+
+func playSmokeGame(seed int64) []Record {
+	e := engine.New()
+	e.Depth, e.Nodes = 64, smokeConfig.Nodes
+	return playGame(smokeConfig, e, rand.New(rand.NewSource(seed)))
+}
+
+func TestRun(t *testing.T) {
+	cfg := smokeConfig
+	cfg.Games, cfg.Concurrency = 3, 2
+	var games [][]Record
+	cfg.OnGame = func(records []Record) error {
+		games = append(games, records)
+		return nil
+	}
+	n, err := Run(cfg)
+	if err != nil || len(games) != 3 {
+		t.Fatalf("Run = %d, %v after %d games", n, err, len(games))
+	}
+	total := 0
+	for _, g := range games {
+		total += len(g)
+	}
+	if n != total {
+		t.Errorf("Run counted %d records, OnGame received %d", n, total)
+	}
+
+	cfg.Board = "list"
+	if _, err := Run(cfg); err == nil {
+		t.Error("Run accepted an unknown board representation")
+	}
+}
@@ -0,0 +1,117 @@
+package datagen
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"go-chess-engine/chess"
+	"io"
+	"math/bits"
+	"strconv"
+)
+
+// Record is a position from a self-play game with the engine's score and
+// the game's result, both from White's point of view.
+type Record struct {
+	Position chess.Position
+	Score    int     // Centipawns
+	Result   float64 // 1 if White won, 0.5 for a draw, 0 if Black won
+}
+
+// Text writes the record as "<FEN> | <score> | <result>", which the tuner
+// reads.
+func (r Record) Text() string {
+	return fmt.Sprintf("%s | %d | %s", r.Position.FEN(), r.Score, strconv.FormatFloat(r.Result, 'f', 1, 64))
+}
+
+// RecordSize is the length of a record in the binary format.
+const RecordSize = 32
+
+// AppendBinary appends the record in the binary format, all integers little
+// endian:
+//
+//	uint64   occupied squares, bit 0 = a1
+//	[16]byte the piece on each occupied square in ascending order, four
+//	         bits each (a chess.Piece), the first in the low bits
+//	byte     side to move (0 White, 1 Black) | castling rights << 1
+//	byte     en passant square, 64 if none
+//	int16    score
+//	byte     result: 0 Black won, 1 draw, 2 White won
+//	byte     halfmove clock
+//	uint16   fullmove number
+//
+// Castling rights refer to the standard rook squares, so Chess960
+// positions are not supported.
+func (r Record) AppendBinary(b []byte) ([]byte, error) {
+	p := r.Position
+	if p.Chess960 {
+		return b, errors.New("datagen: Chess960 positions are not supported")
+	}
+	var occupied uint64
+	var pieces [16]byte
+	n := 0
+	for sq, piece := range p.Pieces {
+		if piece == chess.Empty {
+			continue
+		}
+		if n == 32 {
+			return b, errors.New("datagen: more than 32 pieces")
+		}
+		occupied |= 1 << sq
+		pieces[n/2] |= byte(piece) << (4 * (n % 2))
+		n++
+	}
+	ep := byte(64)
+	if p.EnPassant != chess.NoSquare {
+		ep = byte(p.EnPassant)
+	}
+	b = binary.LittleEndian.AppendUint64(b, occupied)
+	b = append(b, pieces[:]...)
+	b = append(b, byte(p.SideToMove)|byte(p.Castling)<<1, ep)
+	b = binary.LittleEndian.AppendUint16(b, uint16(int16(max(min(r.Score, 32767), -32767))))
+	b = append(b, byte(2*r.Result), byte(min(p.HalfmoveClock, 255)))
+	b = binary.LittleEndian.AppendUint16(b, uint16(min(p.FullmoveNumber, 65535)))
+	return b, nil
+}
+
+// ReadBinary reads the next record in the binary format. It returns io.EOF
+// at the end of the input and an error for a corrupt record.
+func ReadBinary(r io.Reader) (Record, error) {
+	var buf [RecordSize]byte
+	if _, err := io.ReadFull(r, buf[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			err = errors.New("datagen: truncated record")
+		}
+		return Record{}, err
+	}
+
+	p := chess.Position{EnPassant: chess.NoSquare, CastlingRooks: chess.MustParseFEN(chess.StartFEN).CastlingRooks}
+	occupied := binary.LittleEndian.Uint64(buf[:8])
+	for n := 0; occupied != 0; n++ {
+		if n == 32 {
+			return Record{}, errors.New("datagen: more than 32 pieces")
+		}
+		sq := bits.TrailingZeros64(occupied)
+		occupied &= occupied - 1
+		piece := chess.Piece(buf[8+n/2] >> (4 * (n % 2)) & 15)
+		if piece == chess.Empty || piece > chess.BlackKing {
+			return Record{}, fmt.Errorf("datagen: bad piece %d", piece)
+		}
+		p.Pieces[sq] = piece
+	}
+	if buf[28] > 2 {
+		return Record{}, fmt.Errorf("datagen: bad result %d", buf[28])
+	}
+	p.SideToMove = chess.Color(buf[24] & 1)
+	p.Castling = chess.CastlingRights(buf[24] >> 1)
+	if buf[25] < 64 {
+		p.EnPassant = int(buf[25])
+	}
+	p.HalfmoveClock = int(buf[29])
+	p.FullmoveNumber = int(binary.LittleEndian.Uint16(buf[30:]))
+	return Record{
+		Position: p,
+		Score:    int(int16(binary.LittleEndian.Uint16(buf[26:]))),
+		Result:   float64(buf[28]) / 2,
+	}, nil
+}
@@ -0,0 +1,94 @@
+package datagen
+
+import (
+	"bytes"
+	"encoding/binary"
+	"go-chess-engine/chess"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestBinaryRoundTrip(t *testing.T) {
+	records := []Record{
+		{chess.MustParseFEN(chess.StartFEN), 20, 0.5},
+		{chess.MustParseFEN("r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w Kq - 3 17"), -140, 0},
+		{chess.MustParseFEN("rnbqkbnr/ppp1p1pp/8/3pPp2/8/8/PPPP1PPP/RNBQKBNR w KQkq f6 0 3"), 35, 1},
+		{chess.MustParseFEN("8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 b - - 99 300"), 0, 0.5},
+	}
+	var data []byte
+	for _, r := range records {
+		var err error
+		if data, err = r.AppendBinary(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(data) != len(records)*RecordSize {
+		t.Fatalf("%d bytes for %d records", len(data), len(records))
+	}
+
+	in := bytes.NewReader(data)
+	for _, want := range records {
+		got, err := ReadBinary(in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Text() != want.Text() {
+			t.Errorf("read %s, want %s", got.Text(), want.Text())
+		}
+	}
+	if _, err := ReadBinary(in); err != io.EOF {
+		t.Errorf("after the last record: %v, want EOF", err)
+	}
+
+	// Scores are clamped to 16 bits.
+	for score, want := range map[int]int{40000: 32767, -40000: -32767} {
+		data, _ := Record{chess.MustParseFEN(chess.StartFEN), score, 1}.AppendBinary(nil)
+		if got, err := ReadBinary(bytes.NewReader(data)); err != nil || got.Score != want {
+			t.Errorf("score %d read as %d, %v, want %d", score, got.Score, err, want)
+		}
+	}
+
+	p := chess.MustParseFEN("bqnb1rkr/pp3ppp/3ppn2/2p5/5P2/P2P4/NPP1P1PP/BQ1BNRKR w HFhf - 2 9")
+	if _, err := (Record{Position: p}).AppendBinary(nil); err == nil {
+		t.Error("AppendBinary wrote a Chess960 position")
+	}
+}
+
+func TestReadBinaryCorrupt(t *testing.T) {
+	good, err := Record{chess.MustParseFEN(chess.StartFEN), 20, 0.5}.AppendBinary(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// corrupt returns the good record changed by f.
+	corrupt := func(f func(b []byte)) []byte {
+		b := bytes.Clone(good)
+		f(b)
+		return b
+	}
+	// pawns fills the squares of occupied with white pawns, the bytes
+	// after the record's pieces included.
+	pawns := func(b []byte, occupied uint64) {
+		binary.LittleEndian.PutUint64(b, occupied)
+		for i := 8; i < len(b); i++ {
+			b[i] = byte(chess.WhitePawn) * 0x11
+		}
+	}
+	tests := []struct {
+		name string
+		data []byte
+		err  string
+	}{
+		{"truncated", good[:20], "truncated record"},
+		{"33 pieces", corrupt(func(b []byte) { pawns(b, 0xFFFF0001FFFF) }), "more than 32 pieces"},
+		{"64 pieces", corrupt(func(b []byte) { pawns(b, ^uint64(0)) }), "more than 32 pieces"},
+		{"empty square", corrupt(func(b []byte) { b[8] &^= 15 }), "bad piece 0"},
+		{"no such piece", corrupt(func(b []byte) { b[8] |= 15 }), "bad piece 15"},
+		{"result", corrupt(func(b []byte) { b[28] = 3 }), "bad result 3"},
+	}
+	for _, tt := range tests {
+		if _, err := ReadBinary(bytes.NewReader(tt.data)); err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s: error %v, want %q", tt.name, err, tt.err)
+		}
+	}
+}
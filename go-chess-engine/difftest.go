@@ -0,0 +1,37 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go-chess-engine/chess"
+	"go-chess-engine/config"
+	"go-chess-engine/difftest"
+	"time"
+)
+
+// difftestCommand implements "difftest [-games N] [-plies N] [-seed S]": it
+// plays random games on the array board and the bitboard in lockstep and
+// prints every divergence as a minimized reproducer. It fails when the
+// representations disagree.
+func difftestCommand(fs *flag.FlagSet) func(config.Config, []string) int {
+	games := fs.Int("games", 1000, "number of random games")
+	plies := fs.Int("plies", 200, "maximum length of each game")
+	seed := fs.Int64("seed", time.Now().UnixNano(), "random seed")
+	return func(cfg config.Config, args []string) int {
+		if len(args) > 0 {
+			fs.Usage()
+			return exitUsage
+		}
+		newArray := func(fen string) chess.Board { return chess.NewArrayBoard(fen) }
+		newBitboard := func(fen string) chess.Board { return chess.NewBitboard(fen) }
+		found := difftest.Run(newArray, newBitboard, *games, *plies, *seed)
+		for _, d := range found {
+			fmt.Println(d)
+		}
+		fmt.Printf("%d games, seed %d: %d divergences (a = array, b = bitboard)\n", *games, *seed, len(found))
+		if len(found) > 0 {
+			return exitFailure
+		}
+		return exitOK
+	}
+}
@@ -0,0 +1,260 @@
+// Package difftest plays games on two board representations in lockstep and
+// reports the first point where they disagree about the legal moves, the
+// game status or the FEN, minimized to a short reproducer.
+package difftest
+
+import (
+	"fmt"
+	"go-chess-engine/chess"
+	"math/rand"
+	"sort"
+	"strings"
+)
+
+// NewBoard creates a board of one representation from a FEN.
+type NewBoard func(fen string) chess.Board
+
+// Divergence is a position where the two boards disagree. Replaying Moves
+// from FEN on fresh boards reproduces it.
+type Divergence struct {
+	FEN   string
+	Moves []chess.Move
+	// What is the compared property: "legal moves", "status" or "fen".
+	What string
+	// A and B are the answers of the two boards. For legal moves they are
+	// the moves only that board generates.
+	A, B string
+}
+
+func (d *Divergence) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s differ\n  fen:   %s\n", d.What, d.FEN)
+	if len(d.Moves) > 0 {
+		sb.WriteString("  moves:")
+		for _, m := range d.Moves {
+			sb.WriteString(" " + chess.FormatMove(m))
+		}
+		sb.WriteString("\n")
+	}
+	fmt.Fprintf(&sb, "  a:     %s\n  b:     %s", d.A, d.B)
+	return sb.String()
+}
+
+// Compare checks two boards that should hold the same position and returns
+// the first property they disagree on, or nil.
+func Compare(a, b chess.Board) *Divergence {
+	if onlyA, onlyB := moveDiff(a, b); onlyA != "" || onlyB != "" {
+		return &Divergence{What: "legal moves", A: onlyA, B: onlyB}
+	}
+	if sa, sb := status(a), status(b); sa != sb {
+		return &Divergence{What: "status", A: sa, B: sb}
+	}
+	if fa, fb := a.ToFEN(), b.ToFEN(); fa != fb {
+		return &Divergence{What: "fen", A: fa, B: fb}
+	}
+	return nil
+}
+
+// Replay plays moves from fen on fresh boards of both representations and
+// compares them after every move. It returns the divergence with the moves
+// up to the point it showed, or nil. Moves are taken to be legal on a.
+func Replay(newA, newB NewBoard, fen string, moves []chess.Move) *Divergence {
+	a, b := newA(fen), newB(fen)
+	for i := 0; ; i++ {
+		if d := Compare(a, b); d != nil {
+			d.FEN, d.Moves = fen, moves[:i]
+			return d
+		}
+		if i == len(moves) {
+			return nil
+		}
+		a.ApplyMove(moves[i])
+		b.ApplyMove(moves[i])
+	}
+}
+
+// RandomGame plays up to plies random legal moves from fen, choosing each
+// with pick(n), which returns a number in [0, n). It stops at the first
+// divergence and returns it minimized.
+func RandomGame(newA, newB NewBoard, fen string, plies int, pick func(n int) int) *Divergence {
+	a := newA(fen)
+	var moves []chess.Move
+	for len(moves) < plies {
+		legal := a.GenerateLegalMoves()
+		if len(legal) == 0 {
+			break
+		}
+		sortMoves(legal)
+		m := legal[pick(len(legal))]
+		a.ApplyMove(m)
+		moves = append(moves, m)
+	}
+	d := Replay(newA, newB, fen, moves)
+	if d == nil {
+		return nil
+	}
+	return Minimize(newA, newB, d)
+}
+
+// Fuzz plays a random game from fen driven by data, one byte per move
+// choice. It is meant as the body of a fuzz target:
+//
+//	f.Fuzz(func(t *testing.T, fen string, data []byte) {
+//		if d := difftest.Fuzz(newArray, newBitboard, fen, data); d != nil {
+//			t.Fatal(d)
+//		}
+//	})
+func Fuzz(newA, newB NewBoard, fen string, data []byte) *Divergence {
+	i := 0
+	return RandomGame(newA, newB, fen, len(data), func(n int) int {
+		c := int(data[i])
+		i++
+		return c % n
+	})
+}
+
+// Run plays games random games of at most plies moves each and returns the
+// distinct divergences found.
+func Run(newA, newB NewBoard, games, plies int, seed int64) []*Divergence {
+	rng := rand.New(rand.NewSource(seed))
+	seen := make(map[string]bool)
+	var found []*Divergence
+	for g := 0; g < games; g++ {
+		d := RandomGame(newA, newB, chess.StartFEN, plies, rng.Intn)
+		if d == nil {
+			continue
+		}
+		if key := d.String(); !seen[key] {
+			seen[key] = true
+			found = append(found, d)
+		}
+	}
+	return found
+}
+
+// Minimize shortens a divergence: it restarts from the latest position that
+// still reproduces it, then removes every piece except the kings and resets
+// the en passant square and clocks where they are not needed to reproduce it.
+func Minimize(newA, newB NewBoard, d *Divergence) *Divergence {
+	// Restart from the FEN after as many moves as possible. The FEN may lose
+	// state the divergence depends on, so each start is checked.
+	a := newA(d.FEN)
+	fens := []string{d.FEN}
+	for _, m := range d.Moves {
+		a.ApplyMove(m)
+		fens = append(fens, a.ToFEN())
+	}
+	for i := len(d.Moves); i > 0; i-- {
+		if r := Replay(newA, newB, fens[i], d.Moves[i:]); r != nil && r.What == d.What {
+			d = r
+			break
+		}
+	}
+
+	// Remove pieces one at a time while the moves stay legal and the boards
+	// still disagree in the same way.
+	for removed := true; removed; {
+		removed = false
+		for sq := 0; sq < 64; sq++ {
+			fen, ok := withoutPiece(newA, d.FEN, sq)
+			if !ok || !legalOn(newA(fen), d.Moves) {
+				continue
+			}
+			if r := Replay(newA, newB, fen, d.Moves); r != nil && r.What == d.What && len(r.Moves) == len(d.Moves) {
+				d, removed = r, true
+			}
+		}
+	}
+
+	// Drop the en passant square and clocks unless they matter.
+	if p, err := chess.ParseFEN(d.FEN); err == nil {
+		p.EnPassant, p.HalfmoveClock, p.FullmoveNumber = chess.NoSquare, 0, 1
+		if r := Replay(newA, newB, p.FEN(), d.Moves); r != nil && r.What == d.What && len(r.Moves) == len(d.Moves) {
+			d = r
+		}
+	}
+	return d
+}
+
+// withoutPiece returns fen with the piece on sq removed. Kings, empty
+// squares and positions that would leave the side not to move in check
+// are refused.
+func withoutPiece(newBoard NewBoard, fen string, sq int) (string, bool) {
+	p, err := chess.ParseFEN(fen)
+	if err != nil {
+		return "", false
+	}
+	switch p.Pieces[sq] {
+	case chess.Empty, chess.WhiteKing, chess.BlackKing:
+		return "", false
+	}
+	p.Pieces[sq] = chess.Empty
+	// Castling needs its rook.
+	for i, rook := range p.CastlingRooks {
+		if sq == rook {
+			p.Castling &^= 1 << i
+		}
+	}
+
+	// The side that just moved must not be in check.
+	flipped := p
+	flipped.SideToMove = 1 - p.SideToMove
+	if newBoard(flipped.FEN()).InCheck() {
+		return "", false
+	}
+	return p.FEN(), true
+}
+
+func legalOn(b chess.Board, moves []chess.Move) bool {
+	for _, m := range moves {
+		ok := false
+		for _, l := range b.GenerateLegalMoves() {
+			ok = ok || l == m
+		}
+		if !ok {
+			return false
+		}
+		b.ApplyMove(m)
+	}
+	return true
+}
+
+// moveDiff returns the legal moves only a generates and those only b does.
+func moveDiff(a, b chess.Board) (onlyA, onlyB string) {
+	ma, mb := a.GenerateLegalMoves(), b.GenerateLegalMoves()
+	return missing(ma, mb), missing(mb, ma)
+}
+
+// missing formats the moves of x that are not in y, sorted.
+func missing(x, y []chess.Move) string {
+	in := make(map[chess.Move]bool, len(y))
+	for _, m := range y {
+		in[m] = true
+	}
+	var s []string
+	for _, m := range x {
+		if !in[m] {
+			s = append(s, chess.FormatMove(m))
+		}
+	}
+	sort.Strings(s)
+	return strings.Join(s, " ")
+}
+
+func sortMoves(moves []chess.Move) {
+	sort.Slice(moves, func(i, j int) bool {
+		return chess.FormatMove(moves[i]) < chess.FormatMove(moves[j])
+	})
+}
+
+func status(b chess.Board) string {
+	switch {
+	case b.IsCheckmate():
+		return "checkmate"
+	case b.IsStalemate():
+		return "stalemate"
+	case b.InCheck():
+		return "check"
+	}
+	return "normal"
+}
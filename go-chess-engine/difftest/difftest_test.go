@@ -0,0 +1,112 @@
+package difftest
+
+import (
+	"go-chess-engine/chess"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func newArray(fen string) chess.Board    { return chess.NewArrayBoard(fen) }
+func newBitboard(fen string) chess.Board { return chess.NewBitboard(fen) }
+
+// starts are the standard position, a few middlegames with castling and en
+// passant to play with, and Chess960 positions.
+var starts = []string{
+	chess.StartFEN,
+	"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+	"8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1",
+	"rnbqkb1r/pp1p1ppp/5n2/2pPp3/8/8/PPP1PPPP/RNBQKBNR w KQkq e6 0 4",
+	"bqnb1rkr/pp3ppp/3ppn2/2p5/5P2/P2P4/NPP1P1PP/BQ1BNRKR w HFhf - 2 9",
+	"2nnrbkr/p1qppppp/8/1ppb4/6PP/3PP3/PPP2P2/BQNNRBKR w HEhe - 1 9",
+	"qrbkrnnb/pppppppp/8/8/8/8/PPPPPPPP/QRBKRNNB w EBeb - 0 1",
+	"rkrnnqbb/pppppppp/8/8/8/8/PPPPPPPP/RKRNNQBB w CAca - 0 1",
+}
+
+func FuzzLockstep(f *testing.F) {
+	for i, fen := range starts {
+		f.Add(fen, []byte{byte(i), 7, 200, 31, 0, 99, 13, 255, 64, 5, 42, 17})
+	}
+	f.Fuzz(func(t *testing.T, fen string, data []byte) {
+		fen, ok := playable(fen)
+		if !ok {
+			t.Skip()
+		}
+		if d := Fuzz(newArray, newBitboard, fen, data); d != nil {
+			t.Fatal(d)
+		}
+	})
+}
+
+func TestRandomGames(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for _, fen := range starts {
+		for g := 0; g < 10; g++ {
+			if d := RandomGame(newArray, newBitboard, fen, 200, rng.Intn); d != nil {
+				t.Fatalf("game %d from %s:\n%s", g, fen, d)
+			}
+		}
+	}
+	if found := Run(newArray, newBitboard, 20, 200, 1); len(found) > 0 {
+		t.Fatalf("%d divergences, first:\n%s", len(found), found[0])
+	}
+}
+
+// noCastling is a board that has lost its castling moves.
+type noCastling struct{ chess.Board }
+
+func (b noCastling) GenerateLegalMoves() []chess.Move {
+	var moves []chess.Move
+	for _, m := range b.Board.GenerateLegalMoves() {
+		if !chess.IsCastling(b.Board, m) {
+			moves = append(moves, m)
+		}
+	}
+	return moves
+}
+
+func TestRunFindsDivergence(t *testing.T) {
+	broken := func(fen string) chess.Board { return noCastling{chess.NewBitboard(fen)} }
+	d := Replay(newArray, broken, "r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", nil)
+	if d == nil || d.What != "legal moves" || d.A != "e1c1 e1g1" || d.B != "" {
+		t.Fatalf("Replay found %v", d)
+	}
+
+	found := Run(newArray, broken, 20, 200, 1)
+	if len(found) == 0 {
+		t.Fatal("no divergence found")
+	}
+	for _, d := range found {
+		if d.What != "legal moves" || d.B != "" || !strings.Contains(d.A, "e1g1") && !strings.Contains(d.A, "e1c1") &&
+			!strings.Contains(d.A, "e8g8") && !strings.Contains(d.A, "e8c8") {
+			t.Errorf("unexpected divergence:\n%s", d)
+		}
+	}
+}
+
+// playable turns a fuzzed FEN into one both boards can be expected to
+// agree on: one king a side, no pawns on the back ranks, the side not to
+// move not in check, and no en passant square.
+func playable(fen string) (string, bool) {
+	p, err := chess.ParseFEN(fen)
+	if err != nil {
+		return "", false
+	}
+	kings := map[chess.Piece]int{}
+	for sq, piece := range p.Pieces {
+		kings[piece]++
+		if (piece == chess.WhitePawn || piece == chess.BlackPawn) && (sq < 8 || sq >= 56) {
+			return "", false
+		}
+	}
+	if kings[chess.WhiteKing] != 1 || kings[chess.BlackKing] != 1 {
+		return "", false
+	}
+	p.EnPassant = chess.NoSquare
+	flipped := p
+	flipped.SideToMove = 1 - p.SideToMove
+	if chess.NewArrayBoard(flipped.FEN()).InCheck() {
+		return "", false
+	}
+	return p.FEN(), true
+}
@@ -1,26 +1,134 @@
 package engine
 
 import (
+	"context"
+	"go-chess-engine/book"
 	"go-chess-engine/chess"
-	"math/rand"
+	"go-chess-engine/nnue"
 	"time"
 )
 
 type Engine struct {
-	// Future fields: transposition tables, search settings, etc.
+	// MultiPV is the number of best lines to search and report (1 = normal play).
+	MultiPV int
+	// Depth is the maximum search depth in plies; 0 means DefaultDepth.
+	Depth int
+	// MoveTime, if positive, limits the search time. The search always
+	// completes depth 1, then stops at the first check past the deadline.
+	MoveTime time.Duration
+	// Nodes, if positive, limits the number of nodes searched: the search
+	// stops as soon as it has searched Nodes nodes, even within depth 1.
+	Nodes int
+	// OnInfo, if set, receives every principal variation as it is completed.
+	OnInfo func(Info)
+	// Book, if set, is consulted before searching.
+	Book *book.Book
+	// Tablebase, if set, restricts the root moves in covered endgames.
+	Tablebase Tablebase
+	// Params, if set, are the weights of the hand-crafted evaluation
+	// instead of DefaultParams.
+	Params *Params
+	// Net, if set, evaluates positions instead of the hand-crafted evaluation.
+	Net *nnue.Network
+
+	nodes     int
+	nodeLimit int
+	ctx       context.Context // of the running search
+	deadline  time.Time
+	stopped   bool
+	// acc[ply] is the network's accumulator of the position searched at ply.
+	acc   []nnue.Accumulator
+	pawns pawnCache
+	// Future fields: transposition tables, etc.
 }
 
 func New() *Engine {
-	return &Engine{}
+	return &Engine{MultiPV: 1}
 }
 
-// FindBestMove now uses the legal move generator.
-// FindBestMove now accepts the Board INTERFACE
+// FindBestMove searches the position and returns the best legal move.
+// It accepts the Board INTERFACE, so it works with every representation.
 func (e *Engine) FindBestMove(b chess.Board) chess.Move {
+	return e.FindBestMoveContext(context.Background(), b)
+}
+
+// FindBestMoveContext is FindBestMove with a context: once ctx is done the
+// search stops and returns the best move found so far.
+func (e *Engine) FindBestMoveContext(ctx context.Context, b chess.Board) chess.Move {
+	return e.Search(ctx, b, Limits{}).BestMove
+}
+
+// Limits bound one search. A zero field leaves the engine's own setting.
+type Limits struct {
+	Depth    int
+	Nodes    int
+	MoveTime time.Duration
+	// Mate, if positive, first looks for a mate in at most Mate moves with
+	// FindMate and only searches normally if there is none. Both share the
+	// node and time limits.
+	Mate int
+}
+
+// SearchResult is the outcome of a search: the move to play and the last
+// completed iteration. A move from the book or a search stopped within
+// depth 1 has Depth 0 and no score.
+type SearchResult struct {
+	BestMove chess.Move
+	// Ponder is the expected reply, the second move of PV, if any.
+	Ponder chess.Move
+	Score  int // centipawns, from the side to move's point of view
+	PV     []chess.Move
+	Nodes  int // searched in total, including an interrupted iteration
+	Depth  int
+}
+
+// Search searches the position within limits and the engine's own
+// settings. Once ctx is done it stops and returns the result of the last
+// completed iteration.
+func (e *Engine) Search(ctx context.Context, b chess.Board, limits Limits) SearchResult {
 	moves := b.GenerateLegalMoves() // This call works on both ArrayBoard and Bitboard!
 	if len(moves) == 0 {
-		return chess.Move{}
+		return SearchResult{}
+	}
+	if e.Book != nil {
+		if m, ok := e.Book.Pick(b); ok {
+			return SearchResult{BestMove: m, PV: []chess.Move{m}}
+		}
+	}
+	if limits.Depth <= 0 {
+		limits.Depth = e.Depth
+	}
+	if limits.Nodes <= 0 {
+		limits.Nodes = e.Nodes
+	}
+	if limits.MoveTime <= 0 {
+		limits.MoveTime = e.MoveTime
+	}
+	if limits.Mate > 0 {
+		start := time.Now()
+		if info, ok := e.findMate(ctx, b, limits); ok {
+			return newSearchResult(info, e.nodes)
+		}
+		// The normal search gets what the mate search left.
+		if limits.Nodes > 0 {
+			limits.Nodes = max(limits.Nodes-e.nodes, 1)
+		}
+		if limits.MoveTime > 0 {
+			limits.MoveTime = max(limits.MoveTime-time.Since(start), 1)
+		}
+	}
+	moves = e.tablebaseRootMoves(b, moves)
+	// The search is deterministic: the same position and limits always give
+	// the same move and node count, on either board representation.
+	orderMoves(b, moves)
+	e.ctx = ctx
+	return e.search(b, moves, limits)
+}
+
+func newSearchResult(info Info, nodes int) SearchResult {
+	r := SearchResult{BestMove: info.PV[0], Score: info.Score, PV: info.PV, Nodes: nodes, Depth: info.Depth}
+	if len(info.PV) > 1 {
+		r.Ponder = info.PV[1]
 	}
-	rand.Seed(time.Now().UnixNano())
-	return moves[rand.Intn(len(moves))]
+	return r
 }
@@ -0,0 +1,249 @@
+package engine
+
+import (
+	"go-chess-engine/chess"
+	"math/bits"
+)
+
+// pieceValues holds the material value of every piece in centipawns,
+// indexed by chess.Piece. Kings are never traded, so they are worth 0. Move
+// ordering uses these values; the evaluation starts from them in
+// DefaultParams.
+var pieceValues = [13]int{
+	chess.Empty:       0,
+	chess.WhitePawn:   100,
+	chess.WhiteKnight: 320,
+	chess.WhiteBishop: 330,
+	chess.WhiteRook:   500,
+	chess.WhiteQueen:  900,
+	chess.WhiteKing:   0,
+	chess.BlackPawn:   100,
+	chess.BlackKnight: 320,
+	chess.BlackBishop: 330,
+	chess.BlackRook:   500,
+	chess.BlackQueen:  900,
+	chess.BlackKing:   0,
+}
+
+// The default piece-square tables, written from White's point of view with
+// a8 in the top-left corner so they read like a diagram. Black squares are
+// mirrored.
+var (
+	pawnTable = [64]int{
+		0, 0, 0, 0, 0, 0, 0, 0,
+		50, 50, 50, 50, 50, 50, 50, 50,
+		10, 10, 20, 30, 30, 20, 10, 10,
+		5, 5, 10, 25, 25, 10, 5, 5,
+		0, 0, 0, 20, 20, 0, 0, 0,
+		5, -5, -10, 0, 0, -10, -5, 5,
+		5, 10, 10, -20, -20, 10, 10, 5,
+		0, 0, 0, 0, 0, 0, 0, 0,
+	}
+	knightTable = [64]int{
+		-50, -40, -30, -30, -30, -30, -40, -50,
+		-40, -20, 0, 0, 0, 0, -20, -40,
+		-30, 0, 10, 15, 15, 10, 0, -30,
+		-30, 5, 15, 20, 20, 15, 5, -30,
+		-30, 0, 15, 20, 20, 15, 0, -30,
+		-30, 5, 10, 15, 15, 10, 5, -30,
+		-40, -20, 0, 5, 5, 0, -20, -40,
+		-50, -40, -30, -30, -30, -30, -40, -50,
+	}
+	bishopTable = [64]int{
+		-20, -10, -10, -10, -10, -10, -10, -20,
+		-10, 0, 0, 0, 0, 0, 0, -10,
+		-10, 0, 5, 10, 10, 5, 0, -10,
+		-10, 5, 5, 10, 10, 5, 5, -10,
+		-10, 0, 10, 10, 10, 10, 0, -10,
+		-10, 10, 10, 10, 10, 10, 10, -10,
+		-10, 5, 0, 0, 0, 0, 5, -10,
+		-20, -10, -10, -10, -10, -10, -10, -20,
+	}
+	rookTable = [64]int{
+		0, 0, 0, 0, 0, 0, 0, 0,
+		5, 10, 10, 10, 10, 10, 10, 5,
+		-5, 0, 0, 0, 0, 0, 0, -5,
+		-5, 0, 0, 0, 0, 0, 0, -5,
+		-5, 0, 0, 0, 0, 0, 0, -5,
+		-5, 0, 0, 0, 0, 0, 0, -5,
+		-5, 0, 0, 0, 0, 0, 0, -5,
+		0, 0, 0, 5, 5, 0, 0, 0,
+	}
+	queenTable = [64]int{
+		-20, -10, -10, -5, -5, -10, -10, -20,
+		-10, 0, 0, 0, 0, 0, 0, -10,
+		-10, 0, 5, 5, 5, 5, 0, -10,
+		-5, 0, 5, 5, 5, 5, 0, -5,
+		0, 0, 5, 5, 5, 5, 0, -5,
+		-10, 5, 5, 5, 5, 5, 0, -10,
+		-10, 0, 5, 0, 0, 0, 0, -10,
+		-20, -10, -10, -5, -5, -10, -10, -20,
+	}
+	kingTable = [64]int{
+		-30, -40, -40, -50, -50, -40, -40, -30,
+		-30, -40, -40, -50, -50, -40, -40, -30,
+		-30, -40, -40, -50, -50, -40, -40, -30,
+		-30, -40, -40, -50, -50, -40, -40, -30,
+		-20, -30, -30, -40, -40, -30, -30, -20,
+		-10, -20, -20, -20, -20, -20, -20, -10,
+		20, 20, 0, 0, 0, 0, 20, 20,
+		20, 30, 10, 0, 0, 10, 30, 20,
+	}
+)
+
+// Evaluate returns a static score of the position in centipawns, from the
+// point of view of the side to move (positive means the mover is better),
+// with the default weights.
+func Evaluate(b chess.Board) int {
+	return defaultParams.Evaluate(b)
+}
+
+// fromSideToMove turns White's score into the side to move's.
+func fromSideToMove(b chess.Board, score int) int {
+	if b.SideToMove() == chess.Black {
+		return -score
+	}
+	return score
+}
+
+// term is a part of the evaluation that Trace reports on its own.
+type term int
+
+const (
+	termMaterial term = iota
+	termPieceSquare
+	termPassed
+	termDoubled
+	termIsolated
+	termBackward
+	termShield
+	termKingFiles
+	termMobility
+	numTerms
+)
+
+var termNames = [numTerms]string{
+	"Material", "Piece-square", "Passed pawns", "Doubled pawns", "Isolated pawns",
+	"Backward pawns", "Pawn shield", "King files", "Mobility",
+}
+
+// evalTrace records what evaluate adds up.
+type evalTrace struct {
+	coef  map[*int]int     // If not nil, the coefficient of every weight used
+	terms [numTerms][2]int // The score of every term for each side
+}
+
+// adder adds n times the weight w to the score of side c, as part of term t.
+type adder func(t term, c chess.Color, w *int, n int)
+
+// evaluate returns the score from White's point of view. If trace is not
+// nil, every weight used is recorded in it; pawns, if not nil, caches the
+// pawn structure scores.
+func (p *Params) evaluate(b chess.Board, trace *evalTrace, pawns *pawnCache) int {
+	score := 0
+	add := func(t term, c chess.Color, w *int, n int) {
+		sign := 1
+		if c == chess.Black {
+			sign = -1
+		}
+		score += sign * n * *w
+		if trace != nil {
+			trace.terms[t][c] += n * *w
+			if trace.coef != nil {
+				trace.coef[w] += sign * n
+			}
+		}
+	}
+
+	var pieces [13]uint64 // The squares of every piece, indexed by chess.Piece
+	for sq := 0; sq < 64; sq++ {
+		piece := b.PieceAt(sq)
+		if piece == chess.Empty {
+			continue
+		}
+		pieces[piece] |= 1 << sq
+		// The tables are laid out rank 8 first, so flip the rank for White.
+		kind, row, c := int(piece-chess.WhitePawn), sq^56, chess.White
+		if piece.Color() == chess.Black {
+			kind, row, c = int(piece-chess.BlackPawn), sq, chess.Black
+		}
+		add(termMaterial, c, &p.PieceValues[kind], 1)
+		add(termPieceSquare, c, &p.PieceSquare[kind][row], 1)
+	}
+
+	white, black := pieces[chess.WhitePawn], pieces[chess.BlackPawn]
+	if pawns != nil && trace == nil {
+		if s, ok := pawns.probe(p, white, black); ok {
+			score += s
+		} else {
+			before := score
+			p.pawnStructure(white, black, add)
+			pawns.store(white, black, score-before)
+		}
+	} else {
+		p.pawnStructure(white, black, add)
+	}
+	p.kingSafety(&pieces, add)
+	p.mobility(b, &pieces, add)
+	return score
+}
+
+// kingSafety scores the pawns in front of each king and the files around
+// it, while the opponent has a queen to attack it with.
+func (p *Params) kingSafety(pieces *[13]uint64, add adder) {
+	for _, c := range []chess.Color{chess.White, chess.Black} {
+		king, pawn, enemyPawn, enemyQueen, forward := chess.WhiteKing, chess.WhitePawn, chess.BlackPawn, chess.BlackQueen, 8
+		if c == chess.Black {
+			king, pawn, enemyPawn, enemyQueen, forward = chess.BlackKing, chess.BlackPawn, chess.WhitePawn, chess.WhiteQueen, -8
+		}
+		if pieces[enemyQueen] == 0 || pieces[king] == 0 {
+			continue
+		}
+		sq := bits.TrailingZeros64(pieces[king])
+		for file := max(sq%8-1, 0); file <= min(sq%8+1, 7); file++ {
+			switch {
+			case pieces[pawn]&fileMasks[file] != 0:
+			case pieces[enemyPawn]&fileMasks[file] != 0:
+				add(termKingFiles, c, &p.KingHalfOpenFile, 1)
+			default:
+				add(termKingFiles, c, &p.KingOpenFile, 1)
+			}
+			for i := range p.PawnShield {
+				shield := sq - sq%8 + file + forward*(i+1)
+				if shield >= 0 && shield < 64 && pieces[pawn]&(1<<shield) != 0 {
+					add(termShield, c, &p.PawnShield[i], 1)
+				}
+			}
+		}
+	}
+}
+
+// mobility counts the moves of each knight, bishop, rook and queen, as the
+// board generates them, to squares no enemy pawn attacks. Moves that leave
+// the king in check count too.
+func (p *Params) mobility(b chess.Board, pieces *[13]uint64, add adder) {
+	for _, c := range []chess.Color{chess.White, chess.Black} {
+		first, enemy, enemyPawn := chess.WhitePawn, chess.Black, chess.BlackPawn
+		if c == chess.Black {
+			first, enemy, enemyPawn = chess.BlackPawn, chess.White, chess.WhitePawn
+		}
+		guarded := pawnAttacks(enemy, pieces[enemyPawn])
+
+		var n [4]int
+		for _, m := range b.PseudoLegalMoves(c) {
+			if guarded&(1<<m.To) != 0 {
+				continue
+			}
+			for kind := range n {
+				if pieces[first+chess.Piece(kind+1)]&(1<<m.From) != 0 {
+					n[kind]++
+				}
+			}
+		}
+		for kind := range n {
+			if n[kind] > 0 {
+				add(termMobility, c, &p.Mobility[kind], n[kind])
+			}
+		}
+	}
+}
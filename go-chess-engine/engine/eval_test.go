@@ -0,0 +1,114 @@
+package engine
+
+import (
+	"go-chess-engine/chess"
+	"testing"
+)
+
+// TestTraceSum checks that the terms Trace reports add up to Evaluate on
+// both boards.
+func TestTraceSum(t *testing.T) {
+	p := DefaultParams()
+	fens := append(searchPositions,
+		"4k3/8/8/8/2p1P3/8/3P4/4K3 b - - 0 1",
+		"rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1",
+		"6k1/5ppp/8/8/8/8/5PPP/3QR1K1 w - - 0 1",
+	)
+	for _, rep := range []string{"array", "bitboard"} {
+		for _, fen := range fens {
+			b, err := chess.NewBoard(rep, fen)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sum := 0
+			for _, term := range p.Trace(b) {
+				sum += term.White - term.Black
+			}
+			if got, want := fromSideToMove(b, sum), p.Evaluate(b); got != want {
+				t.Errorf("%s: %s: terms add up to %d, Evaluate gives %d", rep, fen, got, want)
+			}
+		}
+	}
+}
+
+// TestTraceTerms checks single terms on positions made to show them.
+func TestTraceTerms(t *testing.T) {
+	tests := []struct {
+		fen          string
+		term         string
+		white, black int
+	}{
+		// A passed pawn on d5 scores for its rank.
+		{"4k3/8/8/3P4/8/8/8/4K3 w - - 0 1", "Passed pawns", 35, 0},
+		// Of two pawns on a file, only the front one can be passed.
+		{"4k3/8/8/8/3P4/3P4/8/4K3 w - - 0 1", "Doubled pawns", -10, 0},
+		{"4k3/8/8/8/3P4/3P4/8/4K3 w - - 0 1", "Passed pawns", 20, 0},
+		{"4k3/p7/8/8/8/8/P7/4K3 w - - 0 1", "Isolated pawns", -10, -10},
+		{"4k3/p7/8/8/8/8/P7/4K3 w - - 0 1", "Passed pawns", 0, 0},
+		// The pawn on d2 (d7) has no neighbour behind it and c4 (c5) stops
+		// it from advancing.
+		{"4k3/8/8/8/2p1P3/8/3P4/4K3 w - - 0 1", "Backward pawns", -8, 0},
+		{"4k3/3p4/8/2P1p3/8/8/8/4K3 b - - 0 1", "Backward pawns", 0, -8},
+		// Supported by e2 on its rank, d2 is not backward.
+		{"4k3/8/8/8/2p5/8/3PP3/4K3 w - - 0 1", "Backward pawns", 0, 0},
+		// Each side's knights have four moves; the other pieces none.
+		{chess.StartFEN, "Mobility", 16, 16},
+		// The knight on g1 keeps off e2, its own pawn's square, and f3 and
+		// h3, which the pawn on g4 attacks.
+		{"4k3/8/8/8/6p1/8/4P3/K5N1 w - - 0 1", "Mobility", 0, 0},
+		{"4k3/8/8/8/6p1/8/8/K5N1 w - - 0 1", "Mobility", 4, 0},
+	}
+	p := DefaultParams()
+	for _, rep := range []string{"array", "bitboard"} {
+		for _, tt := range tests {
+			b, err := chess.NewBoard(rep, tt.fen)
+			if err != nil {
+				t.Fatal(err)
+			}
+			found := false
+			for _, term := range p.Trace(b) {
+				if term.Name != tt.term {
+					continue
+				}
+				found = true
+				if term.White != tt.white || term.Black != tt.black {
+					t.Errorf("%s: %s: %s %d, %d, want %d, %d",
+						rep, tt.fen, tt.term, term.White, term.Black, tt.white, tt.black)
+				}
+			}
+			if !found {
+				t.Errorf("no %s term", tt.term)
+			}
+		}
+	}
+}
+
+func TestPawnAttacks(t *testing.T) {
+	tests := []struct {
+		c     chess.Color
+		pawns []string
+		want  []string
+	}{
+		{chess.White, []string{"e4"}, []string{"d5", "f5"}},
+		{chess.White, []string{"a2", "h2"}, []string{"b3", "g3"}},
+		{chess.Black, []string{"e5"}, []string{"d4", "f4"}},
+		{chess.Black, []string{"a7", "h7"}, []string{"b6", "g6"}},
+	}
+	for _, tt := range tests {
+		var pawns, want uint64
+		for _, s := range tt.pawns {
+			pawns |= 1 << square(s)
+		}
+		for _, s := range tt.want {
+			want |= 1 << square(s)
+		}
+		if got := pawnAttacks(tt.c, pawns); got != want {
+			t.Errorf("pawnAttacks(%d, %v) = %#x, want %#x", tt.c, tt.pawns, got, want)
+		}
+	}
+}
+
+// square returns the index of a square name such as "e4".
+func square(name string) int {
+	return int(name[0]-'a') + 8*int(name[1]-'1')
+}
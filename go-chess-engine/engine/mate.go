@@ -0,0 +1,126 @@
+package engine
+
+import (
+	"context"
+	"go-chess-engine/chess"
+	"time"
+)
+
+// FindMate looks for a forced mate in at most moves moves for the side to
+// move, shortest first, and reports it through OnInfo as a mate score. It
+// returns the first move of the mate, or false if there is none within
+// moves or ctx was done first. The engine's MoveTime and Nodes bound the
+// search; without a completed iteration to fall back on, it stops as soon
+// as either runs out.
+//
+// The search proves or refutes a mate instead of scoring positions: the
+// attacker needs one move after which every defence loses, and in the last
+// move only checks can mate.
+func (e *Engine) FindMate(ctx context.Context, b chess.Board, moves int) (chess.Move, bool) {
+	info, ok := e.findMate(ctx, b, Limits{Nodes: e.Nodes, MoveTime: e.MoveTime, Mate: moves})
+	if !ok {
+		return chess.Move{}, false
+	}
+	return info.PV[0], true
+}
+
+// findMate is FindMate for a mate in limits.Mate moves within the node and
+// time limits, returning the mate as it was reported.
+func (e *Engine) findMate(ctx context.Context, b chess.Board, limits Limits) (Info, bool) {
+	e.ctx = ctx
+	e.nodes = 0
+	e.stopped = false
+	start := time.Now()
+	e.deadline = time.Time{}
+	if limits.MoveTime > 0 {
+		e.deadline = start.Add(limits.MoveTime)
+	}
+	e.nodeLimit = limits.Nodes
+
+	for n := 1; n <= limits.Mate; n++ {
+		pv, ok := e.attack(b, 2*n-1)
+		if e.stopped {
+			return Info{}, false
+		}
+		if ok {
+			info := Info{
+				Depth:   2*n - 1,
+				MultiPV: 1,
+				Score:   MateScore - (2*n - 1),
+				Nodes:   e.nodes,
+				Time:    time.Since(start),
+				PV:      pv,
+			}
+			if e.OnInfo != nil {
+				e.OnInfo(info)
+			}
+			return info, true
+		}
+	}
+	return Info{}, false
+}
+
+// attack reports whether the side to move mates within plies plies, an
+// odd number, and returns the mating line.
+func (e *Engine) attack(b chess.Board, plies int) ([]chess.Move, bool) {
+	if e.timeUp() {
+		return nil, false
+	}
+	e.nodes++
+	moves := b.GenerateLegalMoves()
+	orderMoves(b, moves)
+	// Checks first: they leave the defender the fewest replies.
+	var checks, quiet []chess.Move
+	var checkBoards, quietBoards []chess.Board
+	for _, m := range moves {
+		child := b.Clone()
+		child.ApplyMove(m)
+		if child.InCheck() {
+			checks, checkBoards = append(checks, m), append(checkBoards, child)
+		} else if plies > 1 {
+			quiet, quietBoards = append(quiet, m), append(quietBoards, child)
+		}
+	}
+	children := append(checkBoards, quietBoards...)
+	for i, m := range append(checks, quiet...) {
+		if line, ok := e.defend(children[i], plies-1); ok {
+			return append([]chess.Move{m}, line...), true
+		}
+		if e.stopped {
+			return nil, false
+		}
+	}
+	return nil, false
+}
+
+// defend reports whether the side to move, the defender, is mated within
+// plies plies whatever it plays, and returns the longest defence.
+// A stopped search proves nothing, so it returns false as well.
+func (e *Engine) defend(b chess.Board, plies int) ([]chess.Move, bool) {
+	if e.timeUp() {
+		return nil, false
+	}
+	e.nodes++
+	moves := b.GenerateLegalMoves()
+	if len(moves) == 0 {
+		return nil, b.InCheck()
+	}
+	if plies == 0 {
+		return nil, false
+	}
+	var longest []chess.Move
+	for _, m := range moves {
+		child := b.Clone()
+		child.ApplyMove(m)
+		// A mate in fewer plies is proven on the way, so the defence
+		// that holds out longest gives the principal variation.
+		line, ok := e.attack(child, plies-1)
+		if !ok {
+			return nil, false
+		}
+		if longest == nil || len(line)+1 > len(longest) {
+			longest = append([]chess.Move{m}, line...)
+		}
+	}
+	return longest, true
+}
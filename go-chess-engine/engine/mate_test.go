@@ -0,0 +1,103 @@
+package engine
+
+import (
+	"context"
+	"go-chess-engine/chess"
+	"testing"
+	"time"
+)
+
+func TestFindMate(t *testing.T) {
+	tests := []struct {
+		name  string
+		fen   string
+		moves int    // The mate is in this many moves, or none within it
+		want  string // The first move, or "" if there is no mate
+	}{
+		{"back rank", "6k1/5ppp/8/8/8/8/8/R5K1 w - - 0 1", 1, "a1a8"},
+		{"scholar's mate", "r1bqkbnr/pppp1ppp/2n5/4p3/2B1P3/5Q2/PPPP1PPP/RNB1K1NR w KQkq - 0 1", 1, "f3f7"},
+		// Only a knight gives check from f8.
+		{"underpromotion", "6nb/5Ppk/7p/8/8/8/8/4K3 w - - 0 1", 1, "f7f8n"},
+		{"rook ladder", "7k/8/8/8/8/8/8/RR4K1 w - - 0 1", 2, "a1a7"},
+		{"knight sacrifice", "r2qkb1r/pp2nppp/3p4/2pNN1B1/2BnP3/3P4/PPP2PPP/R2bK2R w KQkq - 1 1", 2, "d5f6"},
+		{"rook and bishop", "r5rk/5p1p/5R2/4B3/8/8/7P/7K w - - 0 1", 3, "f6a6"},
+		{"king hunt", "r1b1kb1r/pppp1ppp/5q2/4n3/3KP3/2N3PN/PPP4P/R1BQ1B1R b kq - 0 1", 3, "f8c5"},
+		{"start", chess.StartFEN, 2, ""},
+		{"lone rook", "6k1/8/6K1/8/8/8/8/R7 b - - 0 1", 3, ""},
+	}
+	for _, rep := range []string{"array", "bitboard"} {
+		for _, tt := range tests {
+			b, err := chess.NewBoard(rep, tt.fen)
+			if err != nil {
+				t.Fatal(err)
+			}
+			info, ok := New().findMate(context.Background(), b, Limits{Mate: tt.moves})
+			if tt.want == "" {
+				if ok {
+					t.Errorf("%s: %s: found a mate %v", rep, tt.name, info.PV)
+				}
+				continue
+			}
+			if !ok {
+				t.Errorf("%s: %s: no mate in %d", rep, tt.name, tt.moves)
+				continue
+			}
+			if got := chess.FormatMove(info.PV[0]); got != tt.want {
+				t.Errorf("%s: %s: mate starts with %s, want %s", rep, tt.name, got, tt.want)
+			}
+			if plies := 2*tt.moves - 1; len(info.PV) != plies || info.Score != MateScore-plies {
+				t.Errorf("%s: %s: line %v scored %d, want a mate in %d", rep, tt.name, info.PV, info.Score, tt.moves)
+			}
+			// The line ends in mate.
+			end := b.Clone()
+			for _, m := range info.PV {
+				end.ApplyMove(m)
+			}
+			if !end.InCheck() || len(end.GenerateLegalMoves()) != 0 {
+				t.Errorf("%s: %s: line %v ends in %s, not mate", rep, tt.name, info.PV, end.ToFEN())
+			}
+			// No shorter mate exists.
+			if _, ok := New().FindMate(context.Background(), b, tt.moves-1); ok {
+				t.Errorf("%s: %s: found a mate in %d", rep, tt.name, tt.moves-1)
+			}
+		}
+	}
+}
+
+// TestFindMateLimits checks that a mate search without a mate to find
+// stops at the engine's and the search's time and node limits.
+func TestFindMateLimits(t *testing.T) {
+	const fen = "r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3"
+	for _, rep := range []string{"array", "bitboard"} {
+		b, err := chess.NewBoard(rep, fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		e := New()
+		e.MoveTime = 50 * time.Millisecond
+		start := time.Now()
+		if _, ok := e.FindMate(context.Background(), b, 6); ok {
+			t.Errorf("%s: found a mate", rep)
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("%s: FindMate with a 50ms move time took %v", rep, elapsed)
+		}
+
+		e = New()
+		e.Nodes = 5000
+		if _, ok := e.FindMate(context.Background(), b, 6); ok || e.nodes != 5000 {
+			t.Errorf("%s: FindMate with a limit of 5000 nodes searched %d, found %v", rep, e.nodes, ok)
+		}
+
+		// Search plays a move within the time left by the mate search.
+		start = time.Now()
+		result := New().Search(context.Background(), b, Limits{Mate: 6, MoveTime: 50 * time.Millisecond})
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("%s: go mate 6 movetime 50 took %v", rep, elapsed)
+		}
+		if IsMateScore(result.Score) || result.Depth == 0 {
+			t.Errorf("%s: go mate 6 movetime 50 gave %+v", rep, result)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+	"go-chess-engine/chess"
+	"io"
+	"os"
+	"strings"
+)
+
+// Params are the weights of the hand-crafted evaluation, in centipawns.
+// The evaluation is linear in them, so they can be fitted to game results
+// (see the tune package). The tables are indexed by piece kind, pawn to
+// king, and laid out like the default tables: a8 first, from White's side.
+type Params struct {
+	PieceValues [6]int     `json:"piece_values"`
+	PieceSquare [6][64]int `json:"piece_square"`
+
+	// PassedPawn is the bonus of a pawn no enemy pawn can stop, by its
+	// rank counted from its own side (index 0 is the first rank).
+	PassedPawn [8]int `json:"passed_pawn"`
+	// DoubledPawn counts for every pawn with another of its side in front
+	// of it on the same file.
+	DoubledPawn int `json:"doubled_pawn"`
+	// IsolatedPawn counts for a pawn without friendly pawns on the
+	// neighbouring files.
+	IsolatedPawn int `json:"isolated_pawn"`
+	// BackwardPawn counts for a pawn whose neighbours have all advanced
+	// past it and whose next square an enemy pawn attacks.
+	BackwardPawn int `json:"backward_pawn"`
+
+	// King safety only counts while the opponent has a queen. PawnShield
+	// is the bonus of a pawn on the king's file or next to it, one or two
+	// ranks in front of the king; the file terms count for each of those
+	// files without a friendly pawn, with or without enemy pawns.
+	PawnShield       [2]int `json:"pawn_shield"`
+	KingOpenFile     int    `json:"king_open_file"`
+	KingHalfOpenFile int    `json:"king_half_open_file"`
+
+	// Mobility is the bonus per square a knight, bishop, rook or queen
+	// attacks that holds no friendly piece and no enemy pawn attacks.
+	Mobility [4]int `json:"mobility"`
+}
+
+var defaultParams = DefaultParams()
+
+// DefaultParams returns the weights the engine uses unless told otherwise.
+func DefaultParams() *Params {
+	p := &Params{
+		PassedPawn:       [8]int{0, 5, 10, 20, 35, 60, 100, 0},
+		DoubledPawn:      -10,
+		IsolatedPawn:     -10,
+		BackwardPawn:     -8,
+		PawnShield:       [2]int{10, 5},
+		KingOpenFile:     -25,
+		KingHalfOpenFile: -10,
+		Mobility:         [4]int{4, 5, 2, 1},
+	}
+	tables := []*[64]int{&pawnTable, &knightTable, &bishopTable, &rookTable, &queenTable, &kingTable}
+	for kind := range p.PieceValues {
+		p.PieceValues[kind] = pieceValues[chess.WhitePawn+chess.Piece(kind)]
+		p.PieceSquare[kind] = *tables[kind]
+	}
+	return p
+}
+
+// LoadParams reads weights written as JSON, e.g. by the tuner. Weights
+// missing from the file keep their default values.
+func LoadParams(path string) (*Params, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	p := DefaultParams()
+	if err := json.Unmarshal(data, p); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return p, nil
+}
+
+// WriteJSON writes the weights for LoadParams, each piece-square table
+// with one rank per line.
+func (p *Params) WriteJSON(w io.Writer) error {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "{\n  \"piece_values\": [%s],\n  \"piece_square\": [\n", joinInts(p.PieceValues[:]))
+	for kind, table := range p.PieceSquare {
+		sb.WriteString("    [\n")
+		for rank := 0; rank < 8; rank++ {
+			sep := ","
+			if rank == 7 {
+				sep = ""
+			}
+			fmt.Fprintf(&sb, "      %s%s\n", joinInts(table[rank*8:rank*8+8]), sep)
+		}
+		sep := ","
+		if kind == len(p.PieceSquare)-1 {
+			sep = ""
+		}
+		fmt.Fprintf(&sb, "    ]%s\n", sep)
+	}
+	sb.WriteString("  ],\n")
+	fmt.Fprintf(&sb, "  \"passed_pawn\": [%s],\n", joinInts(p.PassedPawn[:]))
+	fmt.Fprintf(&sb, "  \"doubled_pawn\": %d,\n", p.DoubledPawn)
+	fmt.Fprintf(&sb, "  \"isolated_pawn\": %d,\n", p.IsolatedPawn)
+	fmt.Fprintf(&sb, "  \"backward_pawn\": %d,\n", p.BackwardPawn)
+	fmt.Fprintf(&sb, "  \"pawn_shield\": [%s],\n", joinInts(p.PawnShield[:]))
+	fmt.Fprintf(&sb, "  \"king_open_file\": %d,\n", p.KingOpenFile)
+	fmt.Fprintf(&sb, "  \"king_half_open_file\": %d,\n", p.KingHalfOpenFile)
+	fmt.Fprintf(&sb, "  \"mobility\": [%s]\n}\n", joinInts(p.Mobility[:]))
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+func joinInts(values []int) string {
+	s := make([]string, len(values))
+	for i, v := range values {
+		s[i] = fmt.Sprint(v)
+	}
+	return strings.Join(s, ", ")
+}
+
+// Weights returns a pointer to every weight, in a fixed order.
+func (p *Params) Weights() []*int {
+	var weights []*int
+	for kind := range p.PieceValues {
+		weights = append(weights, &p.PieceValues[kind])
+	}
+	for kind := range p.PieceSquare {
+		for sq := range p.PieceSquare[kind] {
+			weights = append(weights, &p.PieceSquare[kind][sq])
+		}
+	}
+	for rank := range p.PassedPawn {
+		weights = append(weights, &p.PassedPawn[rank])
+	}
+	weights = append(weights, &p.DoubledPawn, &p.IsolatedPawn, &p.BackwardPawn)
+	weights = append(weights, &p.PawnShield[0], &p.PawnShield[1], &p.KingOpenFile, &p.KingHalfOpenFile)
+	for kind := range p.Mobility {
+		weights = append(weights, &p.Mobility[kind])
+	}
+	return weights
+}
+
+// Evaluate returns a static score of the position in centipawns, from the
+// point of view of the side to move.
+func (p *Params) Evaluate(b chess.Board) int {
+	return fromSideToMove(b, p.evaluate(b, nil, nil))
+}
+
+// Coefficients returns how often each weight counts in the evaluation of
+// b: the times it is added for White minus the times for Black. White's
+// score is the sum of every weight times its coefficient; weights that do
+// not count are left out.
+func (p *Params) Coefficients(b chess.Board) map[*int]int {
+	t := &evalTrace{coef: make(map[*int]int)}
+	p.evaluate(b, t, nil)
+	for w, c := range t.coef {
+		if c == 0 {
+			delete(t.coef, w)
+		}
+	}
+	return t.coef
+}
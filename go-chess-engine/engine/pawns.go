@@ -0,0 +1,128 @@
+package engine
+
+import (
+	"go-chess-engine/chess"
+	"math/bits"
+)
+
+// Pawn masks, bit 0 = a1.
+var (
+	fileMasks [8]uint64
+	// adjacentFiles[f] are the files next to file f.
+	adjacentFiles [8]uint64
+	// frontSpan[c][sq] are the squares in front of sq on its file, seen
+	// from side c.
+	frontSpan [2][64]uint64
+	// passedSpan[c][sq] are the squares in front of sq on its file and the
+	// files next to it: a pawn of c on sq is passed if no enemy pawn
+	// stands there.
+	passedSpan [2][64]uint64
+	// supportSpan[c][sq] are the squares on the files next to sq, on its
+	// rank or behind it: the pawns of c there can still protect a pawn on sq.
+	supportSpan [2][64]uint64
+)
+
+func init() {
+	for f := 0; f < 8; f++ {
+		fileMasks[f] = 0x0101010101010101 << f
+	}
+	for f := 0; f < 8; f++ {
+		if f > 0 {
+			adjacentFiles[f] |= fileMasks[f-1]
+		}
+		if f < 7 {
+			adjacentFiles[f] |= fileMasks[f+1]
+		}
+	}
+	for sq := 0; sq < 64; sq++ {
+		file, rank := sq%8, sq/8
+		for r := 0; r < 8; r++ {
+			ranks := uint64(0xFF) << (8 * r)
+			switch {
+			case r > rank:
+				frontSpan[chess.White][sq] |= ranks & fileMasks[file]
+				passedSpan[chess.White][sq] |= ranks & (fileMasks[file] | adjacentFiles[file])
+				supportSpan[chess.Black][sq] |= ranks & adjacentFiles[file]
+			case r < rank:
+				frontSpan[chess.Black][sq] |= ranks & fileMasks[file]
+				passedSpan[chess.Black][sq] |= ranks & (fileMasks[file] | adjacentFiles[file])
+				supportSpan[chess.White][sq] |= ranks & adjacentFiles[file]
+			default:
+				supportSpan[chess.White][sq] |= ranks & adjacentFiles[file]
+				supportSpan[chess.Black][sq] |= ranks & adjacentFiles[file]
+			}
+		}
+	}
+}
+
+// pawnStructure scores the passed, doubled, isolated and backward pawns of
+// both sides. It depends on the pawns alone, so its score can be cached.
+func (p *Params) pawnStructure(white, black uint64, add adder) {
+	for _, c := range []chess.Color{chess.White, chess.Black} {
+		own, enemy, forward := white, black, 8
+		if c == chess.Black {
+			own, enemy, forward = black, white, -8
+		}
+		for pawns := own; pawns != 0; pawns &= pawns - 1 {
+			sq := bits.TrailingZeros64(pawns)
+			rank, stop := sq/8, sq+forward
+			if c == chess.Black {
+				rank = 7 - rank
+			}
+			switch {
+			case own&frontSpan[c][sq] != 0:
+				add(termDoubled, c, &p.DoubledPawn, 1)
+			case enemy&passedSpan[c][sq] == 0:
+				add(termPassed, c, &p.PassedPawn[rank], 1)
+			}
+			switch {
+			case own&adjacentFiles[sq%8] == 0:
+				add(termIsolated, c, &p.IsolatedPawn, 1)
+			case stop >= 0 && stop < 64 && own&supportSpan[c][sq] == 0 && enemy&pawnAttacks(c, 1<<stop) != 0:
+				add(termBackward, c, &p.BackwardPawn, 1)
+			}
+		}
+	}
+}
+
+// pawnAttacks returns the squares the pawns of c attack.
+func pawnAttacks(c chess.Color, pawns uint64) uint64 {
+	if c == chess.White {
+		return (pawns&^fileMasks[0])<<7 | (pawns&^fileMasks[7])<<9
+	}
+	return (pawns&^fileMasks[0])>>9 | (pawns&^fileMasks[7])>>7
+}
+
+// An engine remembers 1<<pawnCacheBits pawn structures.
+const pawnCacheBits = 14
+
+// pawnCache remembers the pawn structure scores of one set of weights. A
+// zero entry stands for the position without pawns, which scores 0, so a
+// new cache needs no marking of empty entries.
+type pawnCache struct {
+	params  *Params
+	entries []pawnEntry
+}
+
+type pawnEntry struct {
+	white, black uint64
+	score        int
+}
+
+func pawnIndex(white, black uint64) uint64 {
+	return (white*0x9E3779B97F4A7C15 ^ black*0xC2B2AE3D27D4EB4F) >> (64 - pawnCacheBits)
+}
+
+// probe returns the cached score of the pawns, starting over whenever the
+// weights change.
+func (c *pawnCache) probe(p *Params, white, black uint64) (int, bool) {
+	if c.params != p {
+		c.params, c.entries = p, make([]pawnEntry, 1<<pawnCacheBits)
+	}
+	e := &c.entries[pawnIndex(white, black)]
+	return e.score, e.white == white && e.black == black
+}
+
+func (c *pawnCache) store(white, black uint64, score int) {
+	c.entries[pawnIndex(white, black)] = pawnEntry{white, black, score}
+}
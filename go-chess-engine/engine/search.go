@@ -0,0 +1,327 @@
+package engine
+
+import (
+	"go-chess-engine/chess"
+	"go-chess-engine/nnue"
+	"sort"
+	"time"
+)
+
+// DefaultDepth is how many plies FindBestMove searches when Engine.Depth is 0.
+const DefaultDepth = 4
+
+// MateScore is the score of delivering mate on the spot. A mate found at
+// ply p scores MateScore-p for the winning side, so shorter mates are
+// always preferred over longer ones.
+const MateScore = 100000
+
+// Score bounds.
+const (
+	infinity = 1000000
+	maxPly   = 1000
+)
+
+// Info describes one principal variation completed during the search.
+// With MultiPV > 1 the engine sends one Info per line at every depth.
+type Info struct {
+	Depth   int
+	MultiPV int // 1-based rank of this line among the root moves
+	Score   int // centipawns, from the side to move's point of view
+	Nodes   int
+	Time    time.Duration
+	PV      []chess.Move
+}
+
+// IsMateScore reports whether a score encodes a forced mate.
+func IsMateScore(score int) bool {
+	return score > MateScore-maxPly || score < -MateScore+maxPly
+}
+
+// MateIn converts a mate score into full moves: positive when the side to
+// move delivers mate, negative when it gets mated.
+func MateIn(score int) int {
+	if score > 0 {
+		return (MateScore - score + 1) / 2
+	}
+	return -(MateScore + score) / 2
+}
+
+// search runs an iterative deepening alpha-beta search over rootMoves and
+// returns the best move found at the deepest completed iteration. If not
+// even depth 1 completes, it returns the first root move.
+//
+// For MultiPV the root is searched several times per depth: every pass
+// excludes the root moves already reported, so pass k finds the k-th best line.
+func (e *Engine) search(b chess.Board, rootMoves []chess.Move, limits Limits) SearchResult {
+	maxDepth := limits.Depth
+	if maxDepth <= 0 {
+		maxDepth = DefaultDepth
+	}
+	multiPV := e.MultiPV
+	if multiPV < 1 {
+		multiPV = 1
+	}
+	if multiPV > len(rootMoves) {
+		multiPV = len(rootMoves)
+	}
+
+	e.nodes = 0
+	e.stopped = false
+	start := time.Now()
+	e.deadline = time.Time{}
+	e.nodeLimit = limits.Nodes
+	result := SearchResult{BestMove: rootMoves[0], PV: []chess.Move{rootMoves[0]}}
+	if e.Net != nil {
+		e.Net.Refresh(e.accumulator(0), b)
+	}
+
+	for depth := 1; depth <= maxDepth; depth++ {
+		if depth == 2 && limits.MoveTime > 0 {
+			e.deadline = start.Add(limits.MoveTime)
+		}
+		var reported []chess.Move
+		for k := 1; k <= multiPV; k++ {
+			score, pv := e.searchRoot(b, rootMoves, reported, depth)
+			if e.stopped {
+				// The interrupted iteration is not trusted
+				result.Nodes = e.nodes
+				return result
+			}
+			info := Info{
+				Depth:   depth,
+				MultiPV: k,
+				Score:   score,
+				Nodes:   e.nodes,
+				Time:    time.Since(start),
+				PV:      pv,
+			}
+			if k == 1 {
+				result = newSearchResult(info, e.nodes)
+			}
+			reported = append(reported, pv[0])
+			if e.OnInfo != nil {
+				e.OnInfo(info)
+			}
+		}
+		// Try the lines we just reported first at the next depth, best first.
+		rootMoves = append(reported, withoutMoves(rootMoves, reported)...)
+	}
+	result.Nodes = e.nodes
+	return result
+}
+
+// searchRoot finds the best root move that is not in excluded and returns
+// its score together with the principal variation starting with that move.
+func (e *Engine) searchRoot(b chess.Board, rootMoves, excluded []chess.Move, depth int) (int, []chess.Move) {
+	alpha := -infinity
+	var pv []chess.Move
+	for _, m := range rootMoves {
+		if containsMove(excluded, m) {
+			continue
+		}
+		child := e.play(b, m, 0)
+		score, line := e.negamax(child, depth-1, 1, -infinity, -alpha)
+		if e.stopped {
+			break
+		}
+		score = -score
+		if pv == nil || score > alpha {
+			alpha = score
+			pv = append([]chess.Move{m}, line...)
+		}
+	}
+	return alpha, pv
+}
+
+// negamax is a fail-hard alpha-beta search. It returns the score of the
+// position and, when the score is exact, the principal variation.
+func (e *Engine) negamax(b chess.Board, depth, ply, alpha, beta int) (int, []chess.Move) {
+	if depth <= 0 {
+		return e.quiesce(b, ply, alpha, beta), nil
+	}
+	if e.timeUp() {
+		return 0, nil
+	}
+	e.nodes++
+
+	moves := b.GenerateLegalMoves()
+	if len(moves) == 0 {
+		if b.InCheck() {
+			return -MateScore + ply, nil
+		}
+		return 0, nil // Stalemate
+	}
+	orderMoves(b, moves)
+
+	var pv []chess.Move
+	for _, m := range moves {
+		child := e.play(b, m, ply)
+		score, line := e.negamax(child, depth-1, ply+1, -beta, -alpha)
+		if e.stopped {
+			break
+		}
+		score = -score
+		if score >= beta {
+			return beta, nil
+		}
+		if score > alpha {
+			alpha = score
+			pv = append([]chess.Move{m}, line...)
+		}
+	}
+	return alpha, pv
+}
+
+// quiesce extends the search through captures and promotions only, so the
+// static evaluation is never taken in the middle of an exchange.
+func (e *Engine) quiesce(b chess.Board, ply, alpha, beta int) int {
+	if e.timeUp() {
+		return 0
+	}
+	e.nodes++
+
+	standPat := e.evaluate(b, ply)
+	if standPat >= beta {
+		return beta
+	}
+	if standPat > alpha {
+		alpha = standPat
+	}
+
+	var tactical []chess.Move
+	for _, m := range b.GenerateLegalMoves() {
+		if b.PieceAt(m.To) != chess.Empty || m.Promotion != chess.Empty {
+			tactical = append(tactical, m)
+		}
+	}
+	orderMoves(b, tactical)
+
+	for _, m := range tactical {
+		child := e.play(b, m, ply)
+		score := -e.quiesce(child, ply+1, -beta, -alpha)
+		if e.stopped {
+			break
+		}
+		if score >= beta {
+			return beta
+		}
+		if score > alpha {
+			alpha = score
+		}
+	}
+	return alpha
+}
+
+// play returns the position after m on b, the position searched at ply,
+// and brings the network's accumulator for ply+1 up to date.
+func (e *Engine) play(b chess.Board, m chess.Move, ply int) chess.Board {
+	child := b.Clone()
+	child.ApplyMove(m)
+	if e.Net != nil {
+		e.Net.Update(e.accumulator(ply+1), &e.acc[ply], b, child, m)
+	}
+	return child
+}
+
+// evaluate scores the position searched at ply with the network if there
+// is one, else with the engine's weights.
+func (e *Engine) evaluate(b chess.Board, ply int) int {
+	if e.Net != nil {
+		return e.Net.Evaluate(&e.acc[ply], b.SideToMove())
+	}
+	params := e.Params
+	if params == nil {
+		params = defaultParams
+	}
+	return fromSideToMove(b, params.evaluate(b, nil, &e.pawns))
+}
+
+// Quiet reports whether no capture or promotion changes e's static
+// evaluation of b: the quiescence search returns it unchanged. One engine
+// can check many positions in turn, between searches or after them.
+func (e *Engine) Quiet(b chess.Board) bool {
+	if b.InCheck() {
+		return false
+	}
+	e.ctx, e.deadline, e.nodeLimit = nil, time.Time{}, 0
+	e.nodes, e.stopped = 0, false
+	if e.Net != nil {
+		e.Net.Refresh(e.accumulator(0), b)
+	}
+	return e.quiesce(b, 0, -infinity, infinity) == e.evaluate(b, 0)
+}
+
+// accumulator returns the accumulator of ply, growing the stack as needed.
+// The accumulators are reused from search to search.
+func (e *Engine) accumulator(ply int) *nnue.Accumulator {
+	for len(e.acc) <= ply {
+		e.acc = append(e.acc, nnue.Accumulator{})
+	}
+	return &e.acc[ply]
+}
+
+// orderMoves sorts moves so that the most promising ones are searched first:
+// promotions and captures of valuable pieces by cheap ones (MVV-LVA). Ties
+// are broken by the squares, not by the order the board generated the
+// moves in, so every representation searches the same tree.
+func orderMoves(b chess.Board, moves []chess.Move) {
+	sort.Slice(moves, func(i, j int) bool {
+		si, sj := moveOrderScore(b, moves[i]), moveOrderScore(b, moves[j])
+		if si != sj {
+			return si > sj
+		}
+		if moves[i].From != moves[j].From {
+			return moves[i].From < moves[j].From
+		}
+		if moves[i].To != moves[j].To {
+			return moves[i].To < moves[j].To
+		}
+		return moves[i].Promotion < moves[j].Promotion
+	})
+}
+
+func moveOrderScore(b chess.Board, m chess.Move) int {
+	score := 0
+	if victim := b.PieceAt(m.To); victim != chess.Empty {
+		score += 10*pieceValues[victim] - pieceValues[b.PieceAt(m.From)]/10
+	}
+	if m.Promotion != chess.Empty {
+		score += pieceValues[m.Promotion]
+	}
+	return score
+}
+
+// timeUp reports whether the node limit is reached, the search context is
+// done or the deadline has passed. It is called before a node is counted,
+// so a search stops at exactly its node limit, and checks the clock only
+// before every 1024th node. Once it has, every search call and move loop
+// unwinds immediately.
+func (e *Engine) timeUp() bool {
+	if e.nodeLimit > 0 && e.nodes >= e.nodeLimit {
+		e.stopped = true
+	}
+	if !e.stopped && (e.nodes+1)&1023 == 0 {
+		e.stopped = (e.ctx != nil && e.ctx.Err() != nil) ||
+			(!e.deadline.IsZero() && time.Now().After(e.deadline))
+	}
+	return e.stopped
+}
+
+func containsMove(moves []chess.Move, m chess.Move) bool {
+	for _, x := range moves {
+		if x == m {
+			return true
+		}
+	}
+	return false
+}
+
+func withoutMoves(moves, drop []chess.Move) []chess.Move {
+	var rest []chess.Move
+	for _, m := range moves {
+		if !containsMove(drop, m) {
+			rest = append(rest, m)
+		}
+	}
+	return rest
+}
@@ -0,0 +1,85 @@
+package engine
+
+import (
+	"context"
+	"go-chess-engine/chess"
+	"slices"
+	"testing"
+)
+
+var searchPositions = []string{
+	chess.StartFEN,
+	"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+	"8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1",
+	"r4rk1/1pp1qppp/p1np1n2/2b1p1B1/2B1P1b1/P1NP1N2/1PP1QPPP/R4RK1 w - - 0 10",
+}
+
+// TestSearchDeterministic checks that a fixed-depth search gives the same
+// move, score, line and node count every time and on both boards.
+func TestSearchDeterministic(t *testing.T) {
+	for _, fen := range searchPositions {
+		var first SearchResult
+		for i, rep := range []string{"array", "bitboard", "array", "bitboard"} {
+			b, err := chess.NewBoard(rep, fen)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := New().Search(context.Background(), b, Limits{Depth: 3})
+			if i == 0 {
+				first = got
+				continue
+			}
+			if got.BestMove != first.BestMove || got.Score != first.Score ||
+				got.Nodes != first.Nodes || got.Depth != first.Depth || !slices.Equal(got.PV, first.PV) {
+				t.Errorf("%s: search %d on %s gave %+v, first gave %+v", fen, i, rep, got, first)
+			}
+		}
+	}
+}
+
+// TestNodeLimit checks that a node limit stops the search at exactly that
+// many nodes, and that a limited search repeats on the same engine.
+func TestNodeLimit(t *testing.T) {
+	for _, fen := range searchPositions {
+		for _, rep := range []string{"array", "bitboard"} {
+			b, err := chess.NewBoard(rep, fen)
+			if err != nil {
+				t.Fatal(err)
+			}
+			e := New()
+			for _, n := range []int{1, 100, 1000, 5000} {
+				limits := Limits{Depth: 64, Nodes: n}
+				first := e.Search(context.Background(), b, limits)
+				if first.Nodes != n {
+					t.Errorf("%s: %s: searched %d nodes, want %d", rep, fen, first.Nodes, n)
+				}
+				again := e.Search(context.Background(), b, limits)
+				if again.BestMove != first.BestMove || again.Score != first.Score ||
+					again.Nodes != first.Nodes || again.Depth != first.Depth || !slices.Equal(again.PV, first.PV) {
+					t.Errorf("%s: %s: %d nodes gave %+v, then %+v", rep, fen, n, first, again)
+				}
+			}
+		}
+	}
+}
+
+func TestQuiet(t *testing.T) {
+	tests := []struct {
+		fen   string
+		quiet bool
+	}{
+		{chess.StartFEN, true},
+		{"4k3/8/8/3q4/4P3/8/8/4K3 w - - 0 1", false}, // exd5 wins the queen
+		{"4k3/8/8/8/8/8/8/R3K3 w - - 0 1", true},
+		{"4k3/8/8/8/8/8/4r3/R3K3 w - - 0 1", false}, // In check
+		{"4k3/P7/8/8/8/8/8/4K3 w - - 0 1", false},   // a8=Q
+	}
+	// One engine checks every position, even after a stopped search.
+	e := New()
+	e.Search(context.Background(), chess.NewBitboard(chess.StartFEN), Limits{Nodes: 1})
+	for _, tt := range tests {
+		if got := e.Quiet(chess.NewBitboard(tt.fen)); got != tt.quiet {
+			t.Errorf("Quiet(%s) = %v, want %v", tt.fen, got, tt.quiet)
+		}
+	}
+}
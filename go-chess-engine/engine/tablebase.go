@@ -0,0 +1,93 @@
+package engine
+
+import (
+	"go-chess-engine/chess"
+)
+
+// Tablebase is an endgame database the engine can consult. ProbeWDL
+// returns -2 (loss) .. 2 (win) with ±1 meaning the result is spoilt by the
+// 50-move rule; ProbeDTZ returns the distance to zeroing in plies, signed
+// like the result. ok is false when the position is not covered.
+type Tablebase interface {
+	MaxPieces() int
+	ProbeWDL(b chess.Board) (wdl int, ok bool)
+	ProbeDTZ(b chess.Board) (dtz int, ok bool)
+}
+
+// tablebaseRootMoves keeps only the root moves that preserve the best
+// tablebase result: the fastest wins, all drawing moves, or the slowest
+// losses. If the position is not covered, rootMoves is returned unchanged.
+func (e *Engine) tablebaseRootMoves(b chess.Board, rootMoves []chess.Move) []chess.Move {
+	if e.Tablebase == nil || pieceCount(b) > e.Tablebase.MaxPieces() {
+		return rootMoves
+	}
+
+	ranks := make([]int, len(rootMoves))
+	best := -infinity
+	for i, m := range rootMoves {
+		zeroing := b.PieceAt(m.To) != chess.Empty ||
+			b.PieceAt(m.From) == chess.WhitePawn || b.PieceAt(m.From) == chess.BlackPawn
+		child := b.Clone()
+		child.ApplyMove(m)
+
+		// dtz is counted from the root, in plies, signed for the side to move.
+		var dtz int
+		switch {
+		case child.IsCheckmate():
+			dtz = 1
+		case zeroing:
+			// After a zeroing move only the result matters.
+			wdl, ok := e.Tablebase.ProbeWDL(child)
+			if !ok {
+				return rootMoves
+			}
+			dtz = [5]int{1, 101, 0, -101, -1}[wdl+2]
+		default:
+			d, ok := e.Tablebase.ProbeDTZ(child)
+			if !ok {
+				return rootMoves
+			}
+			dtz = -d
+			if dtz > 0 {
+				dtz++
+			} else if dtz < 0 {
+				dtz--
+			}
+		}
+
+		// Quicker wins rank higher, all draws rank the same and losses that
+		// take longer rank higher than quick ones.
+		switch {
+		case dtz > 0:
+			ranks[i] = 1000 - dtz
+		case dtz < 0:
+			ranks[i] = -1000 - dtz
+		}
+		if ranks[i] > best {
+			best = ranks[i]
+		}
+	}
+
+	var kept []chess.Move
+	for i, m := range rootMoves {
+		if ranks[i] == best {
+			kept = append(kept, m)
+		}
+	}
+	return kept
+}
+
+// pieceCount counts the pieces on the board, kings included. Positions with
+// castling rights are never in the tables, so they count as full boards.
+func pieceCount(b chess.Board) int {
+	if b.CastlingRights() != 0 {
+		return 32
+	}
+	n := 0
+	for sq := 0; sq < 64; sq++ {
+		if b.PieceAt(sq) != chess.Empty {
+			n++
+		}
+	}
+	return n
+}
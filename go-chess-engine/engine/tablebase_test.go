@@ -0,0 +1,49 @@
+package engine
+
+import (
+	"go-chess-engine/chess"
+	"go-chess-engine/syzygy"
+	"slices"
+	"testing"
+)
+
+func TestTablebaseRootMoves(t *testing.T) {
+	tables, err := syzygy.Open("../syzygy/testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		fen  string
+		want []string // nil: every move is kept
+	}{
+		// Only the mates are kept among the winning moves.
+		{"4k3/8/4K3/8/8/8/8/Q7 w - - 0 1", []string{"a1a8", "a1h8"}},
+		// Taking the queen is the only move that does not lose.
+		{"8/8/8/8/8/8/1k6/Q6K b - - 0 1", []string{"b2a1"}},
+		// Capturing the bishop wins at once.
+		{"b3k3/8/8/8/8/8/8/R3K3 w - - 0 1", []string{"a1a8"}},
+		// Too many pieces for the tables.
+		{chess.StartFEN, nil},
+	}
+	e := New()
+	e.Tablebase = tables
+	for _, tt := range tests {
+		b := chess.NewArrayBoard(tt.fen)
+		moves := b.GenerateLegalMoves()
+		var got []string
+		for _, m := range e.tablebaseRootMoves(b, moves) {
+			got = append(got, chess.FormatMove(m))
+		}
+		slices.Sort(got)
+		want := tt.want
+		if want == nil {
+			for _, m := range moves {
+				want = append(want, chess.FormatMove(m))
+			}
+			slices.Sort(want)
+		}
+		if !slices.Equal(got, want) {
+			t.Errorf("%s: kept %v, want %v", tt.fen, got, want)
+		}
+	}
+}
@@ -0,0 +1,21 @@
+package engine
+
+import "go-chess-engine/chess"
+
+// Term is the score of one evaluation term for each side, in centipawns.
+type Term struct {
+	Name         string
+	White, Black int
+}
+
+// Trace evaluates b term by term. White's score, Evaluate's from White's
+// side, is the sum of White minus Black over the terms.
+func (p *Params) Trace(b chess.Board) []Term {
+	var t evalTrace
+	p.evaluate(b, &t, nil)
+	terms := make([]Term, numTerms)
+	for i := range terms {
+		terms[i] = Term{Name: termNames[i], White: t.terms[i][chess.White], Black: t.terms[i][chess.Black]}
+	}
+	return terms
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go-chess-engine/config"
+	"go-chess-engine/engine"
+	"go-chess-engine/epd"
+	"os"
+)
+
+// epdCommand implements "epd [-depth N] [-time D] [-json] FILE...": it runs
+// the engine on every position of the given EPD test suites, using the
+// configured board, and reports how many it solved.
+func epdCommand(fs *flag.FlagSet) func(config.Config, []string) int {
+	depth := fs.Int("depth", engine.DefaultDepth, "search depth per position in plies")
+	moveTime := fs.Duration("time", 0, "search time per position, e.g. 500ms (0 = depth only)")
+	asJSON := fs.Bool("json", false, "write the report as JSON")
+	return func(cfg config.Config, args []string) int {
+		if len(args) == 0 {
+			fs.Usage()
+			return exitUsage
+		}
+		var positions []epd.Position
+		for _, name := range args {
+			f, err := os.Open(name)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return exitFailure
+			}
+			ps, err := epd.Parse(f)
+			f.Close()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %v\n", name, err)
+				return exitFailure
+			}
+			positions = append(positions, ps...)
+		}
+
+		e := engine.New()
+		var err error
+		if e.Params, err = evalParams(cfg); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitFailure
+		}
+		e.Depth = *depth
+		e.MoveTime = *moveTime
+		if *moveTime > 0 && !flagSet(fs, "depth") {
+			e.Depth = 64 // Limited by time only
+		}
+		report := epd.Run(e, cfg.BoardRepresentation, positions)
+
+		if *asJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(report); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return exitFailure
+			}
+			return exitOK
+		}
+		report.WriteText(os.Stdout)
+		return exitOK
+	}
+}
+
+// flagSet reports whether the named flag was given on the command line.
+func flagSet(fs *flag.FlagSet, name string) bool {
+	found := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			found = true
+		}
+	})
+	return found
+}
@@ -0,0 +1,144 @@
+// Package epd reads Extended Position Description records and runs test
+// suites such as WAC or STS on them.
+//
+// An EPD record is the first four FEN fields followed by operations, e.g.
+//
+//	r1b1k2r/ppppnppp/2n2q2/2b5/3NP3/2P1B3/PP3PPP/RN1QKB1R w KQkq - bm Nf5; id "Test 1";
+package epd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+)
+
+// Position is one EPD record.
+type Position struct {
+	// FEN is the position as a full FEN, with the clocks taken from the
+	// "hmvc" and "fmvn" operations or defaulting to "0 1".
+	FEN string
+	// Ops maps each operation code to its operands, e.g. "bm" to ["Nf5"].
+	// Quoted operands are stored without their quotes.
+	Ops map[string][]string
+}
+
+// ID returns the "id" operation, or "" if the record has none.
+func (p Position) ID() string {
+	return strings.Join(p.Ops["id"], " ")
+}
+
+// BestMoves returns the "bm" operands in SAN.
+func (p Position) BestMoves() []string {
+	return p.Ops["bm"]
+}
+
+// AvoidMoves returns the "am" operands in SAN.
+func (p Position) AvoidMoves() []string {
+	return p.Ops["am"]
+}
+
+// Parse reads every record in r, skipping blank lines and "#" comments.
+func Parse(r io.Reader) ([]Position, error) {
+	var positions []Position
+	sc := bufio.NewScanner(r)
+	for n := 1; sc.Scan(); n++ {
+		text := strings.TrimSpace(sc.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		p, err := ParseLine(text)
+		if err != nil {
+			return nil, fmt.Errorf("epd: line %d: %w", n, err)
+		}
+		positions = append(positions, p)
+	}
+	return positions, sc.Err()
+}
+
+// ParseLine parses a single EPD record. Fields may be separated by any
+// run of spaces and tabs. The FEN is not checked here: a record with a bad
+// one fails when it is set up.
+func ParseLine(s string) (Position, error) {
+	fields, ops := cutFields(s, 4)
+	if len(fields) < 4 {
+		return Position{}, fmt.Errorf("need at least 4 fields, got %d", len(fields))
+	}
+	p := Position{Ops: make(map[string][]string)}
+	for _, op := range splitOps(ops) {
+		words := splitOperands(op)
+		if len(words) > 0 {
+			p.Ops[words[0]] = words[1:]
+		}
+	}
+
+	halfmove, fullmove := "0", "1"
+	if v := p.Ops["hmvc"]; len(v) == 1 {
+		halfmove = v[0]
+	}
+	if v := p.Ops["fmvn"]; len(v) == 1 {
+		fullmove = v[0]
+	}
+	p.FEN = strings.Join(append(fields, halfmove, fullmove), " ")
+	return p, nil
+}
+
+// cutFields splits off the first n whitespace separated fields of s and
+// returns them with the rest of s, which keeps its inner spacing.
+func cutFields(s string, n int) (fields []string, rest string) {
+	rest = strings.TrimSpace(s)
+	for len(fields) < n && rest != "" {
+		end := strings.IndexFunc(rest, unicode.IsSpace)
+		if end < 0 {
+			end = len(rest)
+		}
+		fields = append(fields, rest[:end])
+		rest = strings.TrimLeftFunc(rest[end:], unicode.IsSpace)
+	}
+	return fields, rest
+}
+
+// splitOps splits the operations on ";", ignoring semicolons in quotes.
+func splitOps(s string) []string {
+	var ops []string
+	start, quoted := 0, false
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '"':
+			quoted = !quoted
+		case ';':
+			if !quoted {
+				ops = append(ops, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	if rest := strings.TrimSpace(s[start:]); rest != "" {
+		ops = append(ops, rest)
+	}
+	return ops
+}
+
+// splitOperands splits an operation into words, keeping quoted strings whole.
+func splitOperands(s string) []string {
+	var words []string
+	for s = strings.TrimSpace(s); s != ""; s = strings.TrimSpace(s) {
+		if s[0] == '"' {
+			end := strings.IndexByte(s[1:], '"')
+			if end < 0 {
+				end = len(s) - 1
+			}
+			words = append(words, s[1:end+1])
+			s = s[min(end+2, len(s)):]
+			continue
+		}
+		end := strings.IndexAny(s, " \t")
+		if end < 0 {
+			end = len(s)
+		}
+		words = append(words, s[:end])
+		s = s[end:]
+	}
+	return words
+}
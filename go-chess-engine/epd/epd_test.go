@@ -0,0 +1,88 @@
+package epd
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseLine(t *testing.T) {
+	tests := []struct {
+		line string
+		fen  string
+		ops  map[string][]string
+	}{
+		{
+			`r1b1k2r/ppppnppp/2n2q2/2b5/3NP3/2P1B3/PP3PPP/RN1QKB1R w KQkq - bm Nf5; id "Test 1";`,
+			"r1b1k2r/ppppnppp/2n2q2/2b5/3NP3/2P1B3/PP3PPP/RN1QKB1R w KQkq - 0 1",
+			map[string][]string{"bm": {"Nf5"}, "id": {"Test 1"}},
+		},
+		// Repeated spaces and tabs between the fields.
+		{
+			"6k1/5ppp/8/8/8/8/8/R5K1  w\t-   - \t bm Ra8#; am Kf1 Kg2;",
+			"6k1/5ppp/8/8/8/8/8/R5K1 w - - 0 1",
+			map[string][]string{"bm": {"Ra8#"}, "am": {"Kf1", "Kg2"}},
+		},
+		// Quotes keep spaces and semicolons; the clocks come from hmvc and fmvn.
+		{
+			`8/8/8/8/8/8/8/K6k b - - dm 3; id "WAC.001; a  test"; c0 "x"; hmvc 12; fmvn 40;`,
+			"8/8/8/8/8/8/8/K6k b - - 12 40",
+			map[string][]string{"dm": {"3"}, "id": {"WAC.001; a  test"}, "c0": {"x"}, "hmvc": {"12"}, "fmvn": {"40"}},
+		},
+		// No operations at all, and a last operation without its ";".
+		{"8/8/8/8/8/8/8/K6k w - -", "8/8/8/8/8/8/8/K6k w - - 0 1", map[string][]string{}},
+		{"8/8/8/8/8/8/8/K6k w - - bm Kb2", "8/8/8/8/8/8/8/K6k w - - 0 1", map[string][]string{"bm": {"Kb2"}}},
+	}
+	for _, tt := range tests {
+		p, err := ParseLine(tt.line)
+		if err != nil {
+			t.Errorf("ParseLine(%q): %v", tt.line, err)
+			continue
+		}
+		if p.FEN != tt.fen {
+			t.Errorf("ParseLine(%q): FEN %q, want %q", tt.line, p.FEN, tt.fen)
+		}
+		if len(p.Ops) != len(tt.ops) {
+			t.Errorf("ParseLine(%q): ops %q, want %q", tt.line, p.Ops, tt.ops)
+		}
+		for op, want := range tt.ops {
+			if !slices.Equal(p.Ops[op], want) {
+				t.Errorf("ParseLine(%q): %s = %q, want %q", tt.line, op, p.Ops[op], want)
+			}
+		}
+	}
+
+	p, _ := ParseLine(tests[1].line)
+	if !slices.Equal(p.BestMoves(), []string{"Ra8#"}) || !slices.Equal(p.AvoidMoves(), []string{"Kf1", "Kg2"}) {
+		t.Errorf("BestMoves %q, AvoidMoves %q", p.BestMoves(), p.AvoidMoves())
+	}
+	if p, _ := ParseLine(tests[2].line); p.ID() != "WAC.001; a  test" {
+		t.Errorf("ID = %q", p.ID())
+	}
+
+	for _, line := range []string{"", "8/8/8/8/8/8/8/K6k w", "8/8/8/8/8/8/8/K6k\tw -"} {
+		if _, err := ParseLine(line); err == nil {
+			t.Errorf("ParseLine(%q) succeeded", line)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	input := `# A suite
+6k1/5ppp/8/8/8/8/8/R5K1 w - - bm Ra8#; id "one";
+
+6k1/5ppp/8/8/8/8/8/R5K1 w - - bm Ra8#; id "two";
+`
+	positions, err := Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(positions) != 2 || positions[0].ID() != "one" || positions[1].ID() != "two" {
+		t.Errorf("Parse gave %+v", positions)
+	}
+
+	_, err = Parse(strings.NewReader("# header\n6k1/5ppp/8/8/8/8/8/R5K1 w - - bm Ra8#;\n8/8 w\n"))
+	if err == nil || !strings.Contains(err.Error(), "line 3") {
+		t.Errorf("Parse error %v, want one for line 3", err)
+	}
+}
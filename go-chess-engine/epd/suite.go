@@ -0,0 +1,152 @@
+package epd
+
+import (
+	"context"
+	"fmt"
+	"go-chess-engine/chess"
+	"go-chess-engine/engine"
+	"io"
+	"strconv"
+	"time"
+)
+
+// Result is the outcome of one position of a test suite.
+type Result struct {
+	ID         string   `json:"id"`
+	FEN        string   `json:"fen"`
+	BestMoves  []string `json:"bm,omitempty"`
+	AvoidMoves []string `json:"am,omitempty"`
+	Move       string   `json:"move"`
+	Solved     bool     `json:"solved"`
+	Depth      int      `json:"depth"`
+	Score      int      `json:"score"`
+	Nodes      int      `json:"nodes"`
+	TimeMs     int64    `json:"time_ms"`
+	Error      string   `json:"error,omitempty"`
+}
+
+// Report summarises a test suite run.
+type Report struct {
+	Total   int      `json:"total"`
+	Solved  int      `json:"solved"`
+	Results []Result `json:"results"`
+}
+
+// Run searches every position with e on boards of the given representation,
+// using its Depth and MoveTime limits, and checks the chosen move against
+// the "bm" and "am" operations. A position is solved if the move is one of
+// the best moves, if any are given, and none of the moves to avoid.
+// Positions with a "dm N" (direct mate) operation are solved by finding a
+// mate in at most N moves instead. A position that cannot be set up or
+// checked, e.g. with a bad FEN, gets an Error and the run goes on.
+func Run(e *engine.Engine, representation string, positions []Position) Report {
+	report := Report{Total: len(positions)}
+	onInfo := e.OnInfo
+	defer func() { e.OnInfo = onInfo }()
+
+	for _, p := range positions {
+		r, err := runPosition(e, representation, p)
+		if err != nil {
+			r.Error = err.Error()
+		}
+		if r.Solved {
+			report.Solved++
+		}
+		report.Results = append(report.Results, r)
+	}
+	return report
+}
+
+func runPosition(e *engine.Engine, representation string, p Position) (Result, error) {
+	r := Result{ID: p.ID(), FEN: p.FEN, BestMoves: p.BestMoves(), AvoidMoves: p.AvoidMoves()}
+	b, err := chess.NewBoard(representation, p.FEN)
+	if err != nil {
+		return r, err
+	}
+	if len(b.GenerateLegalMoves()) == 0 {
+		return r, fmt.Errorf("no legal moves")
+	}
+	bm, err := parseMoves(b, r.BestMoves)
+	if err != nil {
+		return r, err
+	}
+	am, err := parseMoves(b, r.AvoidMoves)
+	if err != nil {
+		return r, err
+	}
+	mate := 0
+	if dm := p.Ops["dm"]; len(dm) == 1 {
+		n, err := strconv.Atoi(dm[0])
+		if err != nil || n < 1 {
+			return r, fmt.Errorf("bad dm operation %q", dm[0])
+		}
+		mate = n
+	}
+	if len(bm) == 0 && len(am) == 0 && mate == 0 {
+		return r, fmt.Errorf("no bm, am or dm operation")
+	}
+
+	var last engine.Info
+	e.OnInfo = func(info engine.Info) {
+		if info.MultiPV == 1 {
+			last = info
+		}
+	}
+	start := time.Now()
+	var m chess.Move
+	found := true
+	if mate > 0 {
+		m, found = e.FindMate(context.Background(), b.Clone(), mate)
+	} else {
+		m = e.FindBestMove(b.Clone())
+	}
+	r.TimeMs = time.Since(start).Milliseconds()
+	r.Depth, r.Score, r.Nodes = last.Depth, last.Score, last.Nodes
+	if found {
+		r.Move = chess.FormatSAN(b, m)
+	}
+	r.Solved = found && (len(bm) == 0 || containsMove(bm, m)) && !containsMove(am, m)
+	return r, nil
+}
+
+// WriteText writes one line per position followed by the solved count.
+func (r Report) WriteText(w io.Writer) {
+	for i, res := range r.Results {
+		status := "FAIL"
+		if res.Solved {
+			status = "ok  "
+		}
+		id := res.ID
+		if id == "" {
+			id = fmt.Sprintf("#%d", i+1)
+		}
+		if res.Error != "" {
+			fmt.Fprintf(w, "ERR  %-16s %s\n", id, res.Error)
+			continue
+		}
+		fmt.Fprintf(w, "%s %-16s move %-7s bm %v am %v depth %d score %d nodes %d time %dms\n",
+			status, id, res.Move, res.BestMoves, res.AvoidMoves, res.Depth, res.Score, res.Nodes, res.TimeMs)
+	}
+	fmt.Fprintf(w, "Solved %d of %d\n", r.Solved, r.Total)
+}
+
+func parseMoves(b chess.Board, sans []string) ([]chess.Move, error) {
+	var moves []chess.Move
+	for _, san := range sans {
+		m, err := chess.ParseSAN(b, san)
+		if err != nil {
+			return nil, err
+		}
+		moves = append(moves, m)
+	}
+	return moves, nil
+}
+
+func containsMove(moves []chess.Move, m chess.Move) bool {
+	for _, x := range moves {
+		if x == m {
+			return true
+		}
+	}
+	return false
+}
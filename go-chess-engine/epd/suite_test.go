@@ -0,0 +1,53 @@
+package epd
+
+import (
+	"go-chess-engine/engine"
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	suite := `6k1/5ppp/8/8/8/8/8/R5K1 w - - bm Ra8#; id "bm";
+6k1/5ppp/8/8/8/8/8/R5K1 w - - am Ra2; id "am";
+6k1/5ppp/8/8/8/8/8/R5K1 w - - am Ra8#; id "am missed";
+k7/8/2K5/8/8/8/8/7R w - - dm 2; id "dm";
+8/8 w - - bm e4; id "bad fen";
+6k1/5ppp/8/8/8/8/8/R5K1 w - - id "no ops";
+6k1/5ppp/8/8/8/8/8/R5K1 w - - bm Qa8; id "bad bm";
+`
+	positions, err := Parse(strings.NewReader(suite))
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := engine.New()
+	e.Depth = 3
+	report := Run(e, "bitboard", positions)
+
+	want := []struct {
+		solved bool
+		err    string
+	}{
+		{true, ""},
+		{true, ""},
+		{false, ""},
+		{true, ""},
+		{false, "fen"},
+		{false, "no bm, am or dm"},
+		{false, "Qa8"},
+	}
+	if report.Total != len(want) || len(report.Results) != len(want) {
+		t.Fatalf("report has %d of %d results, want %d", len(report.Results), report.Total, len(want))
+	}
+	for i, w := range want {
+		r := report.Results[i]
+		if r.Solved != w.solved || (w.err == "") != (r.Error == "") || !strings.Contains(r.Error, w.err) {
+			t.Errorf("%s: solved %v, error %q, want %v, %q", r.ID, r.Solved, r.Error, w.solved, w.err)
+		}
+	}
+	if report.Solved != 3 {
+		t.Errorf("solved %d, want 3", report.Solved)
+	}
+	if m := report.Results[0].Move; m != "Ra8#" {
+		t.Errorf("bm: played %s", m)
+	}
+}
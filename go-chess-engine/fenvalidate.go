@@ -0,0 +1,53 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"go-chess-engine/chess"
+	"go-chess-engine/config"
+	"os"
+	"strings"
+)
+
+// fenValidateCommand implements "fen-validate [-q] [FEN]": it checks the
+// FEN given as arguments, or else every line of standard input, and fails
+// if any of them is invalid.
+func fenValidateCommand(fs *flag.FlagSet) func(config.Config, []string) int {
+	quiet := fs.Bool("q", false, "only report invalid FENs")
+	return func(cfg config.Config, args []string) int {
+		var fens []string
+		if len(args) > 0 {
+			fens = []string{strings.Join(args, " ")} // The FEN may or may not be quoted
+		} else {
+			scanner := bufio.NewScanner(os.Stdin)
+			for scanner.Scan() {
+				if line := strings.TrimSpace(scanner.Text()); line != "" {
+					fens = append(fens, line)
+				}
+			}
+			if err := scanner.Err(); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return exitFailure
+			}
+		}
+
+		code := exitOK
+		for _, fen := range fens {
+			p, err := chess.ParseFEN(fen)
+			if err == nil {
+				if err = p.Validate(); err != nil {
+					err = fmt.Errorf("fen %q: %v", fen, err)
+				}
+			}
+			switch {
+			case err != nil:
+				fmt.Println("invalid:", err)
+				code = exitFailure
+			case !*quiet:
+				fmt.Println("ok:", p.FEN())
+			}
+		}
+		return code
+	}
+}
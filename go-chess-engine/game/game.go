@@ -0,0 +1,129 @@
+// Package game follows a game as it is played: the moves, the positions
+// they lead to and the rules that end it.
+package game
+
+import (
+	"fmt"
+	"go-chess-engine/chess"
+	"strings"
+)
+
+// Game is a game in progress. It keeps the position after every move, so
+// moves can be taken back and repetitions spotted.
+type Game struct {
+	boards []chess.Board // boards[i] is the position after i moves
+	keys   []string      // keys[i] is the PositionKey of boards[i]
+	moves  []chess.Move
+}
+
+// New starts a game from the position on b, which the game takes over.
+func New(b chess.Board) *Game {
+	return &Game{boards: []chess.Board{b}, keys: []string{PositionKey(b)}}
+}
+
+// Board returns the current position. It must not be changed; use Play.
+func (g *Game) Board() chess.Board {
+	return g.boards[len(g.boards)-1]
+}
+
+// Start returns the position the game started from. It must not be changed.
+func (g *Game) Start() chess.Board {
+	return g.boards[0]
+}
+
+// Moves returns the moves played so far.
+func (g *Game) Moves() []chess.Move {
+	return g.moves
+}
+
+// Play plays m if it is legal in the current position.
+func (g *Game) Play(m chess.Move) error {
+	b := g.Board()
+	legal := false
+	for _, l := range b.GenerateLegalMoves() {
+		legal = legal || l == m
+	}
+	if !legal {
+		return fmt.Errorf("illegal move %s", chess.FormatMove(m))
+	}
+	next := b.Clone()
+	next.ApplyMove(m)
+	g.boards = append(g.boards, next)
+	g.keys = append(g.keys, PositionKey(next))
+	g.moves = append(g.moves, m)
+	return nil
+}
+
+// Undo takes back the last move. It reports false if no move was played.
+func (g *Game) Undo() bool {
+	if len(g.moves) == 0 {
+		return false
+	}
+	g.boards = g.boards[:len(g.boards)-1]
+	g.keys = g.keys[:len(g.keys)-1]
+	g.moves = g.moves[:len(g.moves)-1]
+	return true
+}
+
+// Repetitions counts how often the current position has occurred,
+// including now.
+func (g *Game) Repetitions() int {
+	key := g.keys[len(g.keys)-1]
+	n := 0
+	for _, k := range g.keys {
+		if k == key {
+			n++
+		}
+	}
+	return n
+}
+
+// Outcome applies the rules of chess: mate, stalemate, the fifty-move
+// rule, threefold repetition and insufficient material. It returns the
+// result ("1-0", "0-1" or "1/2-1/2") and the reason, or "" while the game
+// goes on.
+func (g *Game) Outcome() (string, string) {
+	b := g.Board()
+	if len(b.GenerateLegalMoves()) == 0 {
+		if !b.InCheck() {
+			return "1/2-1/2", "stalemate"
+		}
+		if b.SideToMove() == chess.White {
+			return "0-1", "Black mates"
+		}
+		return "1-0", "White mates"
+	}
+	if b.HalfmoveClock() >= 100 {
+		return "1/2-1/2", "fifty-move rule"
+	}
+	if g.Repetitions() >= 3 {
+		return "1/2-1/2", "threefold repetition"
+	}
+	if InsufficientMaterial(b) {
+		return "1/2-1/2", "insufficient material"
+	}
+	return "", ""
+}
+
+// PositionKey identifies a position for repetition detection: the first
+// four FEN fields, without the clocks.
+func PositionKey(b chess.Board) string {
+	fields := strings.Fields(b.ToFEN())
+	return strings.Join(fields[:4], " ")
+}
+
+// InsufficientMaterial reports whether neither side can possibly mate:
+// bare kings, or a king and a single minor piece against a bare king.
+func InsufficientMaterial(b chess.Board) bool {
+	minors := 0
+	for sq := 0; sq < 64; sq++ {
+		switch b.PieceAt(sq) {
+		case chess.Empty, chess.WhiteKing, chess.BlackKing:
+		case chess.WhiteKnight, chess.WhiteBishop, chess.BlackKnight, chess.BlackBishop:
+			minors++
+		default:
+			return false
+		}
+	}
+	return minors <= 1
+}
@@ -0,0 +1,147 @@
+package game
+
+import (
+	"go-chess-engine/chess"
+	"strings"
+	"testing"
+)
+
+// newGame starts a game from fen on rep and plays the moves, given in UCI
+// notation and separated by spaces.
+func newGame(t *testing.T, rep, fen, moves string) *Game {
+	t.Helper()
+	b, err := chess.NewBoard(rep, fen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := New(b)
+	for _, m := range strings.Fields(moves) {
+		if err := g.Play(chess.ParseMove(m)); err != nil {
+			t.Fatalf("%s: %s: %v", rep, fen, err)
+		}
+	}
+	return g
+}
+
+func TestPlayUndo(t *testing.T) {
+	for _, rep := range []string{"array", "bitboard"} {
+		g := newGame(t, rep, chess.StartFEN, "e2e4")
+		if err := g.Play(chess.ParseMove("e2e4")); err == nil {
+			t.Errorf("%s: played e2e4 twice", rep)
+		}
+		if err := g.Play(chess.ParseMove("e7e5")); err != nil {
+			t.Fatal(err)
+		}
+		const after = "rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR w KQkq e6 0 2"
+		if got := g.Board().ToFEN(); got != after || len(g.Moves()) != 2 {
+			t.Errorf("%s: %s after %d moves, want %s after 2", rep, got, len(g.Moves()), after)
+		}
+
+		for _, want := range []string{"rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1", chess.StartFEN} {
+			if !g.Undo() {
+				t.Fatalf("%s: nothing to undo", rep)
+			}
+			if got := g.Board().ToFEN(); got != want {
+				t.Errorf("%s: undo gave %s, want %s", rep, got, want)
+			}
+		}
+		if g.Undo() || len(g.Moves()) != 0 || g.Start() != g.Board() {
+			t.Errorf("%s: undo went past the start", rep)
+		}
+	}
+}
+
+func TestRepetitions(t *testing.T) {
+	const shuffle = "g1f3 g8f6 f3g1 f6g8 "
+	tests := []struct {
+		fen, moves string
+		want       int
+	}{
+		{chess.StartFEN, "", 1},
+		{chess.StartFEN, shuffle, 2},
+		{chess.StartFEN, shuffle + shuffle, 3},
+		// The same squares in between do not count.
+		{chess.StartFEN, shuffle + "g1f3", 2},
+		// The same pieces without the castling rights are another position.
+		{"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", "h1h2 h8h7 h2h1 h7h8", 1},
+		{"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", "h1h2 h8h7 h2h1 h7h8 h1h2 h8h7 h2h1 h7h8", 2},
+	}
+	for _, rep := range []string{"array", "bitboard"} {
+		for _, tt := range tests {
+			g := newGame(t, rep, tt.fen, tt.moves)
+			if got := g.Repetitions(); got != tt.want {
+				t.Errorf("%s: %s %s: %d repetitions, want %d", rep, tt.fen, tt.moves, got, tt.want)
+			}
+		}
+
+		// Undo forgets the positions taken back.
+		g := newGame(t, rep, chess.StartFEN, shuffle+shuffle)
+		g.Undo()
+		g.Undo()
+		g.Undo()
+		g.Undo()
+		if got := g.Repetitions(); got != 2 {
+			t.Errorf("%s: %d repetitions after undoing a shuffle, want 2", rep, got)
+		}
+	}
+}
+
+func TestInsufficientMaterial(t *testing.T) {
+	tests := []struct {
+		fen  string
+		want bool
+	}{
+		{"4k3/8/8/8/8/8/8/4K3 w - - 0 1", true},
+		{"4k3/8/8/8/8/8/8/4KB2 w - - 0 1", true},
+		{"4k3/8/8/8/8/8/8/4KN2 w - - 0 1", true},
+		{"4kn2/8/8/8/8/8/8/4K3 w - - 0 1", true},
+		// Two minor pieces may mate, even if only in a helpmate.
+		{"4kn2/8/8/8/8/8/8/4KB2 w - - 0 1", false},
+		{"4k3/8/8/8/8/8/8/3NKN2 w - - 0 1", false},
+		{"4k3/8/8/8/8/8/8/2B1KB2 w - - 0 1", false},
+		{"4k3/8/8/8/8/8/4P3/4K3 w - - 0 1", false},
+		{"4k3/8/8/8/8/8/8/4KR2 w - - 0 1", false},
+		{"3qk3/8/8/8/8/8/8/4K3 w - - 0 1", false},
+		{chess.StartFEN, false},
+	}
+	for _, rep := range []string{"array", "bitboard"} {
+		for _, tt := range tests {
+			b, err := chess.NewBoard(rep, tt.fen)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := InsufficientMaterial(b); got != tt.want {
+				t.Errorf("%s: InsufficientMaterial(%s) = %v, want %v", rep, tt.fen, got, tt.want)
+			}
+		}
+	}
+}
+
+func TestOutcome(t *testing.T) {
+	tests := []struct {
+		fen, moves     string
+		result, reason string
+	}{
+		{chess.StartFEN, "e2e4", "", ""},
+		{chess.StartFEN, "f2f3 e7e5 g2g4 d8h4", "0-1", "Black mates"},
+		{"6k1/5ppp/8/8/8/8/8/R5K1 w - - 0 1", "a1a8", "1-0", "White mates"},
+		{"7k/8/6K1/8/8/8/8/5Q2 w - - 0 1", "f1f7", "1/2-1/2", "stalemate"},
+		{"4k3/8/8/8/8/8/8/R3K3 w - - 99 80", "a1a2", "1/2-1/2", "fifty-move rule"},
+		// A capture or pawn move resets the count.
+		{"4k3/8/8/8/8/8/p7/R3K3 w - - 99 80", "a1a2", "", ""},
+		{"4k3/8/8/8/8/8/P7/R3K3 w - - 99 80", "a2a3", "", ""},
+		// Mate on the hundredth half-move still wins.
+		{"6k1/5ppp/8/8/8/8/8/R5K1 w - - 99 80", "a1a8", "1-0", "White mates"},
+		{chess.StartFEN, "g1f3 g8f6 f3g1 f6g8 g1f3 g8f6 f3g1", "", ""},
+		{chess.StartFEN, "g1f3 g8f6 f3g1 f6g8 g1f3 g8f6 f3g1 f6g8", "1/2-1/2", "threefold repetition"},
+		{"4k3/8/8/8/8/8/3q4/4KB2 w - - 0 1", "e1d2", "1/2-1/2", "insufficient material"},
+	}
+	for _, rep := range []string{"array", "bitboard"} {
+		for _, tt := range tests {
+			g := newGame(t, rep, tt.fen, tt.moves)
+			if result, reason := g.Outcome(); result != tt.result || reason != tt.reason {
+				t.Errorf("%s: %s %s: %q (%s), want %q (%s)", rep, tt.fen, tt.moves, result, reason, tt.result, tt.reason)
+			}
+		}
+	}
+}
@@ -1,24 +1,54 @@
 package logging
 
 import (
-	"log"
+	"io"
+	"log/slog"
 	"os"
 )
 
-// Log is the global logger instance for the entire application.
-var Log *log.Logger
+// Log is the global logger instance for the entire application. Until
+// Setup is called it is the standard slog logger, writing to stderr.
+var Log = slog.Default()
 
-// init() is a special Go function that runs before main().
-// This is the perfect place to set up our logger, guaranteeing
-// it's ready before any other part of the application runs.
-func init() {
-	// Open the log file. O_TRUNC will clear the file on each start.
-	logFile, err := os.OpenFile("uci.log", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0666)
-	if err != nil {
-		// If we can't create a log file, we have to panic.
-		log.Fatalf("FATAL: Failed to open log file: %v", err)
+// Options selects where and how the application logs.
+type Options struct {
+	// File is appended to, so that external log rotation works; "" or
+	// "stderr" logs to standard error instead.
+	File string
+	// Level is the minimum level logged: "debug", "info", "warn" or "error".
+	// The UCI traffic is logged at debug level.
+	Level string
+	// JSON writes one JSON object per record instead of key=value text.
+	JSON bool
+}
+
+// Setup replaces Log according to opts and makes it the default slog and
+// log logger too, so packages logging through the standard library end up
+// in the same place. The returned function closes the log file.
+func Setup(opts Options) (func() error, error) {
+	var level slog.Level
+	if opts.Level != "" {
+		if err := level.UnmarshalText([]byte(opts.Level)); err != nil {
+			return nil, err
+		}
 	}
 
-	// Initialize the global logger.
-	Log = log.New(logFile, "", log.Ltime)
+	var w io.Writer = os.Stderr
+	closeFn := func() error { return nil }
+	if opts.File != "" && opts.File != "stderr" {
+		f, err := os.OpenFile(opts.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+		if err != nil {
+			return nil, err
+		}
+		w, closeFn = f, f.Close
+	}
+
+	handlerOpts := &slog.HandlerOptions{Level: level}
+	var h slog.Handler = slog.NewTextHandler(w, handlerOpts)
+	if opts.JSON {
+		h = slog.NewJSONHandler(w, handlerOpts)
+	}
+	Log = slog.New(h)
+	slog.SetDefault(Log)
+	return closeFn, nil
 }
@@ -1,18 +1,143 @@
 package main
 
 import (
-	// Import our new logging package. Its init() function will run automatically.
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"go-chess-engine/config"
 	"go-chess-engine/logging"
 	"go-chess-engine/uci"
 )
 
+// Exit codes, the same for every command.
+const (
+	exitOK      = 0
+	exitFailure = 1 // The command ran but failed, e.g. an unreadable file or a perft mismatch
+	exitUsage   = 2 // Bad flags, arguments or configuration
+)
+
+// A command is one of the tools the program runs. setup defines the
+// command's own flags on fs and returns the function that runs it with the
+// configuration and the arguments left after the flags.
+type command struct {
+	name    string
+	args    string // Synopsis of the arguments after the flags
+	summary string
+	setup   func(fs *flag.FlagSet) func(cfg config.Config, args []string) int
+}
+
+var commands = []command{
+	{"uci", "", "Run as a UCI engine on standard input and output (the default)", uciCommand},
+	{"perft", "[FEN]", "Count the move tree of a position, the start position by default", perftCommand},
+	{"bench", "", "Search a fixed set of positions and report nodes and speed", benchCommand},
+	{"epd", "FILE...", "Run EPD test suites", epdCommand},
+	{"play", "", "Play a game against the engine in the terminal", playCommand},
+	{"fen-validate", "[FEN]", "Check the FEN given as arguments, or FENs one per line on standard input", fenValidateCommand},
+	{"tune", "FILE...", "Fit the evaluation weights to the results of quiet positions", tuneCommand},
+	{"datagen", "", "Generate training data from engine self-play games", datagenCommand},
+	{"match", "", "Play games between two engine configurations", matchCommand},
+	{"difftest", "", "Compare the array board and the bitboard on random games", difftestCommand},
+}
+
 func main() {
-	// The logger is already set up by the logging package's init() function.
-	logging.Log.Println("--- Engine Started ---")
+	os.Exit(run(os.Args[1:]))
+}
+
+// run dispatches "[command] [flags] [arguments]" and returns the exit code.
+// Settings come from config.json, CHESS_* variables and the configuration
+// flags, which every command accepts.
+func run(args []string) int {
+	// Asking for help before any command lists the commands rather than the
+	// flags of the UCI engine.
+	if len(args) > 0 && slices.Contains([]string{"help", "-h", "-help", "--help"}, args[0]) {
+		usage(os.Stdout)
+		return exitOK
+	}
+	name := "uci"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+	for _, c := range commands {
+		if c.name == name {
+			return c.run(args)
+		}
+	}
+	fmt.Fprintf(os.Stderr, "unknown command %q\n\n", name)
+	usage(os.Stderr)
+	return exitUsage
+}
 
-	// Create and run the UCI handler. We no longer need to pass the logger.
-	handler := uci.NewHandler()
-	handler.Loop()
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "usage: go-chess-engine [command] [flags] [arguments]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %-13s %s\n", c.name, c.summary)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, `"go-chess-engine COMMAND -h" lists the flags of a command. Exit codes: 0 success,`)
+	fmt.Fprintln(w, "1 failure, 2 bad usage or configuration.")
+}
+
+// run parses the flags of the command, sets up the configuration and
+// logging and runs it.
+func (c command) run(args []string) int {
+	fs := flag.NewFlagSet(c.name, flag.ContinueOnError)
+	flags := config.AddFlags(fs)
+	run := c.setup(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: go-chess-engine %s [flags] %s\n\n%s.\n\nFlags:\n", c.name, c.args, c.summary)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return exitOK
+		}
+		return exitUsage
+	}
+
+	cfg, err := flags.Load(os.Getenv)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
+	// A GUI does not show the engine's standard error, so the UCI engine
+	// logs to a file unless told otherwise.
+	if cfg.LogFile == "" && c.name == "uci" {
+		cfg.LogFile = "uci.log"
+	}
+	closeLog, err := logging.Setup(logging.Options{File: cfg.LogFile, Level: cfg.LogLevel, JSON: cfg.LogJSON})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
+	defer closeLog()
+	return run(cfg, fs.Args())
+}
 
-	logging.Log.Println("--- Engine Quit ---")
+// uciCommand runs the UCI handler on the standard streams.
+func uciCommand(fs *flag.FlagSet) func(config.Config, []string) int {
+	return func(cfg config.Config, args []string) int {
+		if len(args) > 0 {
+			fs.Usage()
+			return exitUsage
+		}
+		logging.Log.Info("engine started", "board", cfg.BoardRepresentation)
+		handler, err := uci.NewHandler(context.Background(), cfg, os.Stdin, os.Stdout)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitUsage
+		}
+		if err := handler.Loop(); err != nil {
+			logging.Log.Error("reading commands", "err", err)
+			return exitFailure
+		}
+		logging.Log.Info("engine quit")
+		return exitOK
+	}
 }
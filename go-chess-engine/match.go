@@ -0,0 +1,133 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go-chess-engine/config"
+	"go-chess-engine/epd"
+	"go-chess-engine/match"
+	"go-chess-engine/pgn"
+	"os"
+	"time"
+)
+
+// matchCommand implements "match -e1 SPEC -e2 SPEC [flags]": it plays games
+// between two engine configurations and reports the score, the Elo
+// difference and optionally an SPRT. The configured board referees.
+func matchCommand(fs *flag.FlagSet) func(config.Config, []string) int {
+	e1 := fs.String("e1", "board=array", "first engine, e.g. board=array,depth=4 or cmd=./engine,option.Hash=64")
+	e2 := fs.String("e2", "board=bitboard", "second engine")
+	games := fs.Int("games", 10, "number of games")
+	depth := fs.Int("depth", 0, "search depth per move (0 = engine default, or unlimited with -time)")
+	moveTime := fs.Duration("time", 0, "search time per move, e.g. 100ms")
+	openings := fs.String("openings", "", "EPD or FEN file with one opening position per line")
+	randomPlies := fs.Int("random-plies", 4, "random legal moves played from each opening")
+	seed := fs.Int64("seed", time.Now().UnixNano(), "seed for the opening randomization")
+	maxPlies := fs.Int("max-plies", 400, "adjudicate a draw after this many plies (0 = never)")
+	resignScore := fs.Int("resign-score", 1000, "adjudicate a loss at this score in centipawns")
+	resignMoves := fs.Int("resign-moves", 3, "moves both engines must agree on a resignation (0 = never)")
+	drawScore := fs.Int("draw-score", 10, "adjudicate a draw within this score of equality")
+	drawMoves := fs.Int("draw-moves", 0, "moves both engines must agree on a draw (0 = never)")
+	pgnFile := fs.String("pgn", "", "write the games to this PGN file")
+	sprt := fs.String("sprt", "", "run an SPRT, e.g. 0,5 for H0: elo=0 against H1: elo=5")
+	alpha := fs.Float64("alpha", 0.05, "SPRT false positive rate")
+	beta := fs.Float64("beta", 0.05, "SPRT false negative rate")
+	return func(cfg config.Config, args []string) int {
+		if len(args) > 0 {
+			fs.Usage()
+			return exitUsage
+		}
+		mc := match.Config{
+			Games:       *games,
+			RandomPlies: *randomPlies,
+			Seed:        *seed,
+			Board:       cfg.BoardRepresentation,
+			MaxPlies:    *maxPlies,
+			ResignScore: *resignScore,
+			ResignMoves: *resignMoves,
+			DrawScore:   *drawScore,
+			DrawMoves:   *drawMoves,
+		}
+		if *sprt != "" {
+			t := match.SPRT{Alpha: *alpha, Beta: *beta}
+			if _, err := fmt.Sscanf(*sprt, "%g,%g", &t.Elo0, &t.Elo1); err != nil {
+				fmt.Fprintf(os.Stderr, "bad -sprt %q: %v\n", *sprt, err)
+				return exitUsage
+			}
+			mc.SPRT = &t
+		}
+		if *openings != "" {
+			f, err := os.Open(*openings)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return exitFailure
+			}
+			positions, err := epd.Parse(f)
+			f.Close()
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return exitFailure
+			}
+			for _, p := range positions {
+				mc.Openings = append(mc.Openings, p.FEN)
+			}
+		}
+
+		var players []match.Player
+		defer func() {
+			for _, p := range players {
+				p.Close()
+			}
+		}()
+		for _, s := range []string{*e1, *e2} {
+			spec, err := match.ParseSpec(s)
+			if err == nil {
+				var p match.Player
+				if p, err = spec.Start(*depth, *moveTime); err == nil {
+					players = append(players, p)
+					continue
+				}
+			}
+			fmt.Fprintf(os.Stderr, "engine %q: %v\n", s, err)
+			return exitFailure
+		}
+
+		var out *os.File
+		if *pgnFile != "" {
+			f, err := os.Create(*pgnFile)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return exitFailure
+			}
+			defer f.Close()
+			out = f
+		}
+		mc.OnGame = func(res match.GameResult, stats match.Stats) {
+			fmt.Printf("Game %d: %s - %s %s {%s}\n", res.Round, res.White, res.Black, res.Result, res.Reason)
+			fmt.Printf("Score of %s vs %s: %v\n", players[0].Name(), players[1].Name(), stats)
+			if mc.SPRT != nil {
+				fmt.Println(mc.SPRT.Status(stats))
+			}
+			if out != nil {
+				pgn.Write(out, []*pgn.Game{res.Game})
+			}
+		}
+
+		stats, err := match.Run(mc, players[0], players[1])
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitFailure
+		}
+		if mc.SPRT != nil {
+			switch mc.SPRT.Decision(stats) {
+			case "H1":
+				fmt.Println("SPRT: H1 accepted")
+			case "H0":
+				fmt.Println("SPRT: H0 accepted")
+			default:
+				fmt.Println("SPRT: no decision")
+			}
+		}
+		return exitOK
+	}
+}
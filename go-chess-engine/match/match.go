@@ -0,0 +1,248 @@
+// Package match plays games between two engine configurations, either
+// in-process or as UCI subprocesses, and keeps Elo and SPRT statistics.
+package match
+
+import (
+	"fmt"
+	"go-chess-engine/chess"
+	"go-chess-engine/engine"
+	"go-chess-engine/game"
+	"go-chess-engine/pgn"
+	"math/rand"
+	"strconv"
+	"time"
+)
+
+// Config controls how a match is played.
+type Config struct {
+	// Games is the number of games. Every opening is played twice, once
+	// with each player as White.
+	Games int
+	// Openings are the FENs the games start from, used in turn. Without
+	// openings every game starts from the standard position.
+	Openings []string
+	// RandomPlies random legal moves are played from each opening before
+	// the engines take over, so that games differ.
+	RandomPlies int
+	// Seed seeds the opening randomization.
+	Seed int64
+	// Board is the representation the referee checks moves with.
+	Board string
+
+	// MaxPlies adjudicates a draw after this many plies (0 = no limit).
+	MaxPlies int
+	// A game is adjudicated as lost for a side once both engines agree for
+	// ResignMoves moves each that it is at least ResignScore behind.
+	ResignScore, ResignMoves int
+	// A game is adjudicated as drawn once both engines score it within
+	// DrawScore of equality for DrawMoves moves each.
+	DrawScore, DrawMoves int
+
+	// SPRT, if set, stops the match early once the test has a decision.
+	SPRT *SPRT
+	// OnGame, if set, is called after every game.
+	OnGame func(GameResult, Stats)
+}
+
+// GameResult is the outcome of one game.
+type GameResult struct {
+	Round  int
+	White  string
+	Black  string
+	Result string // "1-0", "0-1" or "1/2-1/2"
+	Reason string
+	Game   *pgn.Game
+}
+
+// Run plays the match between p1 and p2 and returns the results from p1's
+// point of view.
+func Run(cfg Config, p1, p2 Player) (Stats, error) {
+	rng := rand.New(rand.NewSource(cfg.Seed))
+	var stats Stats
+	var fen string
+	var opening []chess.Move
+
+	for round := 1; round <= cfg.Games; round++ {
+		white, black := p1, p2
+		if round%2 == 0 {
+			white, black = p2, p1
+		} else {
+			var err error
+			fen = chess.StartFEN
+			if len(cfg.Openings) > 0 {
+				fen = cfg.Openings[(round/2)%len(cfg.Openings)]
+			}
+			if opening, err = randomOpening(cfg.Board, fen, cfg.RandomPlies, rng); err != nil {
+				return stats, err
+			}
+		}
+
+		res, err := playGame(cfg, round, white, black, fen, opening)
+		if err != nil {
+			return stats, err
+		}
+		switch {
+		case res.Result == "1/2-1/2":
+			stats.Draws++
+		case (res.Result == "1-0") == (white == p1):
+			stats.Wins++
+		default:
+			stats.Losses++
+		}
+		if cfg.OnGame != nil {
+			cfg.OnGame(res, stats)
+		}
+		if cfg.SPRT != nil && cfg.SPRT.Decision(stats) != "" {
+			break
+		}
+	}
+	return stats, nil
+}
+
+// randomOpening picks plies random legal moves from fen, retrying when the
+// game would end before the engines get to play.
+func randomOpening(rep, fen string, plies int, rng *rand.Rand) ([]chess.Move, error) {
+	for attempt := 0; attempt < 100; attempt++ {
+		b, err := chess.NewBoard(rep, fen)
+		if err != nil {
+			return nil, err
+		}
+		var moves []chess.Move
+		for len(moves) < plies {
+			legal := b.GenerateLegalMoves()
+			if len(legal) == 0 {
+				break
+			}
+			m := legal[rng.Intn(len(legal))]
+			b.ApplyMove(m)
+			moves = append(moves, m)
+		}
+		if len(moves) == plies && len(b.GenerateLegalMoves()) > 0 {
+			return moves, nil
+		}
+	}
+	return nil, fmt.Errorf("no playable random opening from %q", fen)
+}
+
+// playGame plays one game and adjudicates it.
+func playGame(cfg Config, round int, white, black Player, fen string, opening []chess.Move) (GameResult, error) {
+	res := GameResult{Round: round, White: white.Name(), Black: black.Name()}
+	b, err := chess.NewBoard(cfg.Board, fen)
+	if err != nil {
+		return res, err
+	}
+	for _, p := range []Player{white, black} {
+		if err := p.NewGame(); err != nil {
+			return res, err
+		}
+	}
+
+	record := pgn.NewGame(fen)
+	record.SetTag("Event", "Engine match")
+	record.SetTag("Date", time.Now().Format("2006.01.02"))
+	record.SetTag("Round", strconv.Itoa(round))
+	record.SetTag("White", res.White)
+	record.SetTag("Black", res.Black)
+	node := record.Root
+
+	g := game.New(b)
+	play := func(m chess.Move, comment string) error {
+		if err := g.Play(m); err != nil {
+			return err
+		}
+		node = node.AddMove(m)
+		node.Comment = comment
+		return nil
+	}
+	for i, m := range opening {
+		comment := ""
+		if i == len(opening)-1 {
+			comment = "random opening"
+		}
+		if err := play(m, comment); err != nil {
+			return res, err
+		}
+	}
+
+	var scores []int // Scores from White's point of view, one per engine move
+	for {
+		result, reason := g.Outcome()
+		if result == "" && cfg.MaxPlies > 0 && len(g.Moves()) >= cfg.MaxPlies {
+			result, reason = "1/2-1/2", "adjudication: move limit"
+		}
+		if result == "" {
+			result, reason = adjudicate(cfg, scores)
+		}
+		if result != "" {
+			res.Result, res.Reason = result, reason
+			break
+		}
+
+		side := g.Board().SideToMove()
+		mover, loss := white, "0-1"
+		if side == chess.Black {
+			mover, loss = black, "1-0"
+		}
+		m, score, err := mover.Play(fen, g.Moves())
+		if err != nil {
+			res.Result, res.Reason = loss, fmt.Sprintf("%s: %v", mover.Name(), err)
+			break
+		}
+		if side == chess.Black {
+			score = -score
+		}
+		if err := play(m, formatScore(score, side)); err != nil {
+			res.Result, res.Reason = loss, fmt.Sprintf("%s played illegal move %s", mover.Name(), chess.FormatMove(m))
+			break
+		}
+		scores = append(scores, score)
+	}
+
+	if node.Comment != "" {
+		node.Comment += ", "
+	}
+	node.Comment += res.Reason
+	record.Result = res.Result
+	res.Game = record
+	return res, nil
+}
+
+// adjudicate ends a game early when both engines agree on the outcome. The
+// last 2*n scores alternate between the two engines.
+func adjudicate(cfg Config, scores []int) (string, string) {
+	if n := 2 * cfg.ResignMoves; cfg.ResignMoves > 0 && len(scores) >= n {
+		whiteWins, blackWins := true, true
+		for _, s := range scores[len(scores)-n:] {
+			whiteWins = whiteWins && s >= cfg.ResignScore
+			blackWins = blackWins && s <= -cfg.ResignScore
+		}
+		if whiteWins {
+			return "1-0", "adjudication: Black resigns"
+		}
+		if blackWins {
+			return "0-1", "adjudication: White resigns"
+		}
+	}
+	if n := 2 * cfg.DrawMoves; cfg.DrawMoves > 0 && len(scores) >= n {
+		drawn := true
+		for _, s := range scores[len(scores)-n:] {
+			drawn = drawn && s <= cfg.DrawScore && s >= -cfg.DrawScore
+		}
+		if drawn {
+			return "1/2-1/2", "adjudication: draw"
+		}
+	}
+	return "", ""
+}
+
+// formatScore writes a White-relative score as a PGN comment in pawns, or
+// as "#N" for mates, from the mover's point of view.
+func formatScore(score int, mover chess.Color) string {
+	if mover == chess.Black {
+		score = -score
+	}
+	if engine.IsMateScore(score) {
+		return fmt.Sprintf("#%d", engine.MateIn(score))
+	}
+	return fmt.Sprintf("%+.2f", float64(score)/100)
+}
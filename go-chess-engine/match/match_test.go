@@ -0,0 +1,32 @@
+package match
+
+import "testing"
+
+func TestAdjudicate(t *testing.T) {
+	cfg := Config{ResignScore: 600, ResignMoves: 2, DrawScore: 10, DrawMoves: 3}
+	tests := []struct {
+		scores []int
+		result string
+	}{
+		{nil, ""},
+		{[]int{700, 650, 800, 900}, "1-0"},
+		{[]int{-700, -650, -800, -900}, "0-1"},
+		// Only the last moves count.
+		{[]int{-900, 700, 650, 800, 900}, "1-0"},
+		{[]int{700, 650, 800, 500}, ""},
+		{[]int{700, 650, 800}, ""},
+		{[]int{0, 5, -10, 10, 3, -2}, "1/2-1/2"},
+		{[]int{0, 5, -10, 11, 3, -2}, ""},
+		{[]int{0, 5, -10, 10, 3}, ""},
+	}
+	for _, tt := range tests {
+		if got, _ := adjudicate(cfg, tt.scores); got != tt.result {
+			t.Errorf("adjudicate(%v) = %q, want %q", tt.scores, got, tt.result)
+		}
+	}
+
+	// Without limits nothing is adjudicated.
+	if got, _ := adjudicate(Config{}, []int{900, 900, 900, 900, 0, 0, 0, 0}); got != "" {
+		t.Errorf("adjudicate without limits = %q", got)
+	}
+}
@@ -0,0 +1,236 @@
+package match
+
+import (
+	"bufio"
+	"fmt"
+	"go-chess-engine/chess"
+	"go-chess-engine/engine"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Player is one side of a match.
+type Player interface {
+	Name() string
+	// NewGame tells the player that a new game starts.
+	NewGame() error
+	// Play returns the move to play in the position reached by playing
+	// moves from startFEN, together with the player's score of the position
+	// in centipawns from its own point of view.
+	Play(startFEN string, moves []chess.Move) (chess.Move, int, error)
+	Close() error
+}
+
+// Builtin is a player that runs the engine in-process on the given board
+// representation.
+type Builtin struct {
+	name   string
+	board  string
+	Engine *engine.Engine
+}
+
+// NewBuiltin creates an in-process player searching with e on boards of the
+// given representation ("array" or "bitboard").
+func NewBuiltin(name, board string, e *engine.Engine) *Builtin {
+	return &Builtin{name: name, board: board, Engine: e}
+}
+
+func (p *Builtin) Name() string   { return p.name }
+func (p *Builtin) NewGame() error { return nil }
+func (p *Builtin) Close() error   { return nil }
+
+func (p *Builtin) Play(startFEN string, moves []chess.Move) (chess.Move, int, error) {
+	b, err := chess.NewBoard(p.board, startFEN)
+	if err != nil {
+		return chess.Move{}, 0, err
+	}
+	for _, m := range moves {
+		b.ApplyMove(m)
+	}
+	var score int
+	p.Engine.OnInfo = func(info engine.Info) {
+		if info.MultiPV == 1 {
+			score = info.Score
+		}
+	}
+	m := p.Engine.FindBestMove(b)
+	return m, score, nil
+}
+
+// UCI is a player that talks to an engine subprocess over UCI.
+type UCI struct {
+	name     string
+	cmd      *exec.Cmd
+	stdin    io.WriteCloser
+	lines    chan string
+	depth    int
+	moveTime time.Duration
+}
+
+// StartUCI starts the engine at path, sets the given UCI options and waits
+// until it is ready. Every move is searched with "go movetime" if moveTime
+// is positive and with "go depth" otherwise.
+func StartUCI(name, path string, options map[string]string, depth int, moveTime time.Duration) (*UCI, error) {
+	cmd := exec.Command(path)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	p := &UCI{name: name, cmd: cmd, stdin: stdin, lines: make(chan string, 64), depth: depth, moveTime: moveTime}
+	go func() {
+		sc := bufio.NewScanner(stdout)
+		for sc.Scan() {
+			p.lines <- sc.Text()
+		}
+		close(p.lines)
+	}()
+
+	p.send("uci")
+	if _, err := p.waitFor("uciok", uciTimeout); err != nil {
+		p.Close()
+		return nil, err
+	}
+	for name, value := range options {
+		p.send(fmt.Sprintf("setoption name %s value %s", name, value))
+	}
+	if err := p.isReady(); err != nil {
+		p.Close()
+		return nil, err
+	}
+	return p, nil
+}
+
+// uciTimeout bounds how long we wait for an answer that needs no search.
+const uciTimeout = 10 * time.Second
+
+func (p *UCI) Name() string { return p.name }
+
+func (p *UCI) NewGame() error {
+	p.send("ucinewgame")
+	return p.isReady()
+}
+
+func (p *UCI) Play(startFEN string, moves []chess.Move) (chess.Move, int, error) {
+	var sb strings.Builder
+	sb.WriteString("position fen " + startFEN)
+	if len(moves) > 0 {
+		sb.WriteString(" moves")
+		for _, m := range moves {
+			sb.WriteString(" " + chess.FormatMove(m))
+		}
+	}
+	p.send(sb.String())
+	timeout := uciTimeout + 60*time.Second
+	if p.moveTime > 0 {
+		p.send(fmt.Sprintf("go movetime %d", p.moveTime.Milliseconds()))
+		timeout = uciTimeout + 2*p.moveTime
+	} else {
+		p.send(fmt.Sprintf("go depth %d", p.depth))
+	}
+
+	score := 0
+	for {
+		line, err := p.next(timeout)
+		if err != nil {
+			return chess.Move{}, 0, err
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "info":
+			if s, ok := parseScore(fields); ok {
+				score = s
+			}
+		case "bestmove":
+			if len(fields) < 2 || len(fields[1]) < 4 {
+				return chess.Move{}, 0, fmt.Errorf("%s: bad reply %q", p.name, line)
+			}
+			return chess.ParseMove(fields[1]), score, nil
+		}
+	}
+}
+
+func (p *UCI) Close() error {
+	p.send("quit")
+	p.stdin.Close()
+	done := make(chan error, 1)
+	go func() { done <- p.cmd.Wait() }()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(uciTimeout):
+		p.cmd.Process.Kill()
+		return fmt.Errorf("%s: did not quit", p.name)
+	}
+}
+
+func (p *UCI) send(msg string) {
+	fmt.Fprintln(p.stdin, msg)
+}
+
+func (p *UCI) isReady() error {
+	p.send("isready")
+	_, err := p.waitFor("readyok", uciTimeout)
+	return err
+}
+
+// waitFor reads lines until one starts with token and returns it.
+func (p *UCI) waitFor(token string, timeout time.Duration) (string, error) {
+	for {
+		line, err := p.next(timeout)
+		if err != nil {
+			return "", err
+		}
+		if strings.HasPrefix(line, token) {
+			return line, nil
+		}
+	}
+}
+
+func (p *UCI) next(timeout time.Duration) (string, error) {
+	select {
+	case line, ok := <-p.lines:
+		if !ok {
+			return "", fmt.Errorf("%s: engine exited", p.name)
+		}
+		return line, nil
+	case <-time.After(timeout):
+		return "", fmt.Errorf("%s: no answer within %v", p.name, timeout)
+	}
+}
+
+// parseScore reads "score cp X" or "score mate N" from an info line and
+// converts mates to the engine's internal mate scores.
+func parseScore(fields []string) (int, bool) {
+	for i := 0; i+2 < len(fields); i++ {
+		if fields[i] != "score" {
+			continue
+		}
+		n, err := strconv.Atoi(fields[i+2])
+		if err != nil {
+			return 0, false
+		}
+		switch fields[i+1] {
+		case "cp":
+			return n, true
+		case "mate":
+			if n > 0 {
+				return engine.MateScore - (2*n - 1), true
+			}
+			return -engine.MateScore - 2*n, true
+		}
+	}
+	return 0, false
+}
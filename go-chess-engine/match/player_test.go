@@ -0,0 +1,39 @@
+package match
+
+import (
+	"go-chess-engine/engine"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestParseScore(t *testing.T) {
+	tests := []struct {
+		line  string
+		score int
+		ok    bool
+	}{
+		{"info depth 5 score cp 35 nodes 1000 pv e2e4", 35, true},
+		{"info depth 5 score cp -120 lowerbound", -120, true},
+		{"info depth 3 score mate 1 pv a1a8", engine.MateScore - 1, true},
+		{"info depth 5 score mate 3", engine.MateScore - 5, true},
+		{"info depth 4 score mate -2", -engine.MateScore + 4, true},
+		{"info depth 5 nodes 1000", 0, false},
+		{"info depth 5 score cp x", 0, false},
+		{"info score", 0, false},
+	}
+	for _, tt := range tests {
+		score, ok := parseScore(strings.Fields(tt.line))
+		if score != tt.score || ok != tt.ok {
+			t.Errorf("parseScore(%q) = %d, %v, want %d, %v", tt.line, score, ok, tt.score, tt.ok)
+		}
+	}
+
+	// Mate scores read back as the engine's own.
+	for _, n := range []int{1, 2, 7, -1, -3} {
+		score, _ := parseScore([]string{"score", "mate", strconv.Itoa(n)})
+		if !engine.IsMateScore(score) || engine.MateIn(score) != n {
+			t.Errorf("mate %d parses as %d, MateIn %d", n, score, engine.MateIn(score))
+		}
+	}
+}
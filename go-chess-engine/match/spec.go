@@ -0,0 +1,108 @@
+package match
+
+import (
+	"fmt"
+	"go-chess-engine/book"
+	"go-chess-engine/chess"
+	"go-chess-engine/engine"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Spec describes a player on the command line as comma separated
+// key=value pairs:
+//
+//	name=NAME        name in the PGN and reports
+//	board=REP        in-process engine on the "array" or "bitboard" board
+//	cmd=PATH         UCI engine subprocess instead of the in-process engine
+//	depth=N          search depth, overriding the match default
+//	time=D           search time per move, e.g. 100ms, overriding the default
+//	multipv=N        MultiPV of the in-process engine
+//	book=FILE        Polyglot book of the in-process engine
+//	option.NAME=V    UCI option sent to a subprocess engine
+type Spec struct {
+	Name     string
+	Board    string
+	Cmd      string
+	Depth    int
+	MoveTime time.Duration
+	MultiPV  int
+	Book     string
+	Options  map[string]string
+}
+
+// ParseSpec parses a player description, see Spec.
+func ParseSpec(s string) (Spec, error) {
+	spec := Spec{Board: "array", MultiPV: 1, Options: make(map[string]string)}
+	for _, kv := range strings.Split(s, ",") {
+		key, value, ok := strings.Cut(kv, "=")
+		if !ok {
+			return spec, fmt.Errorf("bad engine setting %q, want key=value", kv)
+		}
+		var err error
+		switch {
+		case key == "name":
+			spec.Name = value
+		case key == "board":
+			spec.Board = value
+		case key == "cmd":
+			spec.Cmd = value
+		case key == "depth":
+			spec.Depth, err = strconv.Atoi(value)
+		case key == "time":
+			spec.MoveTime, err = time.ParseDuration(value)
+		case key == "multipv":
+			spec.MultiPV, err = strconv.Atoi(value)
+		case key == "book":
+			spec.Book = value
+		case strings.HasPrefix(key, "option."):
+			spec.Options[strings.TrimPrefix(key, "option.")] = value
+		default:
+			return spec, fmt.Errorf("unknown engine setting %q", key)
+		}
+		if err != nil {
+			return spec, fmt.Errorf("bad value for %s: %v", key, err)
+		}
+	}
+	if spec.Name == "" {
+		spec.Name = spec.Board
+		if spec.Cmd != "" {
+			spec.Name = spec.Cmd
+		}
+	}
+	return spec, nil
+}
+
+// Start creates the player, using depth and moveTime unless the spec sets
+// its own limits.
+func (s Spec) Start(depth int, moveTime time.Duration) (Player, error) {
+	if s.Depth > 0 || s.MoveTime > 0 {
+		depth, moveTime = s.Depth, s.MoveTime
+	}
+	if s.Cmd != "" {
+		if depth <= 0 {
+			depth = engine.DefaultDepth
+		}
+		return StartUCI(s.Name, s.Cmd, s.Options, depth, moveTime)
+	}
+
+	if _, err := chess.NewBoard(s.Board, chess.StartFEN); err != nil {
+		return nil, err
+	}
+	e := engine.New()
+	e.Depth = depth
+	e.MoveTime = moveTime
+	if moveTime > 0 && depth == 0 {
+		e.Depth = 64 // Limited by time only
+	}
+	e.MultiPV = s.MultiPV
+	if s.Book != "" {
+		bk, err := book.Open(s.Book)
+		if err != nil {
+			return nil, err
+		}
+		e.Book = bk
+	}
+	return NewBuiltin(s.Name, s.Board, e), nil
+}
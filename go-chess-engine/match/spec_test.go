@@ -0,0 +1,43 @@
+package match
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseSpec(t *testing.T) {
+	got, err := ParseSpec("name=new,board=bitboard,depth=6,time=100ms,multipv=2,book=b.bin,option.Hash=16")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Spec{
+		Name: "new", Board: "bitboard", Depth: 6, MoveTime: 100 * time.Millisecond,
+		MultiPV: 2, Book: "b.bin", Options: map[string]string{"Hash": "16"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseSpec = %+v, want %+v", got, want)
+	}
+
+	// The name defaults to the command, else the board.
+	for s, name := range map[string]string{"board=bitboard": "bitboard", "cmd=./old,board=bitboard": "./old"} {
+		if spec, err := ParseSpec(s); err != nil || spec.Name != name {
+			t.Errorf("ParseSpec(%q): name %q, %v, want %q", s, spec.Name, err, name)
+		}
+	}
+
+	bad := []struct{ spec, err string }{
+		{"depth", "want key=value"},
+		{"board=array,,depth=3", "want key=value"},
+		{"hash=16", "unknown engine setting"},
+		{"depth=x", "bad value for depth"},
+		{"time=100", "bad value for time"},
+		{"multipv=two", "bad value for multipv"},
+	}
+	for _, tt := range bad {
+		if _, err := ParseSpec(tt.spec); err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("ParseSpec(%q) error %v, want %q", tt.spec, err, tt.err)
+		}
+	}
+}
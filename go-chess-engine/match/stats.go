@@ -0,0 +1,116 @@
+package match
+
+import (
+	"fmt"
+	"math"
+)
+
+// Stats counts the results of a match from the first player's point of view.
+type Stats struct {
+	Wins, Draws, Losses int
+}
+
+// Games is the number of finished games.
+func (s Stats) Games() int {
+	return s.Wins + s.Draws + s.Losses
+}
+
+// Score is the first player's average score per game, between 0 and 1.
+func (s Stats) Score() float64 {
+	if s.Games() == 0 {
+		return 0.5
+	}
+	return (float64(s.Wins) + 0.5*float64(s.Draws)) / float64(s.Games())
+}
+
+// variance is the per-game variance of the score.
+func (s Stats) variance() float64 {
+	n := float64(s.Games())
+	if n == 0 {
+		return 0
+	}
+	mu := s.Score()
+	w, d, l := float64(s.Wins)/n, float64(s.Draws)/n, float64(s.Losses)/n
+	return w*(1-mu)*(1-mu) + d*(0.5-mu)*(0.5-mu) + l*mu*mu
+}
+
+// Elo estimates the Elo difference of the first player over the second and
+// the half width of its 95% confidence interval. Both are infinite when one
+// side has scored every point.
+func (s Stats) Elo() (elo, margin float64) {
+	if s.Games() == 0 {
+		return 0, math.Inf(1)
+	}
+	mu := s.Score()
+	elo = scoreToElo(mu)
+	if math.IsInf(elo, 0) {
+		return elo, math.Inf(1)
+	}
+	stderr := math.Sqrt(s.variance() / float64(s.Games()))
+	lo, hi := scoreToElo(mu-1.959964*stderr), scoreToElo(mu+1.959964*stderr)
+	return elo, (hi - lo) / 2
+}
+
+func (s Stats) String() string {
+	elo, margin := s.Elo()
+	return fmt.Sprintf("+%d =%d -%d, score %.1f%%, Elo %+.1f +/- %.1f",
+		s.Wins, s.Draws, s.Losses, 100*s.Score(), elo, margin)
+}
+
+func scoreToElo(score float64) float64 {
+	if score <= 0 {
+		return math.Inf(-1)
+	}
+	if score >= 1 {
+		return math.Inf(1)
+	}
+	return -400 * math.Log10(1/score-1)
+}
+
+func eloToScore(elo float64) float64 {
+	return 1 / (1 + math.Pow(10, -elo/400))
+}
+
+// SPRT is a sequential probability ratio test of H0: the Elo difference is
+// Elo0 against H1: it is Elo1, with false positive rate Alpha and false
+// negative rate Beta.
+type SPRT struct {
+	Elo0, Elo1  float64
+	Alpha, Beta float64
+}
+
+// LLR is the log-likelihood ratio of H1 over H0 for the results so far,
+// using the normal approximation of the score distribution.
+func (t SPRT) LLR(s Stats) float64 {
+	v := s.variance()
+	if s.Games() == 0 || v == 0 {
+		return 0
+	}
+	s0, s1 := eloToScore(t.Elo0), eloToScore(t.Elo1)
+	return float64(s.Games()) * (s1 - s0) * (2*s.Score() - s0 - s1) / (2 * v)
+}
+
+// Bounds returns the LLR values at which H0 and H1 are accepted.
+func (t SPRT) Bounds() (lower, upper float64) {
+	return math.Log(t.Beta / (1 - t.Alpha)), math.Log((1 - t.Beta) / t.Alpha)
+}
+
+// Decision returns "H0" or "H1" once the test has accepted a hypothesis,
+// and "" while more games are needed.
+func (t SPRT) Decision(s Stats) string {
+	llr := t.LLR(s)
+	lower, upper := t.Bounds()
+	switch {
+	case llr >= upper:
+		return "H1"
+	case llr <= lower:
+		return "H0"
+	}
+	return ""
+}
+
+// Status describes the current state of the test in one line.
+func (t SPRT) Status(s Stats) string {
+	lower, upper := t.Bounds()
+	return fmt.Sprintf("SPRT [%g, %g]: LLR %.2f (%.2f, %.2f)", t.Elo0, t.Elo1, t.LLR(s), lower, upper)
+}
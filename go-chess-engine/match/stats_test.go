@@ -0,0 +1,67 @@
+package match
+
+import (
+	"math"
+	"testing"
+)
+
+func TestElo(t *testing.T) {
+	tests := []struct {
+		stats       Stats
+		elo, margin float64
+	}{
+		{Stats{Wins: 60, Draws: 20, Losses: 20}, 147.19, 66.01},
+		{Stats{Wins: 20, Draws: 20, Losses: 60}, -147.19, 66.01},
+		{Stats{Wins: 30, Draws: 40, Losses: 30}, 0, 53.16},
+		// Only draws: no spread at all.
+		{Stats{Draws: 10}, 0, 0},
+		{Stats{}, 0, math.Inf(1)},
+		{Stats{Wins: 5}, math.Inf(1), math.Inf(1)},
+		{Stats{Losses: 5}, math.Inf(-1), math.Inf(1)},
+	}
+	for _, tt := range tests {
+		elo, margin := tt.stats.Elo()
+		if !near(elo, tt.elo) || !near(margin, tt.margin) {
+			t.Errorf("%+v: Elo() = %.2f +/- %.2f, want %.2f +/- %.2f", tt.stats, elo, margin, tt.elo, tt.margin)
+		}
+	}
+}
+
+func TestSPRT(t *testing.T) {
+	sprt := SPRT{Elo0: 0, Elo1: 10, Alpha: 0.05, Beta: 0.05}
+	lower, upper := sprt.Bounds()
+	if !near(lower, -math.Log(19)) || !near(upper, math.Log(19)) {
+		t.Errorf("Bounds() = %.3f, %.3f, want -+%.3f", lower, upper, math.Log(19))
+	}
+	tests := []struct {
+		stats    Stats
+		llr      float64
+		decision string
+	}{
+		{Stats{}, 0, ""},
+		{Stats{Draws: 50}, 0, ""},
+		{Stats{Wins: 90, Draws: 100, Losses: 90}, -0.18, ""},
+		// Either side of the upper bound, 2.944.
+		{Stats{Wins: 176, Draws: 100, Losses: 100}, 2.93, ""},
+		{Stats{Wins: 177, Draws: 100, Losses: 100}, 2.97, "H1"},
+		// Either side of the lower bound.
+		{Stats{Wins: 100, Draws: 100, Losses: 165}, -2.91, ""},
+		{Stats{Wins: 100, Draws: 100, Losses: 166}, -2.95, "H0"},
+	}
+	for _, tt := range tests {
+		if llr := sprt.LLR(tt.stats); !near(llr, tt.llr) {
+			t.Errorf("%+v: LLR = %.3f, want %.2f", tt.stats, llr, tt.llr)
+		}
+		if got := sprt.Decision(tt.stats); got != tt.decision {
+			t.Errorf("%+v: Decision = %q, want %q", tt.stats, got, tt.decision)
+		}
+	}
+}
+
+// near reports whether got equals want to the two decimals the tests give.
+func near(got, want float64) bool {
+	if math.IsInf(want, 0) {
+		return got == want
+	}
+	return math.Abs(got-want) < 0.01
+}
@@ -0,0 +1,112 @@
+package nnue
+
+import "go-chess-engine/chess"
+
+// Accumulator holds the first layer's sums of a position for both
+// perspectives, indexed by chess.Color. The zero value is ready to be
+// filled by Refresh or Update.
+type Accumulator struct {
+	values [2][]int16
+	kings  [2]int // Square of each side's king
+}
+
+// Refresh computes acc from scratch for the position on b.
+func (n *Network) Refresh(acc *Accumulator, b chess.Board) {
+	for _, c := range []chess.Color{chess.White, chess.Black} {
+		n.refresh(acc, b, c)
+	}
+}
+
+func (n *Network) refresh(acc *Accumulator, b chess.Board, perspective chess.Color) {
+	king := chess.WhiteKing
+	if perspective == chess.Black {
+		king = chess.BlackKing
+	}
+	for sq := 0; sq < 64; sq++ {
+		if b.PieceAt(sq) == king {
+			acc.kings[perspective] = sq
+		}
+	}
+
+	values := n.values(acc, perspective)
+	copy(values, n.ftBias)
+	for sq := 0; sq < 64; sq++ {
+		if piece := b.PieceAt(sq); hasFeature(piece) {
+			n.add(values, feature(perspective, acc.kings[perspective], piece, sq))
+		}
+	}
+}
+
+// Update sets acc to the accumulator of after, the position reached by
+// playing m on before, whose accumulator is prev. Only the squares the move
+// can change are compared, unless a king moved: its perspective sees every
+// piece anew and is refreshed.
+func (n *Network) Update(acc, prev *Accumulator, before, after chess.Board, m chess.Move) {
+	mover := before.PieceAt(m.From)
+	squares := []int{m.From, m.To}
+	switch {
+	case mover == chess.WhiteKing || mover == chess.BlackKing:
+		// Castling also moves a rook along the back rank. Each square is
+		// compared once.
+		squares = squares[:0]
+		if m.From/8 != m.To/8 {
+			squares = append(squares, m.To)
+		}
+		rank := m.From / 8 * 8
+		for sq := rank; sq < rank+8; sq++ {
+			squares = append(squares, sq)
+		}
+	case (mover == chess.WhitePawn || mover == chess.BlackPawn) && m.From%8 != m.To%8:
+		// En passant takes the pawn beside the mover.
+		squares = append(squares, m.From/8*8+m.To%8)
+	}
+
+	for _, c := range []chess.Color{chess.White, chess.Black} {
+		if mover.Color() == c && (mover == chess.WhiteKing || mover == chess.BlackKing) {
+			n.refresh(acc, after, c)
+			continue
+		}
+		acc.kings[c] = prev.kings[c]
+		values := n.values(acc, c)
+		copy(values, prev.values[c])
+		for _, sq := range squares {
+			old, now := before.PieceAt(sq), after.PieceAt(sq)
+			if old == now {
+				continue
+			}
+			if hasFeature(old) {
+				n.sub(values, feature(c, acc.kings[c], old, sq))
+			}
+			if hasFeature(now) {
+				n.add(values, feature(c, acc.kings[c], now, sq))
+			}
+		}
+	}
+}
+
+// values returns the sums of one perspective, allocating them on first use.
+func (n *Network) values(acc *Accumulator, perspective chess.Color) []int16 {
+	if len(acc.values[perspective]) != n.hidden {
+		acc.values[perspective] = make([]int16, n.hidden)
+	}
+	return acc.values[perspective]
+}
+
+func (n *Network) add(values []int16, f int) {
+	weights := n.ftWeights[f*n.hidden : (f+1)*n.hidden]
+	for i, w := range weights {
+		values[i] += w
+	}
+}
+
+func (n *Network) sub(values []int16, f int) {
+	weights := n.ftWeights[f*n.hidden : (f+1)*n.hidden]
+	for i, w := range weights {
+		values[i] -= w
+	}
+}
+
+// hasFeature reports whether a piece is an input: every piece but the kings.
+func hasFeature(p chess.Piece) bool {
+	return p != chess.Empty && p != chess.WhiteKing && p != chess.BlackKing
+}
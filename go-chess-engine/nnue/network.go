@@ -0,0 +1,142 @@
+// Package nnue evaluates positions with an efficiently updatable neural
+// network: a HalfKP feature transformer feeding one output neuron, all in
+// quantized integers.
+//
+// The inputs are, for each side's perspective, the position of every piece
+// but the kings relative to that side's own king. The first layer's sums
+// (the accumulator) change by a few weights per move, so the search updates
+// them incrementally instead of recomputing them from the whole board.
+package nnue
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"go-chess-engine/chess"
+	"io"
+	"os"
+)
+
+// NumFeatures is the number of inputs per perspective: the own king's
+// square times ten kinds of non-king piece (five kinds of each colour)
+// times their square.
+const NumFeatures = 64 * 10 * 64
+
+// Quantization: the accumulator is clipped to 0..qa, the output weights are
+// scaled by qb and the output is scaled to centipawns by scale/(qa*qb).
+const (
+	qa    = 255
+	qb    = 64
+	scale = 400
+)
+
+const (
+	magic   = "GCNN"
+	version = 1
+)
+
+// Network is a loaded network. It is read-only, so one network can serve
+// several searches at once.
+//
+// The file format is little endian: the magic "GCNN", a uint32 version (1)
+// and a uint32 hidden size N, then int16 feature weights (NumFeatures rows of
+// N), N int16 feature biases, 2N int16 output weights (the side to move's
+// half first) and an int32 output bias, which is scaled by qa*qb.
+type Network struct {
+	hidden     int
+	ftWeights  []int16
+	ftBias     []int16
+	outWeights []int16
+	outBias    int32
+}
+
+// Open reads a network file.
+func Open(path string) (*Network, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	n, err := Load(bufio.NewReader(f))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return n, nil
+}
+
+// Load reads a network in the format described at Network.
+func Load(r io.Reader) (*Network, error) {
+	var header struct {
+		Magic   [4]byte
+		Version uint32
+		Hidden  uint32
+	}
+	if err := binary.Read(r, binary.LittleEndian, &header); err != nil {
+		return nil, fmt.Errorf("reading header: %w", err)
+	}
+	if string(header.Magic[:]) != magic {
+		return nil, errors.New("not a network file")
+	}
+	if header.Version != version {
+		return nil, fmt.Errorf("unsupported version %d", header.Version)
+	}
+	if header.Hidden == 0 || header.Hidden > 4096 {
+		return nil, fmt.Errorf("bad hidden layer size %d", header.Hidden)
+	}
+
+	hidden := int(header.Hidden)
+	n := &Network{
+		hidden:     hidden,
+		ftWeights:  make([]int16, NumFeatures*hidden),
+		ftBias:     make([]int16, hidden),
+		outWeights: make([]int16, 2*hidden),
+	}
+	for _, data := range []any{n.ftWeights, n.ftBias, n.outWeights, &n.outBias} {
+		if err := binary.Read(r, binary.LittleEndian, data); err != nil {
+			return nil, fmt.Errorf("reading weights: %w", err)
+		}
+	}
+	if _, err := io.ReadFull(r, make([]byte, 1)); err != io.EOF {
+		return nil, errors.New("trailing data after the weights")
+	}
+	return n, nil
+}
+
+// Hidden returns the size of the hidden layer.
+func (n *Network) Hidden() int {
+	return n.hidden
+}
+
+// Evaluate scores the position whose accumulator is acc in centipawns,
+// from the point of view of stm, the side to move.
+func (n *Network) Evaluate(acc *Accumulator, stm chess.Color) int {
+	us, them := acc.values[stm], acc.values[1-stm]
+	var sum int64
+	for i := 0; i < n.hidden; i++ {
+		sum += int64(crelu(us[i])) * int64(n.outWeights[i])
+		sum += int64(crelu(them[i])) * int64(n.outWeights[n.hidden+i])
+	}
+	return int((sum + int64(n.outBias)) * scale / (qa * qb))
+}
+
+// crelu is the clipped ReLU activation of the hidden layer.
+func crelu(x int16) int16 {
+	return min(max(x, 0), qa)
+}
+
+// feature is the input index of piece on sq seen from perspective, whose
+// king is on king. Black's perspective mirrors the board vertically, so
+// both sides see their pieces as White would.
+func feature(perspective chess.Color, king int, piece chess.Piece, sq int) int {
+	if perspective == chess.Black {
+		king ^= 56
+		sq ^= 56
+	}
+	kind := int(piece-chess.WhitePawn) % 6 // Pawn 0 to queen 4
+	index := 2 * kind
+	if piece.Color() != perspective {
+		index++
+	}
+	return (king*10+index)*64 + sq
+}
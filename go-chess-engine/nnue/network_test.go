@@ -0,0 +1,151 @@
+package nnue
+
+import (
+	"bytes"
+	"encoding/binary"
+	"go-chess-engine/chess"
+	"math/rand"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func openTiny(t *testing.T) *Network {
+	t.Helper()
+	n, err := Open("testdata/tiny.nnue")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestOpen(t *testing.T) {
+	n := openTiny(t)
+	if n.Hidden() != 2 {
+		t.Errorf("Hidden() = %d, want 2", n.Hidden())
+	}
+	if _, err := Open("testdata/missing.nnue"); err == nil {
+		t.Error("Open succeeded on a missing file")
+	}
+}
+
+func TestLoadMalformed(t *testing.T) {
+	data, err := os.ReadFile("testdata/tiny.nnue")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// withHeader returns the file with its header word at offset replaced.
+	withHeader := func(offset int, v uint32) []byte {
+		b := slices.Clone(data)
+		binary.LittleEndian.PutUint32(b[offset:], v)
+		return b
+	}
+	tests := []struct {
+		name string
+		data []byte
+		err  string
+	}{
+		{"empty", nil, "reading header"},
+		{"short header", data[:6], "reading header"},
+		{"magic", append([]byte("NNUE"), data[4:]...), "not a network file"},
+		{"version", withHeader(4, 2), "unsupported version"},
+		{"no hidden layer", withHeader(8, 0), "bad hidden layer size"},
+		{"huge hidden layer", withHeader(8, 1<<20), "bad hidden layer size"},
+		{"truncated weights", data[:len(data)/2], "reading weights"},
+		{"truncated output bias", data[:len(data)-2], "reading weights"},
+		{"trailing data", append(slices.Clone(data), 0), "trailing data"},
+		// A larger hidden size than the weights written runs out of data.
+		{"hidden size", withHeader(8, 3), "reading weights"},
+	}
+	for _, tt := range tests {
+		_, err := Load(bytes.NewReader(tt.data))
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s: Load error %v, want %q", tt.name, err, tt.err)
+		}
+	}
+}
+
+// TestUpdate plays random games, with castling, en passant and promotions
+// along the way, and checks after every move that the incrementally
+// updated accumulator equals one computed from scratch.
+func TestUpdate(t *testing.T) {
+	n := openTiny(t)
+	starts := []string{
+		chess.StartFEN,
+		"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+		"r3k2r/Pppp1ppp/1b3nbN/nP6/BBP1P3/q4N2/Pp1P2PP/R2Q1RK1 w kq - 0 1",
+		"rnbqkb1r/pp1p1ppp/5n2/2pPp3/8/8/PPP1PPPP/RNBQKBNR w KQkq e6 0 4",
+		"bqnb1rkr/pp3ppp/3ppn2/2p5/5P2/P2P4/NPP1P1PP/BQ1BNRKR w HFhf - 2 9",
+	}
+	rng := rand.New(rand.NewSource(1))
+	for _, rep := range []string{"array", "bitboard"} {
+		for _, fen := range starts {
+			for game := 0; game < 5; game++ {
+				b, err := chess.NewBoard(rep, fen)
+				if err != nil {
+					t.Fatal(err)
+				}
+				var acc Accumulator
+				n.Refresh(&acc, b)
+				for ply := 0; ply < 100; ply++ {
+					moves := b.GenerateLegalMoves()
+					if len(moves) == 0 {
+						break
+					}
+					m := moves[rng.Intn(len(moves))]
+					after := b.Clone()
+					after.ApplyMove(m)
+
+					var updated, refreshed Accumulator
+					n.Update(&updated, &acc, b, after, m)
+					n.Refresh(&refreshed, after)
+					if !equalAccumulators(&updated, &refreshed) {
+						t.Fatalf("%s: %s after %s: updated %+v, refreshed %+v",
+							rep, b.ToFEN(), chess.FormatMove(m), updated, refreshed)
+					}
+					stm := after.SideToMove()
+					if n.Evaluate(&updated, stm) != n.Evaluate(&refreshed, stm) {
+						t.Fatalf("%s: %s: evaluations differ", rep, after.ToFEN())
+					}
+					b, acc = after, updated
+				}
+			}
+		}
+	}
+}
+
+// TestEvaluateMirrored checks that swapping the colours and flipping the
+// board keeps the evaluation for the side to move.
+func TestEvaluateMirrored(t *testing.T) {
+	n := openTiny(t)
+	for _, fen := range []string{
+		chess.StartFEN,
+		"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+		"8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1",
+	} {
+		p := chess.MustParseFEN(fen)
+		var m chess.Position
+		m.SideToMove = 1 - p.SideToMove
+		m.EnPassant = chess.NoSquare
+		for sq, piece := range p.Pieces {
+			switch {
+			case piece >= chess.BlackPawn:
+				piece -= chess.BlackPawn - chess.WhitePawn
+			case piece != chess.Empty:
+				piece += chess.BlackPawn - chess.WhitePawn
+			}
+			m.Pieces[sq^56] = piece
+		}
+		var a, b Accumulator
+		n.Refresh(&a, chess.NewArrayBoardFromPosition(p))
+		n.Refresh(&b, chess.NewArrayBoardFromPosition(m))
+		if ea, eb := n.Evaluate(&a, p.SideToMove), n.Evaluate(&b, m.SideToMove); ea != eb {
+			t.Errorf("%s: %d, but %d mirrored", fen, ea, eb)
+		}
+	}
+}
+
+func equalAccumulators(a, b *Accumulator) bool {
+	return a.kings == b.kings && slices.Equal(a.values[0], b.values[0]) && slices.Equal(a.values[1], b.values[1])
+}
@@ -0,0 +1,53 @@
+//go:build ignore
+
+// gen.go writes tiny.nnue, a network with a hidden layer of two neurons
+// and small random weights, for the nnue tests. It plays no chess worth
+// the name but exercises every input.
+//
+// Run it from this directory:
+//
+//	go run gen.go
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"log"
+	"math/rand"
+	"os"
+)
+
+const (
+	hidden      = 2
+	numFeatures = 64 * 10 * 64
+)
+
+func main() {
+	f, err := os.Create("tiny.nnue")
+	if err != nil {
+		log.Fatal(err)
+	}
+	w := bufio.NewWriter(f)
+	rng := rand.New(rand.NewSource(1))
+	weights := func(n, limit int) []int16 {
+		s := make([]int16, n)
+		for i := range s {
+			s[i] = int16(rng.Intn(2*limit+1) - limit)
+		}
+		return s
+	}
+	for _, data := range []any{
+		[]byte("GCNN"), uint32(1), uint32(hidden),
+		weights(numFeatures*hidden, 40), weights(hidden, 100), weights(2*hidden, 64), int32(1000),
+	} {
+		if err := binary.Write(w, binary.LittleEndian, data); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		log.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go-chess-engine/chess"
+	"go-chess-engine/config"
+	"os"
+	"strings"
+	"time"
+)
+
+// perftCommand implements "perft [-depth N] [-divide] [-expect N] [FEN]":
+// it counts the leaf nodes of the legal move tree of a position, the start
+// position by default, on the configured board. With -expect it fails when
+// the count differs, so scripts can check the move generator.
+func perftCommand(fs *flag.FlagSet) func(config.Config, []string) int {
+	depth := fs.Int("depth", 5, "depth in plies")
+	divide := fs.Bool("divide", false, "print the count below every legal move")
+	expect := fs.Int("expect", -1, "fail unless the count is this (-1 = no check)")
+	return func(cfg config.Config, args []string) int {
+		fen := chess.StartFEN
+		if len(args) > 0 {
+			fen = strings.Join(args, " ") // The FEN may or may not be quoted
+		}
+		b, err := chess.NewBoard(cfg.BoardRepresentation, fen)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitUsage
+		}
+
+		start := time.Now()
+		nodes := 0
+		if *divide && *depth > 0 {
+			for _, m := range b.GenerateLegalMoves() {
+				child := b.Clone()
+				child.ApplyMove(m)
+				n := chess.Perft(child, *depth-1)
+				fmt.Printf("%s: %d\n", chess.FormatMove(m), n)
+				nodes += n
+			}
+			fmt.Println()
+		} else {
+			nodes = chess.Perft(b, *depth)
+		}
+		elapsed := time.Since(start)
+
+		fmt.Printf("Nodes searched: %d\n", nodes)
+		fmt.Printf("Time: %v (%.0f nodes/s)\n", elapsed.Round(time.Millisecond), float64(nodes)/elapsed.Seconds())
+		if *expect >= 0 && nodes != *expect {
+			fmt.Fprintf(os.Stderr, "perft %d: got %d nodes, want %d\n", *depth, nodes, *expect)
+			return exitFailure
+		}
+		return exitOK
+	}
+}
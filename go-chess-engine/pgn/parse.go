@@ -0,0 +1,278 @@
+package pgn
+
+import (
+	"bufio"
+	"fmt"
+	"go-chess-engine/chess"
+	"io"
+	"strconv"
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	tokSymbol tokenKind = iota // Moves, move numbers and results
+	tokString
+	tokComment
+	tokNAG
+	tokOpenTag
+	tokCloseTag
+	tokOpenVariation
+	tokCloseVariation
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	line int
+}
+
+// suffixNAGs maps move suffix annotations to their NAG numbers.
+var suffixNAGs = map[string]int{"!": 1, "?": 2, "!!": 3, "??": 4, "!?": 5, "?!": 6}
+
+// Parse reads every game in r.
+func Parse(r io.Reader) ([]*Game, error) {
+	tokens, err := tokenize(r)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	var games []*Game
+	for p.pos < len(p.tokens) {
+		g, err := p.game()
+		if err != nil {
+			return nil, fmt.Errorf("pgn: game %d: %w", len(games)+1, err)
+		}
+		games = append(games, g)
+	}
+	return games, nil
+}
+
+// tokenize splits PGN text into tokens, dropping "%" escape lines.
+func tokenize(r io.Reader) ([]token, error) {
+	br := bufio.NewReader(r)
+	var tokens []token
+	line := 1
+	atLineStart := true
+
+	for {
+		c, err := br.ReadByte()
+		if err == io.EOF {
+			return tokens, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if c == '%' && atLineStart {
+			if _, err := br.ReadString('\n'); err != nil && err != io.EOF {
+				return nil, err
+			}
+			line++
+			continue
+		}
+		atLineStart = c == '\n'
+
+		switch {
+		case c == '\n':
+			line++
+		case c == ' ' || c == '\t' || c == '\r':
+		case c == '[':
+			tokens = append(tokens, token{tokOpenTag, "[", line})
+		case c == ']':
+			tokens = append(tokens, token{tokCloseTag, "]", line})
+		case c == '(':
+			tokens = append(tokens, token{tokOpenVariation, "(", line})
+		case c == ')':
+			tokens = append(tokens, token{tokCloseVariation, ")", line})
+		case c == '"':
+			var sb strings.Builder
+			for {
+				c, err := br.ReadByte()
+				if err != nil {
+					return nil, fmt.Errorf("pgn: line %d: unterminated string", line)
+				}
+				if c == '"' {
+					break
+				}
+				if c == '\\' {
+					if c, err = br.ReadByte(); err != nil {
+						return nil, fmt.Errorf("pgn: line %d: unterminated string", line)
+					}
+				}
+				sb.WriteByte(c)
+			}
+			tokens = append(tokens, token{tokString, sb.String(), line})
+		case c == '{':
+			text, err := br.ReadString('}')
+			if err != nil {
+				return nil, fmt.Errorf("pgn: line %d: unterminated comment", line)
+			}
+			tokens = append(tokens, token{tokComment, strings.TrimSpace(text[:len(text)-1]), line})
+			line += strings.Count(text, "\n")
+		case c == ';':
+			text, err := br.ReadString('\n')
+			if err != nil && err != io.EOF {
+				return nil, err
+			}
+			tokens = append(tokens, token{tokComment, strings.TrimSpace(text), line})
+			line++
+			atLineStart = true
+		case c == '$':
+			var sb strings.Builder
+			for {
+				c, err := br.ReadByte()
+				if err != nil || c < '0' || c > '9' {
+					if err == nil {
+						br.UnreadByte()
+					}
+					break
+				}
+				sb.WriteByte(c)
+			}
+			tokens = append(tokens, token{tokNAG, sb.String(), line})
+		default:
+			sb := strings.Builder{}
+			sb.WriteByte(c)
+			for {
+				c, err := br.ReadByte()
+				if err != nil {
+					break
+				}
+				if strings.IndexByte(" \t\r\n[](){};\"$", c) >= 0 {
+					br.UnreadByte()
+					break
+				}
+				sb.WriteByte(c)
+			}
+			tokens = append(tokens, token{tokSymbol, sb.String(), line})
+		}
+	}
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+// line is the state of the move sequence being read: the last node and the
+// positions before and after its move. Variations push a new line.
+type line struct {
+	node   *Node
+	before chess.Board
+	board  chess.Board
+}
+
+func (p *parser) game() (*Game, error) {
+	g := &Game{Root: &Node{}, Result: "*"}
+
+	// Tag pair section.
+	for p.pos < len(p.tokens) && p.tokens[p.pos].kind == tokOpenTag {
+		if p.pos+3 >= len(p.tokens) || p.tokens[p.pos+1].kind != tokSymbol ||
+			p.tokens[p.pos+2].kind != tokString || p.tokens[p.pos+3].kind != tokCloseTag {
+			return nil, fmt.Errorf("line %d: malformed tag", p.tokens[p.pos].line)
+		}
+		g.Tags = append(g.Tags, Tag{Name: p.tokens[p.pos+1].text, Value: p.tokens[p.pos+2].text})
+		p.pos += 4
+	}
+	if r := g.Tag("Result"); r != "" {
+		g.Result = r
+	}
+
+	// Movetext section.
+	cur := line{node: g.Root, board: newBoard(g.StartFEN())}
+	var stack []line
+	pendingComment := ""
+
+	for p.pos < len(p.tokens) {
+		tok := p.tokens[p.pos]
+		p.pos++
+
+		switch tok.kind {
+		case tokComment:
+			// A comment before the first move belongs to that move.
+			if cur.node == g.Root || cur.before == nil {
+				pendingComment = joinComment(pendingComment, tok.text)
+			} else {
+				cur.node.Comment = joinComment(cur.node.Comment, tok.text)
+			}
+		case tokNAG:
+			n, err := strconv.Atoi(tok.text)
+			if err != nil || cur.before == nil {
+				return nil, fmt.Errorf("line %d: misplaced NAG", tok.line)
+			}
+			cur.node.NAGs = append(cur.node.NAGs, n)
+		case tokOpenVariation:
+			// A variation replaces the last move, so it starts from its parent.
+			if cur.before == nil {
+				return nil, fmt.Errorf("line %d: variation without a move", tok.line)
+			}
+			stack = append(stack, cur)
+			cur = line{node: cur.node.Parent, board: cur.before.Clone()}
+		case tokCloseVariation:
+			if len(stack) == 0 {
+				return nil, fmt.Errorf("line %d: unmatched ')'", tok.line)
+			}
+			cur = stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+		case tokSymbol:
+			switch tok.text {
+			case "1-0", "0-1", "1/2-1/2", "*":
+				if len(stack) > 0 {
+					return nil, fmt.Errorf("line %d: unterminated variation", tok.line)
+				}
+				g.Result = tok.text
+				return g, nil
+			}
+			san := stripMoveNumber(tok.text)
+			if san == "" {
+				continue // Just a move number
+			}
+			san, nags := splitSuffix(san)
+			m, err := chess.ParseSAN(cur.board, san)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", tok.line, err)
+			}
+			node := cur.node.AddMove(m)
+			node.NAGs = nags
+			node.Before, pendingComment = pendingComment, ""
+			before := cur.board.Clone()
+			cur.board.ApplyMove(m)
+			cur.node, cur.before = node, before
+		default:
+			return nil, fmt.Errorf("line %d: unexpected %q", tok.line, tok.text)
+		}
+	}
+	if len(stack) > 0 {
+		return nil, fmt.Errorf("unterminated variation")
+	}
+	return g, nil // Game without a termination marker at the end of the file
+}
+
+// stripMoveNumber removes a leading move number such as "12." or "12...".
+func stripMoveNumber(s string) string {
+	i := 0
+	for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+		i++
+	}
+	if i > 0 && (i == len(s) || s[i] == '.') {
+		return strings.TrimLeft(s[i:], ".")
+	}
+	return s
+}
+
+// splitSuffix separates "!?"-style annotations from a move.
+func splitSuffix(s string) (string, []int) {
+	move := strings.TrimRight(s, "!?")
+	if nag, ok := suffixNAGs[s[len(move):]]; ok {
+		return move, []int{nag}
+	}
+	return move, nil
+}
+
+func joinComment(a, b string) string {
+	if a == "" {
+		return b
+	}
+	return a + " " + b
+}
@@ -0,0 +1,176 @@
+package pgn
+
+import (
+	"fmt"
+	"go-chess-engine/chess"
+	"slices"
+	"strings"
+	"testing"
+)
+
+const roundTripPGN = `% A line escaped from the parser
+[Event "Casual \"blitz\" game"]
+[Site "?"]
+[Date "2024.01.02"]
+[Round "1"]
+[White "White, A."]
+[Black "Black, B."]
+[Result "1-0"]
+[ECO "C50"]
+
+{Opening comment} 1. e4 e5 2. Nf3 $1 Nc6 (2... d6 {Philidor} 3. d4 (3. Bc4 Be7)
+3... exd4) 3. Bc4!? Bc5 ; the Giuoco Piano
+4. c3 Nf6 5. d4 exd4 6. cxd4 Bb4+ 7. Nc3?! (7. Bd2 Bxd2+ 8. Nbxd2 d5 $10) 7...
+Nxe4 8. O-O Bxc3 9. d5 $16 1-0
+
+[Event "Second"]
+[Result "0-1"]
+
+1. f3 e5 2. g4?? Qh4# 0-1
+
+[Event "Third"]
+[Result "1/2-1/2"]
+[SetUp "1"]
+[FEN "4k3/P7/8/8/8/8/8/4K2R w K - 0 40"]
+
+40. a8=Q+ (40. O-O Kd7) 40... Kd7 41. Qb7+ {Perpetual} 1/2-1/2
+
+[Event "Unfinished"]
+
+1. d4 d5 *
+`
+
+func TestParseRoundTrip(t *testing.T) {
+	games, err := Parse(strings.NewReader(roundTripPGN))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(games) != 4 {
+		t.Fatalf("parsed %d games, want 4", len(games))
+	}
+	for i, want := range []string{"1-0", "0-1", "1/2-1/2", "*"} {
+		if games[i].Result != want {
+			t.Errorf("game %d: result %q, want %q", i+1, games[i].Result, want)
+		}
+	}
+
+	var sb strings.Builder
+	if err := Write(&sb, games); err != nil {
+		t.Fatal(err)
+	}
+	written := sb.String()
+	again, err := Parse(strings.NewReader(written))
+	if err != nil {
+		t.Fatalf("parsing the written games: %v\n%s", err, written)
+	}
+	if len(again) != len(games) {
+		t.Fatalf("parsed %d written games, want %d", len(again), len(games))
+	}
+	for i := range games {
+		if err := equalGames(games[i], again[i]); err != nil {
+			t.Errorf("game %d: %v\n%s", i+1, err, again[i])
+		}
+	}
+
+	// Writing is stable once the game is in export format.
+	sb.Reset()
+	Write(&sb, again)
+	if sb.String() != written {
+		t.Errorf("second export differs:\n%s\nfirst:\n%s", sb.String(), written)
+	}
+}
+
+func TestParseAnnotations(t *testing.T) {
+	games, err := Parse(strings.NewReader(roundTripPGN))
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := games[0]
+	if got := g.Tag("Event"); got != `Casual "blitz" game` {
+		t.Errorf("Event = %q", got)
+	}
+	line := g.MainLine()
+	if len(line) != 17 {
+		t.Fatalf("main line has %d moves, want 17", len(line))
+	}
+	checks := []struct {
+		ply     int
+		nags    []int
+		before  string
+		comment string
+		vars    int
+	}{
+		{0, nil, "Opening comment", "", 0},
+		{2, []int{1}, "", "", 0},
+		{3, nil, "", "", 1},
+		{4, []int{5}, "", "", 0},
+		{5, nil, "", "the Giuoco Piano", 0},
+		{12, []int{6}, "", "", 1},
+		{16, []int{16}, "", "", 0},
+	}
+	for _, c := range checks {
+		n := line[c.ply]
+		if !slices.Equal(n.NAGs, c.nags) || n.Before != c.before || n.Comment != c.comment ||
+			len(n.Parent.Children)-1 != c.vars {
+			t.Errorf("ply %d: NAGs %v, before %q, comment %q, %d variations", c.ply,
+				n.NAGs, n.Before, n.Comment, len(n.Parent.Children)-1)
+		}
+	}
+
+	// The nested variation 3. Bc4 replaces 3. d4 inside 2... d6.
+	philidor := line[3].Parent.Children[1]
+	if philidor.Comment != "Philidor" || len(philidor.Children[0].Children) != 1 ||
+		len(philidor.Children) != 2 {
+		t.Errorf("2... d6 variation parsed wrong")
+	}
+	if b := g.BoardAt(line[16]); b.ToFEN() != "r1bqk2r/pppp1ppp/2n5/3P4/2B1n3/2b2N2/PP3PPP/R1BQ1RK1 b kq - 0 9" {
+		t.Errorf("final position %s", b.ToFEN())
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, input := range []string{
+		`[Event "x"`,
+		`[Event "x]`,
+		`1. e4 {unterminated`,
+		`1. e4 e5 2. Ke3 *`,
+		`1. e4 (1. d4 *`,
+		`1. e4 ) e5 *`,
+		`( 1. e4 ) *`,
+		`$1 1. e4 *`,
+	} {
+		if _, err := Parse(strings.NewReader(input)); err == nil {
+			t.Errorf("Parse(%q) succeeded", input)
+		}
+	}
+}
+
+// equalGames reports how two games differ, or nil.
+func equalGames(a, b *Game) error {
+	for _, t := range a.Tags {
+		if b.Tag(t.Name) != t.Value {
+			return fmt.Errorf("tag %s: %q, want %q", t.Name, b.Tag(t.Name), t.Value)
+		}
+	}
+	if a.Result != b.Result {
+		return fmt.Errorf("result %q, want %q", b.Result, a.Result)
+	}
+	return equalNodes(a.Root, b.Root, "")
+}
+
+func equalNodes(a, b *Node, path string) error {
+	if a.Move != b.Move || !slices.Equal(a.NAGs, b.NAGs) || a.Before != b.Before || a.Comment != b.Comment {
+		return fmt.Errorf("after %s: %s %v {%s} {%s}, want %s %v {%s} {%s}", path,
+			chess.FormatMove(b.Move), b.NAGs, b.Before, b.Comment,
+			chess.FormatMove(a.Move), a.NAGs, a.Before, a.Comment)
+	}
+	if len(a.Children) != len(b.Children) {
+		return fmt.Errorf("after %s: %d continuations, want %d", path, len(b.Children), len(a.Children))
+	}
+	for i := range a.Children {
+		if err := equalNodes(a.Children[i], b.Children[i], path+" "+chess.FormatMove(a.Children[i].Move)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
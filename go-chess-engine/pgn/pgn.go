@@ -0,0 +1,131 @@
+// Package pgn reads and writes games in Portable Game Notation.
+//
+// A game is stored as a tree of moves: the first child of a node continues
+// the line it belongs to, any further children are variations.
+package pgn
+
+import (
+	"go-chess-engine/chess"
+	"strconv"
+	"strings"
+)
+
+// Tag is one tag pair, e.g. [White "Kasparov, Garry"].
+type Tag struct {
+	Name  string
+	Value string
+}
+
+// Node is a move in the game tree. The root node of a game holds no move.
+type Node struct {
+	Move chess.Move
+	// NAGs are the numeric annotation glyphs of the move, e.g. 1 for "!".
+	NAGs []int
+	// Before is a comment written in front of the move, Comment one after it.
+	Before  string
+	Comment string
+
+	Parent   *Node
+	Children []*Node
+}
+
+// Game is one game of a PGN file.
+type Game struct {
+	Tags []Tag
+	Root *Node
+	// Result is the game termination marker: "1-0", "0-1", "1/2-1/2" or "*".
+	Result string
+}
+
+// NewGame creates an empty game starting from fen, or from the standard
+// starting position if fen is empty.
+func NewGame(fen string) *Game {
+	g := &Game{Root: &Node{}, Result: "*"}
+	if fen != "" && fen != chess.StartFEN {
+		g.SetTag("SetUp", "1")
+		g.SetTag("FEN", fen)
+	}
+	return g
+}
+
+// Tag returns the value of the named tag, or "" if the game has none.
+func (g *Game) Tag(name string) string {
+	for _, t := range g.Tags {
+		if t.Name == name {
+			return t.Value
+		}
+	}
+	return ""
+}
+
+// SetTag sets the value of a tag, adding it if it is not present yet.
+func (g *Game) SetTag(name, value string) {
+	for i, t := range g.Tags {
+		if t.Name == name {
+			g.Tags[i].Value = value
+			return
+		}
+	}
+	g.Tags = append(g.Tags, Tag{Name: name, Value: value})
+}
+
+// StartFEN is the position the game starts from.
+func (g *Game) StartFEN() string {
+	if fen := g.Tag("FEN"); fen != "" {
+		return fen
+	}
+	return chess.StartFEN
+}
+
+// BoardAt replays the game up to and including the move of node n and
+// returns the resulting position. BoardAt(g.Root) is the start position.
+func (g *Game) BoardAt(n *Node) chess.Board {
+	var path []*Node
+	for ; n != nil && n.Parent != nil; n = n.Parent {
+		path = append(path, n)
+	}
+	b := newBoard(g.StartFEN())
+	for i := len(path) - 1; i >= 0; i-- {
+		b.ApplyMove(path[i].Move)
+	}
+	return b
+}
+
+// MainLine returns the nodes of the main line, without the root.
+func (g *Game) MainLine() []*Node {
+	var line []*Node
+	for n := g.Root; len(n.Children) > 0; n = n.Children[0] {
+		line = append(line, n.Children[0])
+	}
+	return line
+}
+
+// AddMove appends a move after n, as the main continuation if n has none
+// yet or as a new variation otherwise, and returns the new node.
+func (n *Node) AddMove(m chess.Move) *Node {
+	child := &Node{Move: m, Parent: n}
+	n.Children = append(n.Children, child)
+	return child
+}
+
+// startPly is the number of half moves played before the start position,
+// taken from the side to move and move number fields of the FEN.
+func startPly(fen string) int {
+	fields := strings.Fields(fen)
+	ply := 0
+	if len(fields) > 5 {
+		if n, err := strconv.Atoi(fields[5]); err == nil && n > 0 {
+			ply = 2 * (n - 1)
+		}
+	}
+	if len(fields) > 1 && fields[1] == "b" {
+		ply++
+	}
+	return ply
+}
+
+// newBoard creates the board games are replayed on. PGN needs every rule,
+// including castling, so it always uses the array board.
+func newBoard(fen string) chess.Board {
+	return chess.NewArrayBoard(fen)
+}
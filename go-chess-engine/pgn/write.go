@@ -0,0 +1,139 @@
+package pgn
+
+import (
+	"bufio"
+	"fmt"
+	"go-chess-engine/chess"
+	"io"
+	"strings"
+)
+
+// lineWidth is the maximum length of a movetext line, as the standard asks.
+const lineWidth = 79
+
+// sevenTagRoster are the tags every exported game starts with, in order.
+var sevenTagRoster = []Tag{
+	{"Event", "?"}, {"Site", "?"}, {"Date", "????.??.??"}, {"Round", "?"},
+	{"White", "?"}, {"Black", "?"}, {"Result", "*"},
+}
+
+// Write writes games to w in PGN export format.
+func Write(w io.Writer, games []*Game) error {
+	bw := bufio.NewWriter(w)
+	for _, g := range games {
+		g.write(bw)
+	}
+	return bw.Flush()
+}
+
+// String returns the game in PGN export format.
+func (g *Game) String() string {
+	var sb strings.Builder
+	g.write(&sb)
+	return sb.String()
+}
+
+func (g *Game) write(w io.Writer) {
+	// The Seven Tag Roster comes first, then the remaining tags in order.
+	for _, t := range sevenTagRoster {
+		value := g.Tag(t.Name)
+		if t.Name == "Result" {
+			value = g.Result
+		}
+		if value == "" {
+			value = t.Value
+		}
+		fmt.Fprintf(w, "[%s \"%s\"]\n", t.Name, escape(value))
+	}
+	for _, t := range g.Tags {
+		if !isRosterTag(t.Name) {
+			fmt.Fprintf(w, "[%s \"%s\"]\n", t.Name, escape(t.Value))
+		}
+	}
+	fmt.Fprintln(w)
+
+	mw := &movetextWriter{w: w}
+	fen := g.StartFEN()
+	mw.moves(g.Root, newBoard(fen), startPly(fen), true)
+	mw.word(g.Result)
+	fmt.Fprint(w, "\n\n")
+}
+
+// movetextWriter writes space separated words, wrapping long lines.
+type movetextWriter struct {
+	w       io.Writer
+	lineLen int
+}
+
+func (mw *movetextWriter) word(s string) {
+	switch {
+	case mw.lineLen == 0:
+	case mw.lineLen+1+len(s) > lineWidth:
+		fmt.Fprint(mw.w, "\n")
+		mw.lineLen = 0
+	default:
+		fmt.Fprint(mw.w, " ")
+		mw.lineLen++
+	}
+	fmt.Fprint(mw.w, s)
+	mw.lineLen += len(s)
+}
+
+// moves writes the line continuing after node n, whose position is b.
+// ply counts half moves from the start of the game; forceNumber makes a
+// black move get its "N..." number, e.g. after a comment or variation.
+func (mw *movetextWriter) moves(n *Node, b chess.Board, ply int, forceNumber bool) {
+	for len(n.Children) > 0 {
+		main := n.Children[0]
+		after := mw.move(main, b, ply, forceNumber)
+		forceNumber = main.Comment != ""
+
+		// Alternatives to the main move follow it, before its continuation.
+		for _, alt := range n.Children[1:] {
+			mw.word("(")
+			altAfter := mw.move(alt, b, ply, true)
+			mw.moves(alt, altAfter, ply+1, alt.Comment != "")
+			mw.word(")")
+			forceNumber = true
+		}
+		n, b = main, after
+		ply++
+	}
+}
+
+// move writes a single move with its number, annotations and comments and
+// returns the position after it.
+func (mw *movetextWriter) move(n *Node, b chess.Board, ply int, forceNumber bool) chess.Board {
+	if n.Before != "" {
+		mw.word("{" + n.Before + "}")
+		forceNumber = true
+	}
+	if ply%2 == 0 {
+		mw.word(fmt.Sprintf("%d.", ply/2+1))
+	} else if forceNumber {
+		mw.word(fmt.Sprintf("%d...", ply/2+1))
+	}
+	mw.word(chess.FormatSAN(b, n.Move))
+	for _, nag := range n.NAGs {
+		mw.word(fmt.Sprintf("$%d", nag))
+	}
+	if n.Comment != "" {
+		mw.word("{" + n.Comment + "}")
+	}
+	after := b.Clone()
+	after.ApplyMove(n.Move)
+	return after
+}
+
+func isRosterTag(name string) bool {
+	for _, t := range sevenTagRoster {
+		if t.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func escape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s)
+}
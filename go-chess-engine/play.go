@@ -0,0 +1,319 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"go-chess-engine/book"
+	"go-chess-engine/chess"
+	"go-chess-engine/config"
+	"go-chess-engine/engine"
+	"go-chess-engine/game"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// playCommand implements "play [flags]": a game against the engine in the
+// terminal, using the configured board and book.
+func playCommand(fs *flag.FlagSet) func(config.Config, []string) int {
+	color := fs.String("color", "white", "the side you play: white or black")
+	fen := fs.String("fen", chess.StartFEN, "start position")
+	depth := fs.Int("depth", engine.DefaultDepth, "engine search depth in plies")
+	moveTime := fs.Duration("time", 0, "engine thinking time per move, e.g. 2s (0 = depth only)")
+	tc := fs.String("tc", "", "time control for both sides as base+increment, e.g. 5m+3s")
+	unicode := fs.Bool("unicode", false, "draw the pieces as chess symbols")
+	return func(cfg config.Config, args []string) int {
+		if len(args) > 0 {
+			fs.Usage()
+			return exitUsage
+		}
+		b, err := chess.NewBoard(cfg.BoardRepresentation, *fen)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitUsage
+		}
+		s := &playSession{
+			game:    game.New(b),
+			engine:  engine.New(),
+			unicode: *unicode,
+			in:      bufio.NewScanner(os.Stdin),
+			out:     os.Stdout,
+		}
+		switch *color {
+		case "white":
+			s.human = chess.White
+		case "black":
+			s.human, s.flipped = chess.Black, true
+		default:
+			fmt.Fprintf(os.Stderr, "bad -color %q: want white or black\n", *color)
+			return exitUsage
+		}
+		if *tc != "" {
+			base, inc, ok := parseTimeControl(*tc)
+			if !ok {
+				fmt.Fprintf(os.Stderr, "bad -tc %q: want base+increment, e.g. 5m+3s\n", *tc)
+				return exitUsage
+			}
+			s.timed, s.clock, s.increment = true, [2]time.Duration{base, base}, inc
+		}
+
+		s.engine.Depth = *depth
+		s.engine.MoveTime = *moveTime
+		if (*moveTime > 0 || s.timed) && !flagSet(fs, "depth") {
+			s.engine.Depth = 64 // Limited by time only
+		}
+		if s.engine.Params, err = evalParams(cfg); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitFailure
+		}
+		if cfg.OwnBook && cfg.BookFile != "" {
+			if s.engine.Book, err = book.Open(cfg.BookFile); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return exitFailure
+			}
+		}
+
+		fmt.Fprintln(s.out, `Enter moves as SAN ("Nf3") or coordinates ("g1f3"); "help" lists the commands.`)
+		if err := s.run(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitFailure
+		}
+		return exitOK
+	}
+}
+
+// parseTimeControl reads "base+increment", e.g. "5m+3s"; the increment is
+// optional.
+func parseTimeControl(s string) (base, inc time.Duration, ok bool) {
+	b, i, hasInc := strings.Cut(s, "+")
+	base, err := time.ParseDuration(b)
+	if err != nil || base <= 0 {
+		return 0, 0, false
+	}
+	if hasInc {
+		if inc, err = time.ParseDuration(i); err != nil || inc < 0 {
+			return 0, 0, false
+		}
+	}
+	return base, inc, true
+}
+
+const playHelp = `Commands:
+  <move>     play a move, e.g. Nf3, exd5, O-O, e7e8q
+  moves      list the legal moves
+  undo       take back the last move; you then play the side to move
+  takeback   take back your last move and the engine's reply
+  flip       turn the board around
+  fen        print the position as FEN
+  quit       leave the game`
+
+// playSession is a game between the user and the engine.
+type playSession struct {
+	game    *game.Game
+	engine  *engine.Engine
+	human   chess.Color
+	flipped bool
+	unicode bool
+
+	// With a time control each side has a clock, which gains the
+	// increment after every move. turnStart is when the side to move
+	// started thinking.
+	timed     bool
+	clock     [2]time.Duration
+	increment time.Duration
+	turnStart time.Time
+	// forfeit is the result once a flag has fallen.
+	forfeit, forfeitReason string
+	// redraw is set when the board has to be shown again.
+	redraw bool
+
+	in  *bufio.Scanner
+	out io.Writer
+}
+
+// run plays until the user quits or the input ends.
+func (s *playSession) run() error {
+	s.turnStart, s.redraw = time.Now(), true
+	for {
+		result, reason := s.outcome()
+		if s.redraw {
+			s.show()
+			if result != "" {
+				fmt.Fprintf(s.out, "Game over: %s (%s). You may undo, take back or quit.\n", result, reason)
+			}
+			s.redraw = false
+		}
+		if result == "" && s.game.Board().SideToMove() != s.human {
+			s.engineMove()
+			continue
+		}
+
+		fmt.Fprint(s.out, "> ")
+		if !s.in.Scan() {
+			fmt.Fprintln(s.out)
+			return s.in.Err()
+		}
+		input := strings.TrimSpace(s.in.Text())
+		switch input {
+		case "":
+		case "quit", "exit":
+			return nil
+		case "help":
+			fmt.Fprintln(s.out, playHelp)
+		case "moves":
+			b := s.game.Board()
+			var moves []string
+			for _, m := range b.GenerateLegalMoves() {
+				moves = append(moves, chess.FormatSAN(b, m))
+			}
+			fmt.Fprintln(s.out, strings.Join(moves, " "))
+		case "fen":
+			fmt.Fprintln(s.out, s.game.Board().ToFEN())
+		case "flip":
+			s.flipped, s.redraw = !s.flipped, true
+		case "undo":
+			if !s.undo() {
+				fmt.Fprintln(s.out, "No move to take back.")
+				break
+			}
+			s.human = s.game.Board().SideToMove()
+		case "takeback":
+			if !s.undo() {
+				fmt.Fprintln(s.out, "No move to take back.")
+				break
+			}
+			for s.game.Board().SideToMove() != s.human && s.undo() {
+			}
+		default:
+			if result != "" {
+				fmt.Fprintln(s.out, "The game is over.")
+				break
+			}
+			m, err := parseUserMove(s.game.Board(), input)
+			if err != nil {
+				fmt.Fprintf(s.out, "%v; \"moves\" lists the legal moves.\n", err)
+				break
+			}
+			s.play(m)
+		}
+	}
+}
+
+// parseUserMove reads a move in coordinate notation or SAN.
+func parseUserMove(b chess.Board, s string) (chess.Move, error) {
+	for _, m := range b.GenerateLegalMoves() {
+		if chess.FormatMove(m) == strings.ToLower(s) {
+			return m, nil
+		}
+	}
+	return chess.ParseSAN(b, s)
+}
+
+// engineMove lets the engine play the side to move, within its clock.
+func (s *playSession) engineMove() {
+	b := s.game.Board()
+	ctx := context.Background()
+	if s.timed {
+		side := b.SideToMove()
+		budget := s.clock[side]/30 + s.increment*3/4
+		if s.engine.MoveTime == 0 || budget < s.engine.MoveTime {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, min(budget, s.clock[side]))
+			defer cancel()
+		}
+	}
+
+	var last engine.Info
+	s.engine.OnInfo = func(info engine.Info) {
+		if info.MultiPV == 1 {
+			last = info
+		}
+	}
+	m := s.engine.FindBestMoveContext(ctx, b.Clone())
+	score := fmt.Sprintf("%+.2f", float64(last.Score)/100)
+	if engine.IsMateScore(last.Score) {
+		score = fmt.Sprintf("mate %d", engine.MateIn(last.Score))
+	}
+	fmt.Fprintf(s.out, "Engine plays %s (%s, depth %d)\n", chess.FormatSAN(b, m), score, last.Depth)
+	s.play(m)
+}
+
+// play plays the legal move m and charges the mover's clock.
+func (s *playSession) play(m chess.Move) {
+	side := s.game.Board().SideToMove()
+	if s.timed {
+		s.clock[side] -= time.Since(s.turnStart)
+		if s.clock[side] <= 0 {
+			s.clock[side] = 0
+			s.forfeit, s.forfeitReason = "1-0", "Black loses on time"
+			if side == chess.White {
+				s.forfeit, s.forfeitReason = "0-1", "White loses on time"
+			}
+			s.redraw = true
+			return
+		}
+		s.clock[side] += s.increment
+	}
+	s.game.Play(m)
+	s.turnStart, s.redraw = time.Now(), true
+}
+
+// undo takes back the last move. The clocks keep their time.
+func (s *playSession) undo() bool {
+	s.forfeit, s.forfeitReason = "", ""
+	s.turnStart, s.redraw = time.Now(), true
+	return s.game.Undo()
+}
+
+// outcome is the result of the game by the rules or on time, or "".
+func (s *playSession) outcome() (string, string) {
+	if s.forfeit != "" {
+		return s.forfeit, s.forfeitReason
+	}
+	return s.game.Outcome()
+}
+
+// show prints the board, the moves so far and the clocks.
+func (s *playSession) show() {
+	b := s.game.Board()
+	fmt.Fprintln(s.out)
+	if s.flipped {
+		fmt.Fprint(s.out, chess.FlippedDiagram(b, s.unicode))
+	} else {
+		fmt.Fprint(s.out, chess.Diagram(b, s.unicode))
+	}
+
+	// Replay the game for the SAN of its moves.
+	var sb strings.Builder
+	replay := s.game.Start().Clone()
+	for i, m := range s.game.Moves() {
+		if replay.SideToMove() == chess.White {
+			fmt.Fprintf(&sb, "%d. ", replay.FullmoveNumber())
+		} else if i == 0 {
+			fmt.Fprintf(&sb, "%d... ", replay.FullmoveNumber())
+		}
+		sb.WriteString(chess.FormatSAN(replay, m) + " ")
+		replay.ApplyMove(m)
+	}
+	if sb.Len() > 0 {
+		fmt.Fprintln(s.out, strings.TrimSpace(sb.String()))
+	}
+
+	side := "White"
+	if b.SideToMove() == chess.Black {
+		side = "Black"
+	}
+	if s.timed {
+		fmt.Fprintf(s.out, "White %s  Black %s  ", formatClock(s.clock[chess.White]), formatClock(s.clock[chess.Black]))
+	}
+	fmt.Fprintf(s.out, "%s to move\n", side)
+}
+
+// formatClock writes a clock as m:ss.
+func formatClock(d time.Duration) string {
+	d = d.Round(time.Second)
+	return fmt.Sprintf("%d:%02d", int(d.Minutes()), int(d.Seconds())%60)
+}
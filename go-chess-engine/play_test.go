@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"go-chess-engine/chess"
+	"go-chess-engine/engine"
+	"go-chess-engine/game"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseTimeControl(t *testing.T) {
+	tests := []struct {
+		s         string
+		base, inc time.Duration
+		ok        bool
+	}{
+		{"5m+3s", 5 * time.Minute, 3 * time.Second, true},
+		{"5m", 5 * time.Minute, 0, true},
+		{"90s+0s", 90 * time.Second, 0, true},
+		{"1m30s+500ms", 90 * time.Second, 500 * time.Millisecond, true},
+		{"0+1s", 0, 0, false},
+		{"-1s", 0, 0, false},
+		{"5m+-1s", 0, 0, false},
+		{"5m+", 0, 0, false},
+		{"5", 0, 0, false},
+		{"", 0, 0, false},
+	}
+	for _, tt := range tests {
+		base, inc, ok := parseTimeControl(tt.s)
+		if base != tt.base || inc != tt.inc || ok != tt.ok {
+			t.Errorf("parseTimeControl(%q) = %v, %v, %v, want %v, %v, %v", tt.s, base, inc, ok, tt.base, tt.inc, tt.ok)
+		}
+	}
+}
+
+// playScript runs a session from fen with the user on side human, typing
+// the lines of script, and returns the output.
+func playScript(t *testing.T, fen string, human chess.Color, script string) string {
+	t.Helper()
+	b, err := chess.NewBoard("array", fen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := engine.New()
+	e.Depth = 2 // Enough to see a mate in one
+	var out bytes.Buffer
+	s := &playSession{
+		game:   game.New(b),
+		engine: e,
+		human:  human,
+		in:     bufio.NewScanner(strings.NewReader(script)),
+		out:    &out,
+	}
+	if err := s.run(); err != nil {
+		t.Fatal(err)
+	}
+	return out.String()
+}
+
+// fens returns the FENs the "fen" command printed.
+func fens(out string) []string {
+	var fens []string
+	for _, line := range strings.Split(out, "\n") {
+		line = strings.TrimPrefix(line, "> ")
+		if strings.Count(line, "/") == 7 && len(strings.Fields(line)) == 6 {
+			fens = append(fens, line)
+		}
+	}
+	return fens
+}
+
+func TestPlayUndo(t *testing.T) {
+	out := playScript(t, chess.StartFEN, chess.White, `undo
+e4
+takeback
+fen
+d4
+undo
+fen
+e5
+undo
+fen
+quit
+`)
+	if !strings.Contains(out, "No move to take back.") {
+		t.Errorf("undo at the start took something back:\n%s", out)
+	}
+	want := []string{
+		// takeback undoes the engine's reply and the move before it.
+		chess.StartFEN,
+		// undo takes back the reply only, and the user plays Black.
+		"rnbqkbnr/pppppppp/8/8/3P4/8/PPP1PPPP/RNBQKBNR b KQkq d3 0 1",
+		// Now undo leaves White, the engine's side, to the user.
+		"rnbqkbnr/pppp1ppp/8/4p3/3P4/8/PPP1PPPP/RNBQKBNR w KQkq e6 0 2",
+	}
+	if got := fens(out); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("positions\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if n := strings.Count(out, "Engine plays "); n != 3 {
+		t.Errorf("the engine played %d moves, want 3:\n%s", n, out)
+	}
+}
+
+func TestPlayGameOver(t *testing.T) {
+	const fen = "6k1/5ppp/8/8/8/8/8/R5K1 w - - 0 1"
+	out := playScript(t, fen, chess.White, `nonsense
+Ra8
+Kf1
+undo
+fen
+quit
+`)
+	for _, want := range []string{
+		`"moves" lists the legal moves.`,
+		"Game over: 1-0 (White mates). You may undo, take back or quit.",
+		"The game is over.",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("no %q in:\n%s", want, out)
+		}
+	}
+	if got := fens(out); len(got) != 1 || got[0] != fen {
+		t.Errorf("after undo: %q, want %s", got, fen)
+	}
+
+	// The engine mates the user, who takes the mate back and is mated again.
+	out = playScript(t, fen, chess.Black, "takeback\nquit\n")
+	if n := strings.Count(out, "Game over: 1-0 (White mates)."); n != 2 {
+		t.Errorf("%d mates, want 2:\n%s", n, out)
+	}
+	if strings.Contains(out, "No move to take back.") {
+		t.Errorf("takeback found no move:\n%s", out)
+	}
+}
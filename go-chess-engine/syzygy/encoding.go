@@ -0,0 +1,130 @@
+package syzygy
+
+// Lookup tables used to turn a position into a table index. They follow the
+// encoding of the Syzygy generator and are filled in by init.
+var (
+	// mapB1H1H7 numbers the 28 squares below the a1-h8 diagonal.
+	mapB1H1H7 [64]int
+	// mapA1D1D4 numbers the a1-d1-d4 triangle, diagonal squares last.
+	mapA1D1D4 [64]int
+	// mapKK numbers the 462 legal king pairs with the first king in the triangle.
+	mapKK [10][64]int
+	// binomial[k][n] is the number of ways to choose k squares out of n.
+	binomial [maxPieces][64]int
+	// mapPawns numbers the squares a2-h7 so that the leading pawn, the one
+	// nearest an edge and lowest in rank, has the highest value.
+	mapPawns      [64]int
+	leadPawnIdx   [6][64]int
+	leadPawnsSize [6][4]int
+)
+
+func init() {
+	code := 0
+	for sq := 0; sq < 64; sq++ {
+		if offA1H8(sq) < 0 {
+			mapB1H1H7[sq] = code
+			code++
+		}
+	}
+
+	var diagonal []int
+	code = 0
+	for sq := 0; sq <= 27; sq++ { // a1..d4
+		if offA1H8(sq) < 0 && sq&7 <= 3 {
+			mapA1D1D4[sq] = code
+			code++
+		} else if offA1H8(sq) == 0 && sq&7 <= 3 {
+			diagonal = append(diagonal, sq)
+		}
+	}
+	for _, sq := range diagonal {
+		mapA1D1D4[sq] = code
+		code++
+	}
+
+	type pair struct{ idx, sq int }
+	var bothOnDiagonal []pair
+	code = 0
+	for idx := 0; idx < 10; idx++ {
+		for s1 := 0; s1 <= 27; s1++ {
+			// Squares outside the triangle are also 0, so b1 (0) is checked explicitly.
+			if mapA1D1D4[s1] != idx || (idx == 0 && s1 != 1) {
+				continue
+			}
+			for s2 := 0; s2 < 64; s2++ {
+				switch {
+				case squareDistance(s1, s2) <= 1:
+					continue // Kings touching or on the same square
+				case offA1H8(s1) == 0 && offA1H8(s2) > 0:
+					continue // First on the diagonal, second above it
+				case offA1H8(s1) == 0 && offA1H8(s2) == 0:
+					bothOnDiagonal = append(bothOnDiagonal, pair{idx, s2})
+				default:
+					mapKK[idx][s2] = code
+					code++
+				}
+			}
+		}
+	}
+	for _, p := range bothOnDiagonal {
+		mapKK[p.idx][p.sq] = code
+		code++
+	}
+
+	binomial[0][0] = 1
+	for n := 1; n < 64; n++ {
+		for k := 0; k < maxPieces && k <= n; k++ {
+			if k > 0 {
+				binomial[k][n] += binomial[k-1][n-1]
+			}
+			if k < n {
+				binomial[k][n] += binomial[k][n-1]
+			}
+		}
+	}
+
+	available := 47 // Squares left for the other pawns when the leader is on a2
+	for cnt := 1; cnt <= 5; cnt++ {
+		for file := 0; file < 4; file++ {
+			idx := 0
+			for rank := 1; rank <= 6; rank++ {
+				sq := rank*8 + file
+				if cnt == 1 {
+					mapPawns[sq] = available
+					available--
+					mapPawns[sq^7] = available
+					available--
+				}
+				leadPawnIdx[cnt][sq] = idx
+				idx += binomial[cnt-1][mapPawns[sq]]
+			}
+			leadPawnsSize[cnt][file] = idx
+		}
+	}
+}
+
+// offA1H8 is positive above the a1-h8 diagonal, negative below it.
+func offA1H8(sq int) int {
+	return sq>>3 - sq&7
+}
+
+func edgeDistance(file int) int {
+	if file > 7-file {
+		return 7 - file
+	}
+	return file
+}
+
+func squareDistance(a, b int) int {
+	df, dr := a&7-b&7, a>>3-b>>3
+	if df < 0 {
+		df = -df
+	}
+	if dr < 0 {
+		dr = -dr
+	}
+	if df > dr {
+		return df
+	}
+	return dr
+}
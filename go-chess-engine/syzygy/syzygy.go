@@ -0,0 +1,349 @@
+// Package syzygy probes Syzygy endgame tablebases (.rtbw and .rtbz files)
+// in pure Go.
+//
+// WDL tables give the game theoretical result of a position, DTZ tables the
+// distance to the next capture or pawn move (the "zeroing" move that resets
+// the 50-move counter) on an optimal path.
+package syzygy
+
+import (
+	"fmt"
+	"go-chess-engine/chess"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Results returned by ProbeWDL, from the side to move's point of view.
+const (
+	Loss        = -2
+	BlessedLoss = -1 // Lost, but saved by the 50-move rule
+	Draw        = 0
+	CursedWin   = 1 // Won, but drawn by the 50-move rule
+	Win         = 2
+)
+
+type probeState int
+
+const (
+	probeOK         probeState = iota
+	probeFail                  // Table missing or unreadable
+	changeSTM                  // DTZ stored for the other side to move
+	zeroingBestMove            // Best move is a capture or pawn move
+)
+
+// Tables is a set of tablebase files found in one or more directories.
+type Tables struct {
+	wdl       map[string]*table
+	dtz       map[string]*table
+	maxPieces int
+}
+
+// Open scans the directories in path, separated like PATH entries, for
+// Syzygy files. The files themselves are only read when first probed.
+func Open(path string) (*Tables, error) {
+	t := &Tables{wdl: map[string]*table{}, dtz: map[string]*table{}}
+	for _, dir := range filepath.SplitList(path) {
+		if dir == "" {
+			continue
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			name := entry.Name()
+			ext := filepath.Ext(name)
+			var typ tableType
+			var tables map[string]*table
+			switch ext {
+			case ".rtbw":
+				typ, tables = wdlTable, t.wdl
+			case ".rtbz":
+				typ, tables = dtzTable, t.dtz
+			default:
+				continue
+			}
+			tb, err := newTable(typ, filepath.Join(dir, name), strings.TrimSuffix(name, ext))
+			if err != nil {
+				continue // Not a table we understand, e.g. a 7-man file with suffix
+			}
+			tables[tb.key] = tb
+			tables[tb.key2] = tb
+			if typ == wdlTable && tb.pieceCount > t.maxPieces {
+				t.maxPieces = tb.pieceCount
+			}
+		}
+	}
+	if len(t.wdl) == 0 {
+		return nil, fmt.Errorf("syzygy: no tables found in %q", path)
+	}
+	return t, nil
+}
+
+// MaxPieces is the largest number of pieces (kings included) of any WDL table.
+func (t *Tables) MaxPieces() int {
+	return t.maxPieces
+}
+
+// ProbeWDL returns the result of the position with best play, one of Loss,
+// BlessedLoss, Draw, CursedWin or Win. ok is false if the position is not
+// covered by the tables.
+func (t *Tables) ProbeWDL(b chess.Board) (wdl int, ok bool) {
+	if !t.covers(b) {
+		return 0, false
+	}
+	state := probeOK
+	wdl = t.search(b, false, &state)
+	return wdl, state != probeFail
+}
+
+// ProbeDTZ returns the distance to zeroing in plies: positive when the side
+// to move wins, negative when it loses and 0 for draws. Values beyond 100
+// mean the result is spoilt by the 50-move rule.
+func (t *Tables) ProbeDTZ(b chess.Board) (dtz int, ok bool) {
+	if !t.covers(b) {
+		return 0, false
+	}
+	state := probeOK
+	dtz = t.probeDTZ(b, &state)
+	return dtz, state != probeFail
+}
+
+// covers reports whether b could be in the tables: few enough pieces and
+// no castling rights, which the tables do not encode.
+func (t *Tables) covers(b chess.Board) bool {
+	n := 0
+	for sq := 0; sq < 64; sq++ {
+		if b.PieceAt(sq) != chess.Empty {
+			n++
+		}
+	}
+	return n <= t.maxPieces && b.CastlingRights() == 0
+}
+
+// search resolves captures (and, with checkZeroing, pawn moves) before
+// trusting the table. The generator stores "don't care" values where the
+// best move is a winning capture, so the table alone can be wrong there.
+func (t *Tables) search(b chess.Board, checkZeroing bool, state *probeState) int {
+	bestValue := Loss
+	moves := b.GenerateLegalMoves()
+	moveCount := 0
+
+	for _, m := range moves {
+		if !isCapture(b, m) && (!checkZeroing || !isPawn(b.PieceAt(m.From))) {
+			continue
+		}
+		moveCount++
+		child := b.Clone()
+		child.ApplyMove(m)
+		value := -t.search(child, false, state)
+		if *state == probeFail {
+			return Draw
+		}
+		if value > bestValue {
+			bestValue = value
+			if value >= Win {
+				*state = zeroingBestMove // Winning zeroing move
+				return value
+			}
+		}
+	}
+
+	// If every legal move was searched the table is not needed, and it could
+	// even be wrong (e.g. positions with only capture moves).
+	noMoreMoves := moveCount > 0 && moveCount == len(moves)
+	var value int
+	if noMoreMoves {
+		value = bestValue
+	} else {
+		value = t.probeTable(b, wdlTable, Draw, state)
+		if *state == probeFail {
+			return Draw
+		}
+	}
+
+	if bestValue >= value {
+		if bestValue > Draw || noMoreMoves {
+			*state = zeroingBestMove
+		} else {
+			*state = probeOK
+		}
+		return bestValue
+	}
+	*state = probeOK
+	return value
+}
+
+func (t *Tables) probeDTZ(b chess.Board, state *probeState) int {
+	*state = probeOK
+	wdl := t.search(b, true, state)
+	if *state == probeFail || wdl == Draw { // DTZ tables don't store draws
+		return 0
+	}
+	// The table holds a "don't care" value when a zeroing move is best.
+	if *state == zeroingBestMove {
+		return dtzBeforeZeroing(wdl)
+	}
+
+	dtz := t.probeTable(b, dtzTable, wdl, state)
+	if *state == probeFail {
+		return 0
+	}
+	if *state != changeSTM {
+		if wdl == BlessedLoss || wdl == CursedWin {
+			dtz += 100
+		}
+		return dtz * sign(wdl)
+	}
+
+	// The table stores the other side to move, so do a 1-ply search and take
+	// the winning move with the smallest DTZ (or the longest loss).
+	minDTZ := 0xFFFF
+	for _, m := range b.GenerateLegalMoves() {
+		zeroing := b.PieceAt(m.To) != chess.Empty || isPawn(b.PieceAt(m.From))
+		child := b.Clone()
+		child.ApplyMove(m)
+
+		// For zeroing moves we want the DTZ before the move, otherwise we would
+		// get the one of the next sequence; the search only gives the sign.
+		var dtz int
+		if zeroing {
+			dtz = -dtzBeforeZeroing(t.search(child, false, state))
+		} else {
+			dtz = -t.probeDTZ(child, state)
+		}
+		if dtz == 1 && child.IsCheckmate() {
+			minDTZ = 1
+		}
+		if !zeroing {
+			dtz += sign(dtz)
+		}
+		if dtz < minDTZ && sign(dtz) == sign(wdl) {
+			minDTZ = dtz
+		}
+		if *state == probeFail {
+			return 0
+		}
+	}
+	// No legal moves: we are mated.
+	if minDTZ == 0xFFFF {
+		return -1
+	}
+	return minDTZ
+}
+
+// probeTable looks the position up in the WDL or DTZ table for its material.
+func (t *Tables) probeTable(b chess.Board, typ tableType, wdl int, state *probeState) int {
+	var pieces [64]int
+	var counts [15]int
+	count := 0
+	for sq := 0; sq < 64; sq++ {
+		p := b.PieceAt(sq)
+		if p == chess.Empty {
+			continue
+		}
+		pieces[sq] = pieceCode(p)
+		counts[pieces[sq]]++
+		count++
+	}
+	if count == 2 { // KvK
+		return Draw
+	}
+	white, black := "", ""
+	for _, c := range pieceLetters {
+		white += strings.Repeat(string(c), counts[pieceCode(pieceFromLetter(c, chess.White))])
+		black += strings.Repeat(string(c), counts[pieceCode(pieceFromLetter(c, chess.Black))])
+	}
+	material := white + "v" + black
+
+	tables := t.wdl
+	if typ == dtzTable {
+		tables = t.dtz
+	}
+	tb := tables[material]
+	if tb == nil || tb.load() != nil {
+		*state = probeFail
+		return 0
+	}
+	stm := 0
+	if b.SideToMove() == chess.Black {
+		stm = 1
+	}
+	return tb.probe(&pieces, stm, material, wdl, state)
+}
+
+// pieceCode converts a piece to the numbering used inside the tables:
+// white pawn..king are 1-6 and black pawn..king 9-14.
+func pieceCode(p chess.Piece) int {
+	if p.Color() == chess.Black {
+		return int(p-chess.BlackPawn) + 9
+	}
+	return int(p)
+}
+
+// pieceLetters is the order in which pieces appear in table names.
+const pieceLetters = "KQRBNP"
+
+func pieceFromLetter(c rune, color chess.Color) chess.Piece {
+	p := chess.Piece(strings.IndexRune("PNBRQK", c) + 1)
+	if color == chess.Black {
+		p += chess.BlackPawn - chess.WhitePawn
+	}
+	return p
+}
+
+// isCapture reports whether m captures, en passant included.
+func isCapture(b chess.Board, m chess.Move) bool {
+	return b.PieceAt(m.To) != chess.Empty || (isPawn(b.PieceAt(m.From)) && m.From%8 != m.To%8)
+}
+
+func isPawn(p chess.Piece) bool {
+	return p == chess.WhitePawn || p == chess.BlackPawn
+}
+
+// dtzBeforeZeroing is the DTZ of a position whose best move zeroes the counter.
+func dtzBeforeZeroing(wdl int) int {
+	switch wdl {
+	case Win:
+		return 1
+	case CursedWin:
+		return 101
+	case BlessedLoss:
+		return -101
+	case Loss:
+		return -1
+	}
+	return 0
+}
+
+func sign(x int) int {
+	switch {
+	case x > 0:
+		return 1
+	case x < 0:
+		return -1
+	}
+	return 0
+}
+
+// splitName splits a table name like "KRPvKR" into the two sides, with the
+// pieces of each side in pieceLetters order. Both need exactly one king.
+func splitName(name string) (white, black string, ok bool) {
+	white, black, found := strings.Cut(name, "v")
+	if !found {
+		return "", "", false
+	}
+	sides := [2]string{white, black}
+	for i, side := range sides {
+		if strings.Count(side, "K") != 1 || strings.Trim(side, pieceLetters) != "" {
+			return "", "", false
+		}
+		sorted := ""
+		for _, c := range pieceLetters {
+			sorted += strings.Repeat(string(c), strings.Count(side, string(c)))
+		}
+		sides[i] = sorted
+	}
+	return sides[0], sides[1], true
+}
@@ -0,0 +1,270 @@
+package syzygy
+
+import (
+	"bytes"
+	"go-chess-engine/chess"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// The tables in testdata are written by testdata/gen.go.
+
+func openTestdata(t *testing.T) *Tables {
+	t.Helper()
+	tables, err := Open("testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tables
+}
+
+func TestOpen(t *testing.T) {
+	if got := openTestdata(t).MaxPieces(); got != 4 {
+		t.Errorf("MaxPieces = %d, want 4", got)
+	}
+	if _, err := Open(t.TempDir()); err == nil {
+		t.Error("Open accepted a directory without tables")
+	}
+}
+
+func TestProbe(t *testing.T) {
+	tests := []struct {
+		fen      string
+		wdl, dtz int
+	}{
+		// Mate counts as a zeroing move.
+		{"4k3/8/4K3/8/8/8/8/Q7 w - - 0 1", Win, 1},
+		{"k7/1Q6/1K6/8/8/8/8/8 b - - 0 1", Loss, -1},
+		{"k7/8/1Q6/8/8/8/8/K7 b - - 0 1", Draw, 0}, // Stalemate
+		{"8/8/8/8/8/8/1k6/Q6K b - - 0 1", Draw, 0}, // Kxa1
+		// The same with Black the stronger side.
+		{"4K3/8/4k3/8/8/8/8/q7 b - - 0 1", Win, 1},
+		{"K7/1q6/1k6/8/8/8/8/8 w - - 0 1", Loss, -1},
+		// KRvK stores Black to move only: White's values take a search.
+		{"4k3/8/4K3/8/8/8/8/R7 w - - 0 1", Win, 1},
+		{"R3k3/8/4K3/8/8/8/8/8 b - - 0 1", Loss, -1},
+		{"8/8/8/3k4/8/8/8/KN6 w - - 0 1", Draw, 0},
+		{"8/8/8/3k4/8/8/8/KB6 b - - 0 1", Draw, 0},
+		// Pawn moves and promotions zero the counter.
+		{"8/4P3/8/8/8/8/k7/4K3 w - - 0 1", Win, 1},
+		{"8/8/8/8/8/8/4P3/k3K3 w - - 0 1", Win, 1},
+		{"8/8/8/8/8/8/4Pk2/7K b - - 0 1", Draw, 0}, // Kxe2
+		{"k7/8/8/8/8/8/P7/K7 w - - 0 1", Draw, 0},  // Rook pawn
+		// Four pieces: captures lead to the three-piece tables.
+		{"b3k3/8/8/8/8/8/8/R3K3 w - - 0 1", Win, 1},   // Rxa8
+		{"4k3/8/8/8/8/5b2/8/3RK3 b - - 0 1", Draw, 0}, // Bxd1
+		{"R3k3/8/4K3/8/8/8/8/1b6 b - - 0 1", Loss, -1},
+	}
+	tables := openTestdata(t)
+	for _, rep := range []string{"array", "bitboard"} {
+		for _, tt := range tests {
+			b, err := chess.NewBoard(rep, tt.fen)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if wdl, ok := tables.ProbeWDL(b); !ok || wdl != tt.wdl {
+				t.Errorf("%s: ProbeWDL(%s) = %d, %v, want %d", rep, tt.fen, wdl, ok, tt.wdl)
+			}
+			if dtz, ok := tables.ProbeDTZ(b); !ok || dtz != tt.dtz {
+				t.Errorf("%s: ProbeDTZ(%s) = %d, %v, want %d", rep, tt.fen, dtz, ok, tt.dtz)
+			}
+		}
+	}
+
+	b := chess.NewArrayBoard("r3k3/8/8/8/8/8/8/4K3 w q - 0 1")
+	if _, ok := tables.ProbeWDL(b); ok {
+		t.Error("ProbeWDL covered a position with castling rights")
+	}
+	b = chess.NewArrayBoard("4k3/8/8/8/8/8/8/RN2K3 w - - 0 1")
+	if _, ok := tables.ProbeWDL(b); ok {
+		t.Error("ProbeWDL covered a position without a table")
+	}
+}
+
+// TestMagic checks the file headers against the published ones and that
+// a WDL table saved as a DTZ one, or the other way round, is refused.
+func TestMagic(t *testing.T) {
+	magics := map[string][]byte{
+		".rtbw": {0x71, 0xE8, 0x23, 0x5D},
+		".rtbz": {0xD7, 0x66, 0x0C, 0xA5},
+	}
+	dir := t.TempDir()
+	for ext, magic := range magics {
+		data, err := os.ReadFile("testdata/KQvK" + ext)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.HasPrefix(data, magic) {
+			t.Errorf("KQvK%s starts with % x, want % x", ext, data[:4], magic)
+		}
+		other := map[string]string{".rtbw": ".rtbz", ".rtbz": ".rtbw"}[ext]
+		if err := os.WriteFile(filepath.Join(dir, "KQvK"+other), data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tables, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := chess.NewArrayBoard("4k3/8/8/8/8/8/8/Q3K3 b - - 0 1")
+	if _, ok := tables.ProbeWDL(b); ok {
+		t.Error("ProbeWDL read a DTZ table")
+	}
+	if _, ok := tables.ProbeDTZ(b); ok {
+		t.Error("ProbeDTZ read a WDL table")
+	}
+}
+
+// TestKnownAnswers probes KQvK and KRvK positions whose results are known.
+// Besides the generated tables it probes the official ones in
+// $SYZYGY_TEST_PATH, if set, which need at least KQvK and KRvK.
+func TestKnownAnswers(t *testing.T) {
+	tests := []struct {
+		fen string
+		wdl int
+	}{
+		{"4k3/8/8/8/8/8/8/Q3K3 w - - 0 1", Win},
+		{"4k3/8/8/8/8/8/8/Q3K3 b - - 0 1", Loss},
+		{"8/8/3k4/8/8/2q5/8/6K1 w - - 0 1", Loss},
+		{"1k6/8/8/8/8/8/8/R3K3 w - - 0 1", Win},
+		{"8/8/8/8/8/8/8/R3K2k b - - 0 1", Loss},
+		{"8/8/8/8/8/8/1k6/R6K b - - 0 1", Draw}, // Kxa1
+		{"7k/8/6K1/8/8/8/8/1Q6 w - - 0 1", Win},
+		{"7k/5Q2/6K1/8/8/8/8/8 b - - 0 1", Draw}, // Stalemate
+	}
+	dirs := []string{"testdata"}
+	if dir := os.Getenv("SYZYGY_TEST_PATH"); dir != "" {
+		dirs = append(dirs, dir)
+	}
+	for _, dir := range dirs {
+		tables, err := Open(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, tt := range tests {
+			b := chess.NewBitboard(tt.fen)
+			wdl, ok := tables.ProbeWDL(b)
+			if !ok || wdl != tt.wdl {
+				t.Errorf("%s: ProbeWDL(%s) = %d, %v, want %d", dir, tt.fen, wdl, ok, tt.wdl)
+			}
+			if dtz, ok := tables.ProbeDTZ(b); !ok || sign(dtz) != sign(tt.wdl) {
+				t.Errorf("%s: ProbeDTZ(%s) = %d, %v, want the sign of %d", dir, tt.fen, dtz, ok, tt.wdl)
+			}
+		}
+	}
+}
+
+// TestProbeConsistent checks a sample of every table against a one-ply
+// search: the result is the best result of the moves, and the distance to
+// zeroing is one more than that of the best quiet move.
+func TestProbeConsistent(t *testing.T) {
+	endings := []struct {
+		pieces []chess.Piece
+		step   int
+	}{
+		{[]chess.Piece{chess.WhiteKing, chess.WhiteQueen, chess.BlackKing}, 401},
+		{[]chess.Piece{chess.WhiteKing, chess.WhiteRook, chess.BlackKing}, 401},
+		{[]chess.Piece{chess.WhiteKing, chess.WhiteBishop, chess.BlackKing}, 1601},
+		{[]chess.Piece{chess.WhiteKing, chess.WhiteKnight, chess.BlackKing}, 1601},
+		{[]chess.Piece{chess.WhiteKing, chess.WhitePawn, chess.BlackKing}, 401},
+		{[]chess.Piece{chess.WhiteKing, chess.WhiteRook, chess.BlackKing, chess.BlackBishop}, 25013},
+	}
+	tables := openTestdata(t)
+	for _, ending := range endings {
+		n := len(ending.pieces)
+		for i := 0; i < 1<<(6*n); i += ending.step {
+			for _, stm := range []chess.Color{chess.White, chess.Black} {
+				p := chess.Position{SideToMove: stm, EnPassant: chess.NoSquare, FullmoveNumber: 1}
+				for k, piece := range ending.pieces {
+					sq := i >> (6 * k) & 63
+					if p.Pieces[sq] != chess.Empty || (piece == chess.WhitePawn && (sq < 8 || sq >= 56)) {
+						p.SideToMove = -1
+						break
+					}
+					p.Pieces[sq] = piece
+				}
+				if p.SideToMove < 0 || !legal(p) {
+					continue
+				}
+				b := chess.NewBitboardFromPosition(p)
+				if msg := checkProbe(tables, b); msg != "" {
+					t.Fatalf("%s: %s", b.ToFEN(), msg)
+				}
+				mirror := chess.NewBitboardFromPosition(mirrored(p))
+				wdl, _ := tables.ProbeWDL(b)
+				dtz, _ := tables.ProbeDTZ(b)
+				mwdl, _ := tables.ProbeWDL(mirror)
+				mdtz, _ := tables.ProbeDTZ(mirror)
+				if mwdl != wdl || mdtz != dtz {
+					t.Fatalf("%s: %d, %d but %d, %d with the colours swapped", b.ToFEN(), wdl, dtz, mwdl, mdtz)
+				}
+			}
+		}
+	}
+}
+
+// legal reports whether the side that is not to move is out of check.
+func legal(p chess.Position) bool {
+	p.SideToMove ^= 1
+	return !chess.NewBitboardFromPosition(p).InCheck()
+}
+
+// mirrored swaps the colours and flips the board.
+func mirrored(p chess.Position) chess.Position {
+	m := p
+	for sq, piece := range p.Pieces {
+		if piece != chess.Empty {
+			piece += (chess.BlackPawn - chess.WhitePawn) * chess.Piece(1-2*int(piece.Color()))
+		}
+		m.Pieces[sq^56] = piece
+	}
+	m.SideToMove ^= 1
+	return m
+}
+
+// checkProbe compares the values of b with those of its moves. It returns
+// what is wrong, or "".
+func checkProbe(tables *Tables, b chess.Board) string {
+	wdl, ok1 := tables.ProbeWDL(b)
+	dtz, ok2 := tables.ProbeDTZ(b)
+	if !ok1 || !ok2 || sign(dtz) != sign(wdl) {
+		return "bad probe"
+	}
+	moves := b.GenerateLegalMoves()
+	if len(moves) == 0 {
+		if b.InCheck() && (wdl != Loss || dtz != -1) || !b.InCheck() && wdl != Draw {
+			return "wrong value without moves"
+		}
+		return ""
+	}
+
+	bestWDL := Loss
+	// The best distance among the moves keeping the result: the shortest
+	// win or the longest loss.
+	bestDTZ := 0
+	for _, m := range moves {
+		child := b.Clone()
+		child.ApplyMove(m)
+		childWDL, _ := tables.ProbeWDL(child)
+		bestWDL = max(bestWDL, -childWDL)
+		if -childWDL != wdl || wdl == Draw {
+			continue
+		}
+		d := 1 // A capture, pawn move or mate
+		if !isCapture(b, m) && !isPawn(b.PieceAt(m.From)) && !child.IsCheckmate() {
+			childDTZ, _ := tables.ProbeDTZ(child)
+			d = max(childDTZ, -childDTZ) + 1
+		}
+		if bestDTZ == 0 || (wdl == Win && d < bestDTZ) || (wdl == Loss && d > bestDTZ) {
+			bestDTZ = d
+		}
+	}
+	if bestWDL != wdl {
+		return "result differs from the best move's"
+	}
+	if max(dtz, -dtz) != bestDTZ {
+		return "distance differs from the best move's"
+	}
+	return ""
+}
@@ -0,0 +1,673 @@
+package syzygy
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+)
+
+// maxPieces is the largest number of pieces a Syzygy table can hold.
+const maxPieces = 7
+
+type tableType int
+
+const (
+	wdlTable tableType = iota
+	dtzTable
+)
+
+// magics are the first bytes of every .rtbw and .rtbz file.
+var magics = [2][4]byte{
+	wdlTable: {0x71, 0xE8, 0x23, 0x5D},
+	dtzTable: {0xD7, 0x66, 0x0C, 0xA5},
+}
+
+// Flags stored in front of every pairsData block.
+const (
+	flagSTM         = 1
+	flagMapped      = 2
+	flagWinPlies    = 4
+	flagLossPlies   = 8
+	flagWide        = 16
+	flagSingleValue = 128
+)
+
+// pairsData describes one sub-table: for a given side to move and, in pawn
+// tables, a given file of the leading pawn. Offsets point into table.data.
+type pairsData struct {
+	flags           byte
+	pieces          [maxPieces]int // piece codes in encoding order
+	groupLen        [maxPieces + 1]int
+	groupIdx        [maxPieces + 1]uint64
+	sizeofBlock     uint64
+	span            uint64
+	sparseIndex     int
+	sparseIndexSize uint64
+	blockLength     int
+	blockLengthSize uint64
+	blocksNum       uint64
+	data            int
+	maxSymLen       int
+	minSymLen       int
+	lowestSym       int
+	base64          []uint64
+	symlen          []uint8
+	btree           int
+	mapIdx          [4]int // DTZ only: start of each value map in table.dtzMap
+}
+
+// table is a single .rtbw or .rtbz file. It is read from disk the first
+// time it is probed.
+type table struct {
+	typ             tableType
+	path            string
+	key, key2       string // material of the stronger side first, and swapped
+	pieceCount      int
+	hasPawns        bool
+	hasUniquePieces bool
+	pawnCount       [2]int // leading colour first
+
+	once   sync.Once
+	err    error
+	data   []byte
+	items  [2][4]pairsData
+	dtzMap int
+}
+
+// newTable describes the table stored at path for the material in name,
+// e.g. "KRPvKR", without reading the file yet.
+func newTable(typ tableType, path, name string) (*table, error) {
+	white, black, ok := splitName(name)
+	if !ok {
+		return nil, fmt.Errorf("syzygy: bad table name %q", name)
+	}
+	t := &table{
+		typ:        typ,
+		path:       path,
+		key:        white + "v" + black,
+		key2:       black + "v" + white,
+		pieceCount: len(white) + len(black),
+	}
+	if t.pieceCount > maxPieces {
+		return nil, fmt.Errorf("syzygy: table %q has too many pieces", name)
+	}
+
+	wp, bp := countByte(white, 'P'), countByte(black, 'P')
+	t.hasPawns = wp+bp > 0
+	// The leading colour is the one with fewer pawns, which compresses better.
+	if bp == 0 || (wp > 0 && bp >= wp) {
+		t.pawnCount = [2]int{wp, bp}
+	} else {
+		t.pawnCount = [2]int{bp, wp}
+	}
+	for _, side := range []string{white, black} {
+		for _, c := range "QRBNP" {
+			if countByte(side, byte(c)) == 1 {
+				t.hasUniquePieces = true
+			}
+		}
+	}
+	return t, nil
+}
+
+// load reads and parses the table file once.
+func (t *table) load() error {
+	t.once.Do(func() {
+		data, err := os.ReadFile(t.path)
+		if err != nil {
+			t.err = err
+			return
+		}
+		t.err = t.parse(data)
+		if t.err == nil {
+			t.data = data
+		}
+	})
+	return t.err
+}
+
+// get returns the sub-table for the side to move and leading pawn file.
+func (t *table) get(stm, file int) *pairsData {
+	if t.typ == dtzTable {
+		stm = 0 // DTZ tables only store one side to move
+	}
+	if !t.hasPawns {
+		file = 0
+	}
+	return &t.items[stm][file]
+}
+
+// parse reads the header of a table file and sets up every sub-table.
+// A corrupt file makes the slice accesses panic, which is turned into an error.
+func (t *table) parse(data []byte) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("syzygy: corrupt table %s: %v", t.path, r)
+		}
+	}()
+
+	if len(data) < 5 || [4]byte(data[:4]) != magics[t.typ] {
+		return fmt.Errorf("syzygy: %s is not a %s table", t.path, [...]string{"WDL", "DTZ"}[t.typ])
+	}
+	pos := 4
+	if (data[pos]&2 != 0) != t.hasPawns {
+		return fmt.Errorf("syzygy: %s has unexpected pawn flag", t.path)
+	}
+	pos++
+
+	sides := 1
+	if t.typ == wdlTable && t.key != t.key2 {
+		sides = 2
+	}
+	maxFile := 0
+	if t.hasPawns {
+		maxFile = 3
+	}
+	pp := t.hasPawns && t.pawnCount[1] > 0 // Pawns on both sides
+
+	for f := 0; f <= maxFile; f++ {
+		order := [2][2]int{{int(data[pos] & 0xF), 0xF}, {int(data[pos] >> 4), 0xF}}
+		if pp {
+			order[0][1] = int(data[pos+1] & 0xF)
+			order[1][1] = int(data[pos+1] >> 4)
+			pos++
+		}
+		pos++
+		for k := 0; k < t.pieceCount; k++ {
+			for i := 0; i < sides; i++ {
+				if i == 0 {
+					t.items[i][f].pieces[k] = int(data[pos] & 0xF)
+				} else {
+					t.items[i][f].pieces[k] = int(data[pos] >> 4)
+				}
+			}
+			pos++
+		}
+		for i := 0; i < sides; i++ {
+			t.setGroups(&t.items[i][f], order[i], f)
+		}
+	}
+	pos += pos & 1 // Word alignment
+
+	for f := 0; f <= maxFile; f++ {
+		for i := 0; i < sides; i++ {
+			pos = t.items[i][f].setSizes(data, pos)
+		}
+	}
+	if t.typ == dtzTable {
+		pos = t.setDTZMap(data, pos, maxFile)
+	}
+	for f := 0; f <= maxFile; f++ {
+		for i := 0; i < sides; i++ {
+			d := &t.items[i][f]
+			d.sparseIndex = pos
+			pos += int(d.sparseIndexSize) * 6
+		}
+	}
+	for f := 0; f <= maxFile; f++ {
+		for i := 0; i < sides; i++ {
+			d := &t.items[i][f]
+			d.blockLength = pos
+			pos += int(d.blockLengthSize) * 2
+		}
+	}
+	for f := 0; f <= maxFile; f++ {
+		for i := 0; i < sides; i++ {
+			pos = (pos + 0x3F) &^ 0x3F // 64 byte alignment
+			d := &t.items[i][f]
+			d.data = pos
+			pos += int(d.blocksNum * d.sizeofBlock)
+		}
+	}
+	if pos > len(data) {
+		return errors.New("syzygy: truncated table " + t.path)
+	}
+	return nil
+}
+
+// setGroups splits the pieces into groups that are encoded together and
+// computes the multiplier (groupIdx) of every group in the position index.
+// order gives the position of the leading group and of the remaining pawns.
+func (t *table) setGroups(d *pairsData, order [2]int, file int) {
+	n := 0
+	firstLen := 2
+	if t.hasPawns {
+		firstLen = 0
+	} else if t.hasUniquePieces {
+		firstLen = 3
+	}
+	d.groupLen[n] = 1
+	// Pieces of the same kind form one group, except for the leading group,
+	// which holds the first firstLen pieces.
+	for i := 1; i < t.pieceCount; i++ {
+		firstLen--
+		if firstLen > 0 || d.pieces[i] == d.pieces[i-1] {
+			d.groupLen[n]++
+		} else {
+			n++
+			d.groupLen[n] = 1
+		}
+	}
+	n++
+	d.groupLen[n] = 0 // Zero-terminated
+
+	pp := t.hasPawns && t.pawnCount[1] > 0
+	next := 1
+	freeSquares := 64 - d.groupLen[0]
+	if pp {
+		next = 2
+		freeSquares -= d.groupLen[1]
+	}
+	idx := uint64(1)
+	for k := 0; next < n || k == order[0] || k == order[1]; k++ {
+		switch {
+		case k == order[0]: // Leading pawns or pieces
+			d.groupIdx[0] = idx
+			switch {
+			case t.hasPawns:
+				idx *= uint64(leadPawnsSize[d.groupLen[0]][file])
+			case t.hasUniquePieces:
+				idx *= 31332
+			default:
+				idx *= 462
+			}
+		case k == order[1]: // Remaining pawns
+			d.groupIdx[1] = idx
+			idx *= uint64(binomial[d.groupLen[1]][48-d.groupLen[0]])
+		default: // Remaining pieces
+			d.groupIdx[next] = idx
+			idx *= uint64(binomial[d.groupLen[next]][freeSquares])
+			freeSquares -= d.groupLen[next]
+			next++
+		}
+	}
+	d.groupIdx[n] = idx
+}
+
+// setSizes reads the compression parameters of a sub-table and builds the
+// canonical Huffman decoding tables.
+func (d *pairsData) setSizes(data []byte, pos int) int {
+	d.flags = data[pos]
+	pos++
+	if d.flags&flagSingleValue != 0 {
+		// Every position has the same value, stored in minSymLen.
+		d.minSymLen = int(data[pos])
+		return pos + 1
+	}
+
+	// groupLen is zero-terminated and the matching groupIdx is the table size.
+	n := 0
+	for n < maxPieces && d.groupLen[n] != 0 {
+		n++
+	}
+	tbSize := d.groupIdx[n]
+
+	d.sizeofBlock = 1 << data[pos]
+	d.span = 1 << data[pos+1]
+	d.sparseIndexSize = (tbSize + d.span - 1) / d.span
+	padding := uint64(data[pos+2])
+	d.blocksNum = uint64(binary.LittleEndian.Uint32(data[pos+3:]))
+	// blockLength is padded so the sparse index never points past its end.
+	d.blockLengthSize = d.blocksNum + padding
+	d.maxSymLen = int(data[pos+7])
+	d.minSymLen = int(data[pos+8])
+	pos += 9
+	d.lowestSym = pos
+
+	// Longer codes have lower values in the canonical code. base64[l] is the
+	// smallest 64-bit left-aligned code of length l+minSymLen.
+	d.base64 = make([]uint64, d.maxSymLen-d.minSymLen+1)
+	for i := len(d.base64) - 2; i >= 0; i-- {
+		d.base64[i] = (d.base64[i+1] + uint64(le16(data, d.lowestSym+2*i)) -
+			uint64(le16(data, d.lowestSym+2*(i+1)))) / 2
+	}
+	for i := range d.base64 {
+		d.base64[i] <<= uint(64 - i - d.minSymLen)
+	}
+	pos += len(d.base64) * 2
+
+	// Symbols are built by "recursive pairing": each one expands to a pair of
+	// smaller symbols stored in btree. symlen[s]+1 is how many values s stands for.
+	d.symlen = make([]uint8, le16(data, pos))
+	pos += 2
+	d.btree = pos
+	visited := make([]bool, len(d.symlen))
+	for s := range d.symlen {
+		if !visited[s] {
+			d.symlen[s] = d.setSymlen(data, s, visited)
+		}
+	}
+	return pos + len(d.symlen)*3 + len(d.symlen)&1
+}
+
+func (d *pairsData) setSymlen(data []byte, s int, visited []bool) uint8 {
+	visited[s] = true
+	right := d.right(data, s)
+	if right == 0xFFF {
+		return 0
+	}
+	left := d.left(data, s)
+	if !visited[left] {
+		d.symlen[left] = d.setSymlen(data, left, visited)
+	}
+	if !visited[right] {
+		d.symlen[right] = d.setSymlen(data, right, visited)
+	}
+	return d.symlen[left] + d.symlen[right] + 1
+}
+
+// left and right read the two 12-bit halves of a btree node. For a leaf
+// (right == 0xFFF) the left half is the stored value.
+func (d *pairsData) left(data []byte, s int) int {
+	node := data[d.btree+3*s:]
+	return int(node[1]&0xF)<<8 | int(node[0])
+}
+
+func (d *pairsData) right(data []byte, s int) int {
+	node := data[d.btree+3*s:]
+	return int(node[2])<<4 | int(node[1]>>4)
+}
+
+// setDTZMap records where the value maps of a DTZ table start. Mapped tables
+// store small indices that are translated to real distances through these.
+func (t *table) setDTZMap(data []byte, pos, maxFile int) int {
+	t.dtzMap = pos
+	for f := 0; f <= maxFile; f++ {
+		d := t.get(0, f)
+		if d.flags&flagMapped == 0 {
+			continue
+		}
+		if d.flags&flagWide != 0 {
+			pos += pos & 1 // Word alignment
+			for i := 0; i < 4; i++ {
+				d.mapIdx[i] = (pos-t.dtzMap)/2 + 1
+				pos += 2*int(le16(data, pos)) + 2
+			}
+		} else {
+			for i := 0; i < 4; i++ {
+				d.mapIdx[i] = pos - t.dtzMap + 1
+				pos += int(data[pos]) + 1
+			}
+		}
+	}
+	return pos + pos&1
+}
+
+// decompress returns the value stored at index idx of the sub-table.
+func (d *pairsData) decompress(data []byte, idx uint64) int {
+	if d.flags&flagSingleValue != 0 {
+		return d.minSymLen
+	}
+
+	// The sparse index tells, for every span-th value, in which block it is
+	// and at which offset. Start there and walk to the block holding idx.
+	k := idx / d.span
+	entry := d.sparseIndex + int(k)*6
+	block := int(binary.LittleEndian.Uint32(data[entry:]))
+	offset := int(le16(data, entry+4))
+	offset += int(idx%d.span) - int(d.span/2)
+
+	blockLen := func(b int) int { return int(le16(data, d.blockLength+2*b)) }
+	for offset < 0 {
+		block--
+		offset += blockLen(block) + 1
+	}
+	for offset > blockLen(block) {
+		offset -= blockLen(block) + 1
+		block++
+	}
+
+	// Read Huffman symbols from the block until we reach the one covering offset.
+	ptr := d.data + block*int(d.sizeofBlock)
+	buf64 := binary.BigEndian.Uint64(data[ptr:])
+	ptr += 8
+	buf64Size := 64
+	var sym uint16
+	for {
+		l := 0
+		for buf64 < d.base64[l] {
+			l++
+		}
+		sym = uint16((buf64 - d.base64[l]) >> uint(64-l-d.minSymLen))
+		sym += le16(data, d.lowestSym+2*l)
+		if offset < int(d.symlen[sym])+1 {
+			break
+		}
+		offset -= int(d.symlen[sym]) + 1
+		l += d.minSymLen
+		buf64 <<= uint(l)
+		buf64Size -= l
+		if buf64Size <= 32 {
+			buf64Size += 32
+			buf64 |= uint64(binary.BigEndian.Uint32(data[ptr:])) << uint(64-buf64Size)
+			ptr += 4
+		}
+	}
+
+	// Expand the pair symbol until we reach the leaf holding our value.
+	s := int(sym)
+	for d.symlen[s] != 0 {
+		left := d.left(data, s)
+		if offset < int(d.symlen[left])+1 {
+			s = left
+		} else {
+			offset -= int(d.symlen[left]) + 1
+			s = d.right(data, s)
+		}
+	}
+	return d.left(data, s)
+}
+
+// probe looks up the position described by pieces (piece codes indexed by
+// square) in the table. material is the material key of the position.
+// For DTZ tables wdl is the known result, used to decode the value.
+func (t *table) probe(pieces *[64]int, stm int, material string, wdl int, state *probeState) int {
+	var squares [maxPieces]int
+	var codes [maxPieces]int
+	size, leadPawnsCnt := 0, 0
+	tbFile := 0
+
+	// Tables store white as the stronger side, and symmetric tables only
+	// white to move, so we may have to swap the colours and flip the board.
+	symmetricBlackToMove := t.key == t.key2 && stm == 1
+	blackStronger := material != t.key
+	flipColor, flipSquares := 0, 0
+	if symmetricBlackToMove || blackStronger {
+		flipColor, flipSquares = 8, 56
+		stm ^= 1
+	}
+
+	// With pawns the table is split by the file of the leading pawn: the
+	// pawn of the leading colour nearest the edge, lowest rank first.
+	var leadPawn int
+	if t.hasPawns {
+		leadPawn = t.get(0, 0).pieces[0] ^ flipColor
+		for sq := 0; sq < 64; sq++ {
+			if pieces[sq] == leadPawn {
+				squares[size] = sq ^ flipSquares
+				size++
+			}
+		}
+		leadPawnsCnt = size
+		best := 0
+		for i := 1; i < leadPawnsCnt; i++ {
+			if mapPawns[squares[i]] > mapPawns[squares[best]] {
+				best = i
+			}
+		}
+		squares[0], squares[best] = squares[best], squares[0]
+		tbFile = edgeDistance(squares[0] & 7)
+	}
+
+	// DTZ tables only store one side to move.
+	if t.typ == dtzTable {
+		flags := t.get(stm, tbFile).flags
+		if int(flags&flagSTM) != stm && !(t.key == t.key2 && !t.hasPawns) {
+			*state = changeSTM
+			return 0
+		}
+	}
+
+	for sq := 0; sq < 64; sq++ {
+		if pieces[sq] == 0 || (t.hasPawns && pieces[sq] == leadPawn) {
+			continue
+		}
+		squares[size] = sq ^ flipSquares
+		codes[size] = pieces[sq] ^ flipColor
+		size++
+	}
+
+	d := t.get(stm, tbFile)
+
+	// Put the pieces in the order the table encodes them.
+	for i := leadPawnsCnt; i < size-1; i++ {
+		for j := i + 1; j < size; j++ {
+			if d.pieces[i] == codes[j] {
+				codes[i], codes[j] = codes[j], codes[i]
+				squares[i], squares[j] = squares[j], squares[i]
+				break
+			}
+		}
+	}
+
+	// Mirror so the leading piece is on files a-d.
+	if squares[0]&7 > 3 {
+		for i := 0; i < size; i++ {
+			squares[i] ^= 7
+		}
+	}
+
+	var idx uint64
+	if t.hasPawns {
+		idx = uint64(leadPawnIdx[leadPawnsCnt][squares[0]])
+		rest := squares[1:leadPawnsCnt]
+		sort.SliceStable(rest, func(i, j int) bool { return mapPawns[rest[i]] < mapPawns[rest[j]] })
+		for i := 1; i < leadPawnsCnt; i++ {
+			idx += uint64(binomial[i][mapPawns[squares[i]]])
+		}
+	} else {
+		idx = t.encodePieces(d, squares[:size])
+	}
+
+	// Encode the remaining groups, each with its squares in ascending order.
+	idx *= d.groupIdx[0]
+	start := d.groupLen[0]
+	remainingPawns := t.hasPawns && t.pawnCount[1] > 0
+	for next := 1; d.groupLen[next] != 0; next++ {
+		group := squares[start : start+d.groupLen[next]]
+		sort.Ints(group)
+		var n uint64
+		for i, sq := range group {
+			// Skip squares already taken by the previous groups.
+			adjust := 0
+			for _, prev := range squares[:start] {
+				if sq > prev {
+					adjust++
+				}
+			}
+			if remainingPawns {
+				adjust += 8
+			}
+			n += uint64(binomial[i+1][sq-adjust])
+		}
+		remainingPawns = false
+		idx += n * d.groupIdx[next]
+		start += d.groupLen[next]
+	}
+
+	return t.mapScore(tbFile, d.decompress(t.data, idx), wdl)
+}
+
+// encodePieces computes the index of the leading group of a pawnless table,
+// using the 8-fold symmetry of the board.
+func (t *table) encodePieces(d *pairsData, squares []int) uint64 {
+	// Flip so the leading piece is on ranks 1-4.
+	if squares[0]>>3 > 3 {
+		for i := range squares {
+			squares[i] ^= 56
+		}
+	}
+	// The first leading piece off the a1-h8 diagonal must be below it.
+	for i := 0; i < d.groupLen[0]; i++ {
+		off := offA1H8(squares[i])
+		if off == 0 {
+			continue
+		}
+		if off > 0 {
+			for j := i; j < len(squares); j++ {
+				squares[j] = ((squares[j] >> 3) | (squares[j] << 3)) & 63
+			}
+		}
+		break
+	}
+
+	if !t.hasUniquePieces {
+		// Only the two kings form the leading group.
+		return uint64(mapKK[mapA1D1D4[squares[0]]][squares[1]])
+	}
+
+	// At least three unique pieces: encode the first three together.
+	s0, s1, s2 := squares[0], squares[1], squares[2]
+	adjust1 := b2i(s1 > s0)
+	adjust2 := b2i(s2 > s0) + b2i(s2 > s1)
+	switch {
+	case offA1H8(s0) != 0:
+		return uint64((mapA1D1D4[s0]*63+(s1-adjust1))*62 + s2 - adjust2)
+	case offA1H8(s1) != 0:
+		return uint64((6*63+(s0>>3)*28+mapB1H1H7[s1])*62 + s2 - adjust2)
+	case offA1H8(s2) != 0:
+		return uint64(6*63*62 + 4*28*62 + (s0>>3)*7*28 + ((s1>>3)-adjust1)*28 + mapB1H1H7[s2])
+	default:
+		return uint64(6*63*62 + 4*28*62 + 4*7*28 + (s0>>3)*7*6 + ((s1>>3)-adjust1)*6 + (s2 >> 3) - adjust2)
+	}
+}
+
+// mapScore converts a raw table value into a WDL result or a DTZ in plies.
+func (t *table) mapScore(file, value, wdl int) int {
+	if t.typ == wdlTable {
+		return value - 2
+	}
+
+	d := t.get(0, file)
+	if d.flags&flagMapped != 0 {
+		// Loss, blessed loss, draw, cursed win, win -> map number
+		m := d.mapIdx[[5]int{1, 3, 0, 2, 0}[wdl+2]]
+		if d.flags&flagWide != 0 {
+			value = int(le16(t.data, t.dtzMap+2*(m+value)))
+		} else {
+			value = int(t.data[t.dtzMap+m+value])
+		}
+	}
+
+	// Tables store moves unless the flags say plies; we always return plies.
+	if (wdl == Win && d.flags&flagWinPlies == 0) ||
+		(wdl == Loss && d.flags&flagLossPlies == 0) ||
+		wdl == CursedWin || wdl == BlessedLoss {
+		value *= 2
+	}
+	return value + 1
+}
+
+func le16(data []byte, pos int) uint16 {
+	return binary.LittleEndian.Uint16(data[pos:])
+}
+
+func countByte(s string, c byte) int {
+	n := 0
+	for i := 0; i < len(s); i++ {
+		if s[i] == c {
+			n++
+		}
+	}
+	return n
+}
+
+func b2i(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
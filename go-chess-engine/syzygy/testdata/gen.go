@@ -0,0 +1,1053 @@
+//go:build ignore
+
+// gen.go writes the small tables the syzygy tests probe. Every ending is
+// solved by retrograde analysis and stored in the Syzygy file format,
+// compressed by recursive pairing and a canonical Huffman code like the
+// tables of the original generator.
+//
+// Run it from this directory:
+//
+//	go run gen.go
+package main
+
+import (
+	"container/heap"
+	"encoding/binary"
+	"fmt"
+	"log"
+	"math/bits"
+	"os"
+	"sort"
+	"strings"
+)
+
+// endings are solved in this order, so that every capture and promotion
+// leads to an ending solved before.
+var endings = []string{"KNvK", "KBvK", "KRvK", "KQvK", "KPvK", "KRvKB"}
+
+// Results, from the side to move's point of view.
+const (
+	loss    = -2
+	draw    = 0
+	win     = 2
+	unknown = 100
+	illegal = -100
+
+	noCapture = -3
+)
+
+// Piece kinds, numbered as in the table files.
+const (
+	pawn = iota + 1
+	knight
+	bishop
+	rook
+	queen
+	king
+)
+
+// letters is the order of the pieces of a side in an ending's name.
+const letters = "KQRBNP"
+
+var letterKinds = map[byte]int{'K': king, 'Q': queen, 'R': rook, 'B': bishop, 'N': knight, 'P': pawn}
+
+// piece is a piece of an ending; color 0 is White.
+type piece struct{ color, kind int }
+
+// code is the piece code of the table files: 1-6 for White, 9-14 for Black.
+func (p piece) code() int { return p.kind + 8*p.color }
+
+// ending is a solved ending. Positions are numbered by the squares of the
+// pieces, the first piece in the lowest bits, and the side to move on top.
+// wdl holds the result and dtz the distance to zeroing in plies, signed
+// like the result, as the prober computes it.
+type ending struct {
+	name   string
+	pieces []piece // White's, then Black's, each in letters order
+	wdl    []int8
+	dtz    []int16
+
+	// What the prober finds by itself: the best result of a capture, or
+	// noCapture, and whether the best move is a winning zeroing move or
+	// every move zeroes the counter.
+	capture      []int8
+	allCaptures  []bool
+	zeroingFound []bool
+
+	// While solving: the quiet moves not yet known to lose, and whether no
+	// capture or pawn move saves the position.
+	remaining []uint8
+	noEscape  []bool
+}
+
+var solved = map[string]*ending{}
+
+func newEnding(name string) *ending {
+	e := &ending{name: name}
+	for color, side := range strings.Split(name, "v") {
+		for i := 0; i < len(side); i++ {
+			e.pieces = append(e.pieces, piece{color, letterKinds[side[i]]})
+		}
+	}
+	return e
+}
+
+func (e *ending) size() int { return 2 << (6 * len(e.pieces)) }
+
+func (e *ending) index(stm int, squares []int) int {
+	idx := stm
+	for i := len(squares) - 1; i >= 0; i-- {
+		idx = idx<<6 | squares[i]
+	}
+	return idx
+}
+
+func (e *ending) decode(idx int, squares []int) (stm int) {
+	for i := range squares {
+		squares[i] = idx & 63
+		idx >>= 6
+	}
+	return idx
+}
+
+// Attacks, as bitboards with bit 0 for a1.
+
+var (
+	knightOffsets = [][2]int{{1, 2}, {2, 1}, {2, -1}, {1, -2}, {-1, -2}, {-2, -1}, {-2, 1}, {-1, 2}}
+	kingOffsets   = [][2]int{{1, 0}, {1, 1}, {0, 1}, {-1, 1}, {-1, 0}, {-1, -1}, {0, -1}, {1, -1}}
+	pawnOffsets   = [2][][2]int{{{-1, 1}, {1, 1}}, {{-1, -1}, {1, -1}}}
+	bishopRays    = [][2]int{{1, 1}, {1, -1}, {-1, 1}, {-1, -1}}
+	rookRays      = [][2]int{{1, 0}, {-1, 0}, {0, 1}, {0, -1}}
+)
+
+func onBoard(file, rank int) bool { return file >= 0 && file < 8 && rank >= 0 && rank < 8 }
+
+func steps(sq int, offsets [][2]int) uint64 {
+	var set uint64
+	for _, o := range offsets {
+		if f, r := sq&7+o[0], sq>>3+o[1]; onBoard(f, r) {
+			set |= 1 << (r*8 + f)
+		}
+	}
+	return set
+}
+
+func rays(sq int, occ uint64, directions [][2]int) uint64 {
+	var set uint64
+	for _, d := range directions {
+		for f, r := sq&7+d[0], sq>>3+d[1]; onBoard(f, r); f, r = f+d[0], r+d[1] {
+			set |= 1 << (r*8 + f)
+			if occ&(1<<(r*8+f)) != 0 {
+				break
+			}
+		}
+	}
+	return set
+}
+
+func attacks(p piece, sq int, occ uint64) uint64 {
+	switch p.kind {
+	case pawn:
+		return steps(sq, pawnOffsets[p.color])
+	case knight:
+		return steps(sq, knightOffsets)
+	case bishop:
+		return rays(sq, occ, bishopRays)
+	case rook:
+		return rays(sq, occ, rookRays)
+	case queen:
+		return rays(sq, occ, bishopRays) | rays(sq, occ, rookRays)
+	}
+	return steps(sq, kingOffsets)
+}
+
+// board is a position of an ending being examined. A captured piece has
+// square -1.
+type board struct {
+	pieces  []piece
+	squares []int
+}
+
+// occupied returns the squares of the pieces of color, or of all pieces
+// if color is -1.
+func (b *board) occupied(color int) uint64 {
+	var occ uint64
+	for i, sq := range b.squares {
+		if sq >= 0 && (color < 0 || b.pieces[i].color == color) {
+			occ |= 1 << sq
+		}
+	}
+	return occ
+}
+
+// attacked reports whether a piece of color attacks sq.
+func (b *board) attacked(sq, color int) bool {
+	occ := b.occupied(-1)
+	for i, p := range b.pieces {
+		if p.color == color && b.squares[i] >= 0 && attacks(p, b.squares[i], occ)&(1<<sq) != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (b *board) king(color int) int {
+	for i, p := range b.pieces {
+		if p.color == color && p.kind == king {
+			return b.squares[i]
+		}
+	}
+	panic("no king")
+}
+
+// legal reports whether the squares form a position with stm to move.
+func (b *board) legal(stm int) bool {
+	var occ uint64
+	for i, sq := range b.squares {
+		if occ&(1<<sq) != 0 || b.pieces[i].kind == pawn && (sq < 8 || sq >= 56) {
+			return false
+		}
+		occ |= 1 << sq
+	}
+	return !b.attacked(b.king(1-stm), stm)
+}
+
+// move moves the piece with index i to to, capturing the piece with index
+// captured (-1 if none) and promoting to kind promotion (0 if none).
+type move struct{ i, to, captured, promotion int }
+
+// zeroing reports whether m resets the 50-move counter.
+func (b *board) zeroing(m move) bool {
+	return m.captured >= 0 || b.pieces[m.i].kind == pawn
+}
+
+// moves returns the legal moves of stm.
+func (b *board) moves(stm int) []move {
+	var moves []move
+	occ := b.occupied(-1)
+	add := func(i, to, promotion int) {
+		m := move{i, to, -1, promotion}
+		for j, sq := range b.squares {
+			if sq == to {
+				m.captured = j
+			}
+		}
+		from := b.squares[i]
+		b.squares[i] = to
+		if m.captured >= 0 {
+			b.squares[m.captured] = -1
+		}
+		if !b.attacked(b.king(stm), 1-stm) {
+			moves = append(moves, m)
+		}
+		b.squares[i] = from
+		if m.captured >= 0 {
+			b.squares[m.captured] = to
+		}
+	}
+	for i, p := range b.pieces {
+		from := b.squares[i]
+		if p.color != stm || from < 0 {
+			continue
+		}
+		var targets uint64
+		if p.kind != pawn {
+			targets = attacks(p, from, occ) &^ b.occupied(stm)
+		} else {
+			if p.color != 0 {
+				log.Fatal("only White may have pawns")
+			}
+			if occ&(1<<(from+8)) == 0 {
+				targets |= 1 << (from + 8)
+				if from < 16 && occ&(1<<(from+16)) == 0 {
+					targets |= 1 << (from + 16)
+				}
+			}
+			targets |= attacks(p, from, occ) & b.occupied(1)
+		}
+		for ; targets != 0; targets &= targets - 1 {
+			to := bits.TrailingZeros64(targets)
+			if p.kind != pawn || to < 56 {
+				add(i, to, 0)
+				continue
+			}
+			for _, kind := range []int{queen, rook, bishop, knight} {
+				add(i, to, kind)
+			}
+		}
+	}
+	return moves
+}
+
+func (b *board) apply(m move) *board {
+	child := &board{pieces: append([]piece(nil), b.pieces...), squares: append([]int(nil), b.squares...)}
+	if m.captured >= 0 {
+		child.squares[m.captured] = -1
+	}
+	child.squares[m.i] = m.to
+	if m.promotion != 0 {
+		child.pieces[m.i].kind = m.promotion
+	}
+	return child
+}
+
+// result returns the result of b with stm to move, after a capture or a
+// pawn move, from the solved ending it belongs to.
+func result(b *board, stm int) int {
+	var left []int
+	for i, sq := range b.squares {
+		if sq >= 0 {
+			left = append(left, i)
+		}
+	}
+	if len(left) == 2 {
+		return draw // KvK
+	}
+	for flip := 0; flip < 2; flip++ {
+		var sides [2]string
+		for c := 0; c < len(letters); c++ {
+			for _, i := range left {
+				if p := b.pieces[i]; p.kind == letterKinds[letters[c]] {
+					sides[p.color^flip] += letters[c : c+1]
+				}
+			}
+		}
+		e := solved[sides[0]+"v"+sides[1]]
+		if e == nil {
+			continue
+		}
+		var squares []int
+		used := make([]bool, len(b.pieces))
+		for _, want := range e.pieces {
+			for _, i := range left {
+				if p := b.pieces[i]; !used[i] && p.kind == want.kind && p.color^flip == want.color {
+					used[i] = true
+					squares = append(squares, b.squares[i]^(56*flip))
+					break
+				}
+			}
+		}
+		v := e.wdl[e.index(stm^flip, squares)]
+		if v == unknown || v == illegal {
+			log.Fatalf("%s: probing a position that is not solved", e.name)
+		}
+		return int(v)
+	}
+	log.Fatalf("no ending for the pieces %v", b.pieces)
+	return 0
+}
+
+// solve fills in wdl and dtz. Pawn moves lead to other positions of the
+// same ending, so pawn endings are solved in slices by the square of the
+// pawn, the most advanced first.
+func (e *ending) solve() {
+	n := e.size()
+	e.wdl = make([]int8, n)
+	e.dtz = make([]int16, n)
+	e.capture = make([]int8, n)
+	e.allCaptures = make([]bool, n)
+	e.zeroingFound = make([]bool, n)
+	e.remaining = make([]uint8, n)
+	e.noEscape = make([]bool, n)
+	for i := range e.wdl {
+		e.wdl[i] = illegal
+	}
+	solved[e.name] = e
+
+	pawnIndex := -1
+	for i, p := range e.pieces {
+		if p.kind == pawn {
+			pawnIndex = i
+		}
+	}
+	if pawnIndex < 0 {
+		e.solveSlice(func([]int) bool { return true })
+	} else {
+		for sq := 55; sq >= 8; sq-- {
+			e.solveSlice(func(squares []int) bool { return squares[pawnIndex] == sq })
+		}
+	}
+	e.remaining, e.noEscape = nil, nil
+}
+
+func (e *ending) solveSlice(inSlice func(squares []int) bool) {
+	var winners, losers [128][]int32
+	var slice []int32
+
+	squares := make([]int, len(e.pieces))
+	b := &board{pieces: e.pieces, squares: squares}
+	for idx := 0; idx < e.size(); idx++ {
+		stm := e.decode(idx, squares)
+		if !inSlice(squares) || !b.legal(stm) {
+			continue
+		}
+		slice = append(slice, int32(idx))
+		best, capture, quiet, captures := noCapture, noCapture, 0, 0
+		moves := b.moves(stm)
+		for _, m := range moves {
+			if !b.zeroing(m) {
+				quiet++
+				continue
+			}
+			v := -result(b.apply(m), 1-stm)
+			best = max(best, v)
+			if m.captured >= 0 {
+				capture = max(capture, v)
+				captures++
+			}
+		}
+		e.capture[idx] = int8(capture)
+		e.allCaptures[idx] = captures > 0 && captures == len(moves)
+		e.zeroingFound[idx] = best == win || quiet == 0 && len(moves) > 0
+		switch {
+		case len(moves) == 0 && b.attacked(b.king(stm), 1-stm):
+			e.wdl[idx], e.dtz[idx] = loss, -1
+			losers[1] = append(losers[1], int32(idx))
+		case len(moves) == 0:
+			e.wdl[idx] = draw
+		case best == win:
+			e.wdl[idx], e.dtz[idx] = win, 1
+			winners[1] = append(winners[1], int32(idx))
+		case quiet == 0 && best == loss:
+			e.wdl[idx], e.dtz[idx] = loss, -1
+			losers[1] = append(losers[1], int32(idx))
+		case quiet == 0:
+			e.wdl[idx] = int8(best)
+		default:
+			e.wdl[idx] = unknown
+			e.remaining[idx] = uint8(quiet)
+			e.noEscape[idx] = best <= loss
+		}
+	}
+
+	// Settle the positions in the order of their distance: a winner takes
+	// the quickest way to a lost position, a loser the longest way to a won
+	// one. Losers go first so that mates count as zeroing moves.
+	pred := make([]int, len(e.pieces))
+	for d := 1; d < len(winners); d++ {
+		for _, i := range losers[d] {
+			mated := d == 1 && len(b.moves(e.decode(int(i), squares))) == 0
+			e.predecessors(int(i), pred, func(p int) {
+				if e.wdl[p] != unknown {
+					return
+				}
+				dtz := d + 1
+				if mated {
+					dtz = 1
+				}
+				e.wdl[p], e.dtz[p] = win, int16(dtz)
+				winners[dtz] = append(winners[dtz], int32(p))
+			})
+		}
+		for _, i := range winners[d] {
+			e.predecessors(int(i), pred, func(p int) {
+				if e.wdl[p] != unknown {
+					return
+				}
+				e.remaining[p]--
+				if e.remaining[p] == 0 && e.noEscape[p] {
+					if d+1 >= len(losers) || d+1 > 100 {
+						log.Fatalf("%s: distances beyond the 50-move rule are not supported", e.name)
+					}
+					e.wdl[p], e.dtz[p] = loss, int16(-d-1)
+					losers[d+1] = append(losers[d+1], int32(p))
+				}
+			})
+		}
+	}
+	for _, idx := range slice {
+		if e.wdl[idx] == unknown {
+			e.wdl[idx] = draw
+		}
+	}
+}
+
+// predecessors calls f with every legal position from which a quiet move,
+// neither a capture nor a pawn move, leads to position idx.
+func (e *ending) predecessors(idx int, squares []int, f func(int)) {
+	stm := e.decode(idx, squares)
+	b := &board{pieces: e.pieces, squares: squares}
+	occ := b.occupied(-1)
+	for i, p := range e.pieces {
+		if p.color == stm || p.kind == pawn {
+			continue
+		}
+		to := squares[i]
+		for from := attacks(p, to, occ) &^ occ; from != 0; from &= from - 1 {
+			squares[i] = bits.TrailingZeros64(from)
+			if prev := e.index(1-stm, squares); e.wdl[prev] != illegal {
+				f(prev)
+			}
+		}
+		squares[i] = to
+	}
+}
+
+func main() {
+	for _, name := range endings {
+		e := newEnding(name)
+		e.solve()
+		for typ, ext := range []string{".rtbw", ".rtbz"} {
+			data := e.encode(typ)
+			if err := os.WriteFile(name+ext, data, 0o644); err != nil {
+				log.Fatal(err)
+			}
+			fmt.Printf("%s%s: %d bytes\n", name, ext, len(data))
+		}
+	}
+}
+
+// The file format.
+
+const (
+	wdlTable = iota
+	dtzTable
+)
+
+var magics = [2][4]byte{
+	wdlTable: {0x71, 0xE8, 0x23, 0x5D},
+	dtzTable: {0xD7, 0x66, 0x0C, 0xA5},
+}
+
+const (
+	flagWinPlies    = 4
+	flagLossPlies   = 8
+	flagSingleValue = 128
+
+	blockBits = 6  // 64-byte blocks
+	spanBits  = 10 // A sparse index entry for every 1024 values
+)
+
+// dtzSide is the side to move whose distances the DTZ table of an ending
+// stores, White if not listed. The prober finds the other side by a
+// one-ply search, so the tests cover both ways.
+var dtzSide = map[string]int{"KRvK": 1}
+
+// encode builds the table file of the ending.
+func (e *ending) encode(typ int) []byte {
+	hasPawns := strings.Contains(e.name, "P")
+	// The leading group comes first: the pawn, or the kings and the next
+	// piece. No ending here has two pieces of a kind.
+	var order []int
+	for _, kinds := range [][]int{{pawn}, {king}, {queen, rook, bishop, knight}} {
+		for i, p := range e.pieces {
+			for _, k := range kinds {
+				if p.kind == k {
+					order = append(order, i)
+				}
+			}
+		}
+	}
+	enc := &encoder{e: e, order: order, hasPawns: hasPawns, lead: 3}
+	files := 1
+	if hasPawns {
+		enc.lead, files = 1, 4
+	}
+	sides := 2
+	if typ == dtzTable {
+		sides = 1
+	}
+
+	data := append([]byte{}, magics[typ][:]...)
+	flags := byte(1) // Split: the sides differ
+	if hasPawns {
+		flags |= 2
+	}
+	data = append(data, flags)
+	for f := 0; f < files; f++ {
+		data = append(data, 0) // The leading group is encoded first
+		for _, i := range order {
+			c := byte(e.pieces[i].code())
+			data = append(data, c|c<<4)
+		}
+	}
+	if len(data)%2 != 0 {
+		data = append(data, 0)
+	}
+
+	var parts []*compressed
+	for f := 0; f < files; f++ {
+		for side := 0; side < sides; side++ {
+			stm, flags := side, byte(0)
+			// The prober trusts a capture that is as good as the table
+			// says, and does not look at the table if every move captures.
+			value := func(idx int) (int, bool) {
+				v := int(e.wdl[idx])
+				if e.allCaptures[idx] {
+					return anyValue, false
+				}
+				return v + 2, int(e.capture[idx]) != v
+			}
+			if typ == dtzTable {
+				stm = dtzSide[e.name]
+				flags = byte(stm) | flagWinPlies | flagLossPlies
+				// Draws are not stored, and a winning zeroing move or one
+				// with only zeroing moves needs no distance.
+				value = func(idx int) (int, bool) {
+					if e.wdl[idx] == draw || e.zeroingFound[idx] {
+						return anyValue, false
+					}
+					d := int(e.dtz[idx])
+					return max(d, -d) - 1, true
+				}
+			}
+			parts = append(parts, compress(enc.values(f, stm, value), flags))
+		}
+	}
+
+	for _, c := range parts {
+		data = append(data, c.sizes...)
+	}
+	if typ == dtzTable && len(data)%2 != 0 {
+		data = append(data, 0)
+	}
+	for _, c := range parts {
+		data = append(data, c.sparseIndex...)
+	}
+	for _, c := range parts {
+		data = append(data, c.blockLength...)
+	}
+	for _, c := range parts {
+		for len(data)%64 != 0 {
+			data = append(data, 0)
+		}
+		data = append(data, c.blocks...)
+	}
+	// The decoder reads a little past the last block.
+	return append(data, make([]byte, 16)...)
+}
+
+// The position index.
+
+var (
+	mapB1H1H7     [64]int
+	mapA1D1D4     [64]int
+	leadPawnIdx   [64]int // For a single leading pawn
+	leadPawnsSize [4]int
+)
+
+func offA1H8(sq int) int { return sq>>3 - sq&7 }
+
+func init() {
+	code := 0
+	for sq := 0; sq < 64; sq++ {
+		if offA1H8(sq) < 0 {
+			mapB1H1H7[sq] = code
+			code++
+		}
+	}
+	code = 0
+	var diagonal []int
+	for sq := 0; sq <= 27; sq++ {
+		if sq&7 > 3 {
+			continue
+		}
+		if offA1H8(sq) < 0 {
+			mapA1D1D4[sq] = code
+			code++
+		} else if offA1H8(sq) == 0 {
+			diagonal = append(diagonal, sq)
+		}
+	}
+	for _, sq := range diagonal {
+		mapA1D1D4[sq] = code
+		code++
+	}
+	for file := 0; file < 4; file++ {
+		for rank := 1; rank <= 6; rank++ {
+			leadPawnIdx[rank*8+file] = leadPawnsSize[file]
+			leadPawnsSize[file]++
+		}
+	}
+}
+
+// encoder maps the positions of an ending to the index of its table.
+type encoder struct {
+	e        *ending
+	order    []int // Piece indices in table order
+	hasPawns bool
+	lead     int // Length of the leading group; the others have one piece
+}
+
+// size is the number of values of the sub-table for the leading pawn's file.
+func (enc *encoder) size(file int) int {
+	n := 31332
+	if enc.hasPawns {
+		n = leadPawnsSize[file]
+	}
+	for free := 64 - enc.lead; free > 64-len(enc.order); free-- {
+		n *= free
+	}
+	return n
+}
+
+// anyValue is the bound of a value that does not matter.
+const anyValue = 0xFFF
+
+// values returns the values of the sub-table for file and stm. value
+// returns the value of a position and true, or false and the largest value
+// that will do.
+func (enc *encoder) values(file, stm int, value func(idx int) (int, bool)) []int {
+	values := make([]int, enc.size(file))
+	bound := make([]int, len(values))
+	set := make([]bool, len(values))
+	for i := range bound {
+		bound[i] = anyValue
+	}
+	e := enc.e
+	squares := make([]int, len(e.pieces))
+	for idx := 0; idx < e.size(); idx++ {
+		if e.decode(idx, squares) != stm || e.wdl[idx] == illegal {
+			continue
+		}
+		f, i := enc.index(squares)
+		if f != file {
+			continue
+		}
+		v, exact := value(idx)
+		if !exact {
+			bound[i] = min(bound[i], v)
+			continue
+		}
+		if set[i] && values[i] != v {
+			log.Fatalf("%s: positions with index %d differ", e.name, i)
+		}
+		values[i], set[i] = v, true
+	}
+	// Free values repeat the previous one where they can, which compresses
+	// best.
+	prev := 0
+	for i := range values {
+		if set[i] {
+			prev = values[i]
+			break
+		}
+	}
+	for i := range values {
+		if set[i] && values[i] > bound[i] {
+			log.Fatalf("%s: positions with index %d differ", e.name, i)
+		}
+		if !set[i] {
+			values[i] = min(prev, bound[i])
+		}
+		prev = values[i]
+	}
+	return values
+}
+
+// index returns the file of the leading pawn (0 without pawns) and the
+// index of a position given by the squares of the ending's pieces.
+func (enc *encoder) index(pieceSquares []int) (file, idx int) {
+	sq := make([]int, len(enc.order))
+	for i, p := range enc.order {
+		sq[i] = pieceSquares[p]
+	}
+	if sq[0]&7 > 3 {
+		for i := range sq {
+			sq[i] ^= 7
+		}
+	}
+	mult := 31332
+	if enc.hasPawns {
+		file = sq[0] & 7
+		idx, mult = leadPawnIdx[sq[0]], leadPawnsSize[file]
+	} else {
+		if sq[0]>>3 > 3 {
+			for i := range sq {
+				sq[i] ^= 56
+			}
+		}
+		for i := 0; i < enc.lead; i++ {
+			if off := offA1H8(sq[i]); off != 0 {
+				if off > 0 {
+					for j := i; j < len(sq); j++ {
+						sq[j] = (sq[j]>>3 | sq[j]<<3) & 63
+					}
+				}
+				break
+			}
+		}
+		a1, a2 := 0, 0
+		if sq[1] > sq[0] {
+			a1++
+		}
+		if sq[2] > sq[0] {
+			a2++
+		}
+		if sq[2] > sq[1] {
+			a2++
+		}
+		switch {
+		case offA1H8(sq[0]) != 0:
+			idx = (mapA1D1D4[sq[0]]*63+sq[1]-a1)*62 + sq[2] - a2
+		case offA1H8(sq[1]) != 0:
+			idx = (6*63+(sq[0]>>3)*28+mapB1H1H7[sq[1]])*62 + sq[2] - a2
+		case offA1H8(sq[2]) != 0:
+			idx = 6*63*62 + 4*28*62 + (sq[0]>>3)*7*28 + (sq[1]>>3-a1)*28 + mapB1H1H7[sq[2]]
+		default:
+			idx = 6*63*62 + 4*28*62 + 4*7*28 + (sq[0]>>3)*7*6 + (sq[1]>>3-a1)*6 + sq[2]>>3 - a2
+		}
+	}
+
+	free := 64 - enc.lead
+	for k := enc.lead; k < len(sq); k++ {
+		adjust := 0
+		for _, prev := range sq[:k] {
+			if sq[k] > prev {
+				adjust++
+			}
+		}
+		idx += (sq[k] - adjust) * mult
+		mult *= free
+		free--
+	}
+	return file, idx
+}
+
+// Compression.
+
+type compressed struct {
+	sizes, sparseIndex, blockLength, blocks []byte
+}
+
+// symbol stands for a value (a leaf) or for a pair of symbols.
+type symbol struct {
+	leaf        bool
+	value       int
+	left, right int
+	length      int // Number of values
+}
+
+func compress(values []int, flags byte) *compressed {
+	single := true
+	for _, v := range values {
+		single = single && v == values[0]
+	}
+	if single {
+		return &compressed{sizes: []byte{flags | flagSingleValue, byte(values[0])}}
+	}
+
+	// Recursive pairing: replace the most frequent pair of neighbouring
+	// symbols by a new symbol, again and again.
+	var symbols []symbol
+	leafOf := map[int]int{}
+	seq := make([]int, len(values))
+	for i, v := range values {
+		s, ok := leafOf[v]
+		if !ok {
+			s = len(symbols)
+			leafOf[v] = s
+			symbols = append(symbols, symbol{leaf: true, value: v, length: 1})
+		}
+		seq[i] = s
+	}
+	for len(symbols) < 4000 {
+		counts := map[[2]int]int{}
+		last := -2
+		for i := 0; i+1 < len(seq); i++ {
+			if seq[i] == seq[i+1] && last == i-1 && seq[i-1] == seq[i] {
+				continue // The pair overlaps the one counted before
+			}
+			counts[[2]int{seq[i], seq[i+1]}]++
+			last = i
+		}
+		best, bestCount := [2]int{}, 0
+		for pair, c := range counts {
+			if symbols[pair[0]].length+symbols[pair[1]].length > 256 {
+				continue
+			}
+			if c > bestCount || c == bestCount && (pair[0] < best[0] || pair[0] == best[0] && pair[1] < best[1]) {
+				best, bestCount = pair, c
+			}
+		}
+		if bestCount < 8 {
+			break
+		}
+		s := len(symbols)
+		symbols = append(symbols, symbol{left: best[0], right: best[1], length: symbols[best[0]].length + symbols[best[1]].length})
+		out := seq[:0]
+		for i := 0; i < len(seq); i++ {
+			if i+1 < len(seq) && seq[i] == best[0] && seq[i+1] == best[1] {
+				out = append(out, s)
+				i++
+			} else {
+				out = append(out, seq[i])
+			}
+		}
+		seq = out
+	}
+
+	lengths := huffman(seq, len(symbols))
+	minLen, maxLen := 64, 0
+	for _, l := range lengths {
+		if l > 0 {
+			minLen, maxLen = min(minLen, l), max(maxLen, l)
+		}
+	}
+	if maxLen > 32 {
+		log.Fatal("Huffman code too long")
+	}
+
+	// Number the symbols: those without a code first, then by decreasing
+	// code length, as the canonical code requires.
+	ids := make([]int, len(symbols))
+	for i := range ids {
+		ids[i] = i
+	}
+	sort.SliceStable(ids, func(i, j int) bool {
+		li, lj := lengths[ids[i]], lengths[ids[j]]
+		if li == 0 || lj == 0 {
+			return li == 0 && lj != 0
+		}
+		return li > lj
+	})
+	number := make([]int, len(symbols))
+	for n, s := range ids {
+		number[s] = n
+	}
+	count := make([]int, maxLen+2)
+	for _, l := range lengths {
+		count[l]++
+	}
+	lowest := make([]int, maxLen+2)
+	base := make([]uint64, maxLen+2)
+	lowest[maxLen] = count[0]
+	for l := maxLen - 1; l >= minLen; l-- {
+		lowest[l] = lowest[l+1] + count[l+1]
+		base[l] = (base[l+1] + uint64(count[l+1])) / 2
+	}
+
+	// Pack whole symbols into blocks, most significant bit first.
+	var blocks, block []byte
+	var blockLength []int // Values per block
+	var acc uint64
+	var accBits, bitsUsed, valuesInBlock int
+	flush := func() {
+		for ; accBits > 0; accBits -= 8 {
+			block = append(block, byte(acc>>56))
+			acc <<= 8
+		}
+		acc, accBits = 0, 0
+		blocks = append(blocks, block...)
+		blocks = append(blocks, make([]byte, 1<<blockBits-len(block))...)
+		blockLength = append(blockLength, valuesInBlock)
+		block, bitsUsed, valuesInBlock = nil, 0, 0
+	}
+	for _, s := range seq {
+		l := lengths[s]
+		code := base[l] + uint64(number[s]-lowest[l])
+		if bitsUsed+l > 8<<blockBits || valuesInBlock+symbols[s].length > 65536 {
+			flush()
+		}
+		for i := l - 1; i >= 0; i-- {
+			acc |= (code >> i & 1) << (63 - accBits)
+			if accBits++; accBits == 64 {
+				block = binary.BigEndian.AppendUint64(block, acc)
+				acc, accBits = 0, 0
+			}
+		}
+		bitsUsed += l
+		valuesInBlock += symbols[s].length
+	}
+	flush()
+
+	c := &compressed{blocks: blocks}
+	starts := make([]int, len(blockLength))
+	total := 0
+	for i, n := range blockLength {
+		starts[i] = total
+		total += n
+		c.blockLength = binary.LittleEndian.AppendUint16(c.blockLength, uint16(n-1))
+	}
+	const span = 1 << spanBits
+	for k := 0; k < (total+span-1)/span; k++ {
+		v := k*span + span/2
+		b := sort.Search(len(starts), func(i int) bool { return starts[i] > v }) - 1
+		if v-starts[b] > 0xFFFF {
+			log.Fatal("sparse index offset too large")
+		}
+		c.sparseIndex = binary.LittleEndian.AppendUint32(c.sparseIndex, uint32(b))
+		c.sparseIndex = binary.LittleEndian.AppendUint16(c.sparseIndex, uint16(v-starts[b]))
+	}
+
+	sizes := []byte{flags, blockBits, spanBits, 0}
+	sizes = binary.LittleEndian.AppendUint32(sizes, uint32(len(blockLength)))
+	sizes = append(sizes, byte(maxLen), byte(minLen))
+	for l := minLen; l <= maxLen; l++ {
+		sizes = binary.LittleEndian.AppendUint16(sizes, uint16(lowest[l]))
+	}
+	sizes = binary.LittleEndian.AppendUint16(sizes, uint16(len(symbols)))
+	nodes := make([][3]byte, len(symbols))
+	for s, sym := range symbols {
+		left, right := sym.value, 0xFFF // A leaf
+		if !sym.leaf {
+			left, right = number[sym.left], number[sym.right]
+		}
+		nodes[number[s]] = [3]byte{byte(left), byte(left>>8&0xF | right<<4&0xF0), byte(right >> 4)}
+	}
+	for _, n := range nodes {
+		sizes = append(sizes, n[:]...)
+	}
+	if len(symbols)%2 != 0 {
+		sizes = append(sizes, 0)
+	}
+	c.sizes = sizes
+	return c
+}
+
+// huffman returns the code length of every symbol in seq, 0 for symbols
+// that do not occur.
+func huffman(seq []int, n int) []int {
+	freq := make([]int, n)
+	for _, s := range seq {
+		freq[s]++
+	}
+	h := &nodeHeap{}
+	for s, f := range freq {
+		if f > 0 {
+			heap.Push(h, node{f, s})
+		}
+	}
+	if h.Len() == 1 {
+		// A code needs two symbols: add one that is never used.
+		for s, f := range freq {
+			if f == 0 {
+				heap.Push(h, node{0, s})
+				break
+			}
+		}
+	}
+	parent := map[int]int{}
+	for next := n; h.Len() > 1; next++ {
+		a, b := heap.Pop(h).(node), heap.Pop(h).(node)
+		parent[a.id], parent[b.id] = next, next
+		heap.Push(h, node{a.freq + b.freq, next})
+	}
+	root := heap.Pop(h).(node).id
+	lengths := make([]int, n)
+	for s := range lengths {
+		if _, ok := parent[s]; ok {
+			for x := s; x != root; x = parent[x] {
+				lengths[s]++
+			}
+		}
+	}
+	return lengths
+}
+
+type node struct{ freq, id int }
+
+type nodeHeap []node
+
+func (h nodeHeap) Len() int { return len(h) }
+func (h nodeHeap) Less(i, j int) bool {
+	return h[i].freq < h[j].freq || h[i].freq == h[j].freq && h[i].id < h[j].id
+}
+func (h nodeHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h *nodeHeap) Push(x any)   { *h = append(*h, x.(node)) }
+func (h *nodeHeap) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go-chess-engine/config"
+	"go-chess-engine/engine"
+	"go-chess-engine/pgn"
+	"go-chess-engine/tune"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// tuneCommand implements "tune [flags] FILE...": it fits the evaluation
+// weights to the results of the positions in the files and writes them as
+// JSON, to be loaded with the eval_params setting. PGN files (".pgn") give
+// their quiet positions, any other file one position per line.
+func tuneCommand(fs *flag.FlagSet) func(config.Config, []string) int {
+	iterations := fs.Int("iterations", 1000, "number of gradient descent steps")
+	rate := fs.Float64("rate", 1, "largest change of a weight per step, in centipawns")
+	k := fs.Float64("k", 0, "sigmoid scale (0 = fit to the starting weights)")
+	skip := fs.Int("skip", 8, "plies at the start of each PGN game to leave out")
+	out := fs.String("out", "params.json", "file to write the weights to")
+	return func(cfg config.Config, args []string) int {
+		if len(args) == 0 {
+			fs.Usage()
+			return exitUsage
+		}
+		var samples []tune.Sample
+		for _, name := range args {
+			s, err := readSamples(name, *skip)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return exitFailure
+			}
+			samples = append(samples, s...)
+		}
+
+		params, err := evalParams(cfg)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitFailure
+		}
+		if params == nil {
+			params = engine.DefaultParams()
+		}
+		t, err := tune.New(params, samples)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitFailure
+		}
+		t.K = *k
+		if t.K == 0 {
+			t.FitK()
+		}
+		fmt.Printf("%d positions, K %.4f, loss %.6f\n", t.Len(), t.K, t.Loss())
+
+		for i := 1; i <= *iterations; i++ {
+			loss := t.Step(*rate)
+			if i%50 == 0 || i == *iterations {
+				fmt.Printf("Iteration %d: loss %.6f\n", i, loss)
+			}
+		}
+		fmt.Printf("Final loss %.6f\n", t.Loss())
+
+		f, err := os.Create(*out)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitFailure
+		}
+		err = t.Params().WriteJSON(f)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitFailure
+		}
+		fmt.Printf("Weights written to %s\n", *out)
+		return exitOK
+	}
+}
+
+// readSamples reads the positions of a PGN or sample file.
+func readSamples(name string, skip int) ([]tune.Sample, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if strings.EqualFold(filepath.Ext(name), ".pgn") {
+		games, err := pgn.Parse(f)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		return tune.FromGames(games, skip), nil
+	}
+	samples, err := tune.ReadSamples(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return samples, nil
+}
+
+// evalParams loads the evaluation weights of the eval_params setting, or
+// returns nil for the built-in ones.
+func evalParams(cfg config.Config) (*engine.Params, error) {
+	if cfg.EvalParams == "" {
+		return nil, nil
+	}
+	return engine.LoadParams(cfg.EvalParams)
+}
@@ -0,0 +1,120 @@
+// Package tune fits the weights of the hand-crafted evaluation to game
+// results with Texel's method: squashed by a sigmoid, the evaluation of a
+// quiet position should predict the result of the game it comes from, and
+// the weights are moved down the gradient of the mean squared error.
+package tune
+
+import (
+	"bufio"
+	"fmt"
+	"go-chess-engine/chess"
+	"go-chess-engine/engine"
+	"go-chess-engine/epd"
+	"go-chess-engine/pgn"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Sample is a position and the result of its game.
+type Sample struct {
+	FEN string
+	// Result is 1 if White won, 0.5 for a draw and 0 if Black won.
+	Result float64
+}
+
+// ReadSamples reads one position per line, skipping blank lines and "#"
+// comments. A line is one of
+//
+//	<FEN> | <score> | <result>          as written by datagen
+//	<FEN> [<result>]
+//	<EPD> c9 "<result>";               also as a "result" operation
+//
+// where the result is "1-0", "0-1" or "1/2-1/2", or 1, 0.5 or 0. A FEN may
+// leave out the clocks.
+func ReadSamples(r io.Reader) ([]Sample, error) {
+	var samples []Sample
+	sc := bufio.NewScanner(r)
+	for n := 1; sc.Scan(); n++ {
+		text := strings.TrimSpace(sc.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		s, err := parseSample(text)
+		if err != nil {
+			return nil, fmt.Errorf("tune: line %d: %w", n, err)
+		}
+		samples = append(samples, s)
+	}
+	return samples, sc.Err()
+}
+
+func parseSample(text string) (Sample, error) {
+	var fen, result string
+	if fields := strings.Split(text, "|"); len(fields) > 1 {
+		fen, result = fields[0], fields[len(fields)-1]
+	} else if open := strings.LastIndexByte(text, '['); open >= 0 && strings.HasSuffix(text, "]") {
+		fen, result = text[:open], text[open+1:len(text)-1]
+	} else {
+		p, err := epd.ParseLine(text)
+		if err != nil {
+			return Sample{}, err
+		}
+		fen, result = p.FEN, strings.Join(p.Ops["c9"], " ")
+		if result == "" {
+			result = strings.Join(p.Ops["result"], " ")
+		}
+	}
+
+	fields := strings.Fields(fen)
+	if len(fields) == 4 {
+		fields = append(fields, "0", "1")
+	}
+	fen = strings.Join(fields, " ")
+	if _, err := chess.ParseFEN(fen); err != nil {
+		return Sample{}, err
+	}
+	r, ok := parseResult(strings.TrimSpace(result))
+	if !ok {
+		return Sample{}, fmt.Errorf("bad or missing result %q", result)
+	}
+	return Sample{FEN: fen, Result: r}, nil
+}
+
+func parseResult(s string) (float64, bool) {
+	switch s {
+	case "1-0":
+		return 1, true
+	case "0-1":
+		return 0, true
+	case "1/2-1/2", "1/2":
+		return 0.5, true
+	}
+	r, err := strconv.ParseFloat(s, 64)
+	if err != nil || (r != 0 && r != 0.5 && r != 1) {
+		return 0, false
+	}
+	return r, true
+}
+
+// FromGames collects the quiet positions (see Engine.Quiet) of the main
+// lines of decided games, leaving out the first skip plies of each, which
+// are mostly opening theory.
+func FromGames(games []*pgn.Game, skip int) []Sample {
+	var samples []Sample
+	e := engine.New()
+	for _, g := range games {
+		result, ok := parseResult(g.Result)
+		if !ok {
+			continue
+		}
+		b := g.BoardAt(g.Root)
+		for ply, n := range g.MainLine() {
+			b.ApplyMove(n.Move)
+			if ply+1 >= skip && e.Quiet(b) {
+				samples = append(samples, Sample{FEN: b.ToFEN(), Result: result})
+			}
+		}
+	}
+	return samples
+}
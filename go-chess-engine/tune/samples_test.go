@@ -0,0 +1,63 @@
+package tune
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseSample(t *testing.T) {
+	const after = "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1"
+	tests := []struct {
+		line string
+		want Sample
+	}{
+		// As written by datagen.
+		{after + " | 35 | 1-0", Sample{after, 1}},
+		{after + " | -12 | 0.5", Sample{after, 0.5}},
+		{after + " | 0 | 0", Sample{after, 0}},
+		// A FEN and a bracketed result; the clocks may be left out.
+		{after + " [0-1]", Sample{after, 0}},
+		{"rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 [1/2-1/2]", Sample{after, 0.5}},
+		{"rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 [1]", Sample{after, 1}},
+		// EPD with the result in c9 or a result operation.
+		{`rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 c9 "1-0";`, Sample{after, 1}},
+		{`rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 id "x"; result "1/2";`, Sample{after, 0.5}},
+	}
+	for _, tt := range tests {
+		got, err := parseSample(tt.line)
+		if err != nil || got != tt.want {
+			t.Errorf("parseSample(%q) = %+v, %v, want %+v", tt.line, got, err, tt.want)
+		}
+	}
+
+	bad := []string{
+		after + " | 35 | 2",
+		after + " | 35 |",
+		after + " [draw]",
+		after + " [0.25]",
+		`rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 id "no result";`,
+		"8/8/8 w - - [1-0]",
+		"not a sample",
+	}
+	for _, line := range bad {
+		if s, err := parseSample(line); err == nil {
+			t.Errorf("parseSample(%q) = %+v, want an error", line, s)
+		}
+	}
+}
+
+func TestReadSamples(t *testing.T) {
+	samples, err := ReadSamples(strings.NewReader(`# Comments and blank lines are skipped.
+
+4k3/8/8/8/8/8/8/R3K3 w - - 0 1 [1-0]
+  4k3/8/8/8/8/8/8/r3K3 b - - 0 1 | 0 | 0-1
+`))
+	if err != nil || len(samples) != 2 || samples[0].Result != 1 || samples[1].Result != 0 {
+		t.Errorf("ReadSamples = %+v, %v", samples, err)
+	}
+
+	_, err = ReadSamples(strings.NewReader("4k3/8/8/8/8/8/8/R3K3 w - - 0 1 [1-0]\n\nbad line\n"))
+	if err == nil || !strings.Contains(err.Error(), "line 3") {
+		t.Errorf("ReadSamples error %v, want one on line 3", err)
+	}
+}
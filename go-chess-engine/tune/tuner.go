@@ -0,0 +1,175 @@
+package tune
+
+import (
+	"go-chess-engine/chess"
+	"go-chess-engine/engine"
+	"math"
+	"runtime"
+	"sort"
+	"sync"
+)
+
+// Tuner fits a set of weights to samples. The evaluation is linear in its
+// weights, so each position is reduced once to the coefficients of the
+// weights it uses and every later evaluation is a short dot product.
+type Tuner struct {
+	// K scales evaluations before the sigmoid: a score of 400/K centipawns
+	// predicts a 10 to 1 result.
+	K float64
+
+	params  *engine.Params
+	weights []*int
+	values  []float64 // The weights being fitted, not rounded
+	entries []entry
+
+	// Adam's running averages of the gradient and its square.
+	m, v []float64
+	step int
+}
+
+type entry struct {
+	terms  []term
+	result float64
+}
+
+type term struct {
+	weight int32 // Index into Tuner.values
+	coef   int32
+}
+
+// New prepares to tune a copy of params on samples.
+func New(params *engine.Params, samples []Sample) (*Tuner, error) {
+	p := *params
+	t := &Tuner{K: 1, params: &p, weights: p.Weights()}
+	index := make(map[*int]int32, len(t.weights))
+	for i, w := range t.weights {
+		index[w] = int32(i)
+		t.values = append(t.values, float64(*w))
+	}
+	t.m = make([]float64, len(t.values))
+	t.v = make([]float64, len(t.values))
+
+	for _, s := range samples {
+		pos, err := chess.ParseFEN(s.FEN)
+		if err != nil {
+			return nil, err
+		}
+		e := entry{result: s.Result}
+		for w, c := range t.params.Coefficients(chess.NewBitboardFromPosition(pos)) {
+			e.terms = append(e.terms, term{index[w], int32(c)})
+		}
+		// Sum in a fixed order, so runs give identical results.
+		sort.Slice(e.terms, func(i, j int) bool { return e.terms[i].weight < e.terms[j].weight })
+		t.entries = append(t.entries, e)
+	}
+	return t, nil
+}
+
+// Len returns the number of samples.
+func (t *Tuner) Len() int {
+	return len(t.entries)
+}
+
+// Params returns the fitted weights, rounded to centipawns.
+func (t *Tuner) Params() *engine.Params {
+	for i, w := range t.weights {
+		*w = int(math.Round(t.values[i]))
+	}
+	p := *t.params
+	return &p
+}
+
+// Loss returns the mean squared error of the predicted results.
+func (t *Tuner) Loss() float64 {
+	loss, _ := t.evaluate(false)
+	return loss
+}
+
+// FitK sets K to the value that minimizes the loss of the current weights.
+func (t *Tuner) FitK() {
+	lo, hi := 0.01, 10.0
+	for hi-lo > 1e-4 {
+		a, b := lo+(hi-lo)/3, hi-(hi-lo)/3
+		t.K = a
+		la := t.Loss()
+		t.K = b
+		if la < t.Loss() {
+			hi = b
+		} else {
+			lo = a
+		}
+	}
+	t.K = (lo + hi) / 2
+}
+
+// Step moves every weight once down the gradient of the loss with the Adam
+// method, by about rate centipawns at most, and returns the loss before the
+// step.
+func (t *Tuner) Step(rate float64) float64 {
+	const beta1, beta2, epsilon = 0.9, 0.999, 1e-8
+	loss, grad := t.evaluate(true)
+	t.step++
+	for i, g := range grad {
+		t.m[i] = beta1*t.m[i] + (1-beta1)*g
+		t.v[i] = beta2*t.v[i] + (1-beta2)*g*g
+		m := t.m[i] / (1 - math.Pow(beta1, float64(t.step)))
+		v := t.v[i] / (1 - math.Pow(beta2, float64(t.step)))
+		t.values[i] -= rate * m / (math.Sqrt(v) + epsilon)
+	}
+	return loss
+}
+
+// evaluate returns the loss and, if wanted, its gradient, spreading the
+// samples over every CPU.
+func (t *Tuner) evaluate(withGradient bool) (float64, []float64) {
+	workers := runtime.NumCPU()
+	losses := make([]float64, workers)
+	grads := make([][]float64, workers)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		if withGradient {
+			grads[w] = make([]float64, len(t.values))
+		}
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := w; i < len(t.entries); i += workers {
+				losses[w] += t.sample(&t.entries[i], grads[w])
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	n := float64(max(len(t.entries), 1))
+	loss := 0.0
+	var grad []float64
+	if withGradient {
+		grad = make([]float64, len(t.values))
+	}
+	for w := 0; w < workers; w++ {
+		loss += losses[w] / n
+		for i := range grad {
+			grad[i] += grads[w][i] / n
+		}
+	}
+	return loss, grad
+}
+
+// sample returns the squared error of one entry and adds its gradient to
+// grad, if not nil.
+func (t *Tuner) sample(e *entry, grad []float64) float64 {
+	score := 0.0
+	for _, tm := range e.terms {
+		score += float64(tm.coef) * t.values[tm.weight]
+	}
+	predicted := 1 / (1 + math.Pow(10, -t.K*score/400))
+	diff := predicted - e.result
+	if grad != nil {
+		// d/dscore of diff² with the sigmoid's derivative.
+		d := 2 * diff * predicted * (1 - predicted) * math.Ln10 * t.K / 400
+		for _, tm := range e.terms {
+			grad[tm.weight] += d * float64(tm.coef)
+		}
+	}
+	return diff * diff
+}
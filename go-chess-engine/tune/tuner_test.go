@@ -0,0 +1,85 @@
+package tune
+
+import (
+	"go-chess-engine/engine"
+	"math"
+	"testing"
+)
+
+var tuneSamples = []Sample{
+	{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", 0.5},
+	{"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1", 1},
+	{"8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1", 0.5},
+	{"r4rk1/1pp1qppp/p1np1n2/2b1p1B1/2B1P1b1/P1NP1N2/1PP1QPPP/R4RK1 w - - 0 10", 0},
+	{"6k1/5ppp/8/8/8/8/5PPP/3QR1K1 w - - 0 1", 1},
+	{"4k3/3p4/8/2P1p3/8/8/8/4K3 b - - 0 1", 1},
+	{"2kr3r/ppp2ppp/8/8/8/8/PP3PPP/2KR3R b - - 0 1", 0.5},
+}
+
+// TestGradient compares the gradient with finite differences of the loss.
+func TestGradient(t *testing.T) {
+	tuner, err := New(engine.DefaultParams(), tuneSamples)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tuner.K = 1.3
+	_, grad := tuner.evaluate(true)
+	const h = 0.01
+	checked := 0
+	for i := range tuner.values {
+		v := tuner.values[i]
+		tuner.values[i] = v + h
+		up := tuner.Loss()
+		tuner.values[i] = v - h
+		down := tuner.Loss()
+		tuner.values[i] = v
+		want := (up - down) / (2 * h)
+		if math.Abs(grad[i]-want) > 1e-6*math.Max(1e-3, math.Abs(want)) {
+			t.Errorf("weight %d: gradient %g, finite difference %g", i, grad[i], want)
+		}
+		if want != 0 {
+			checked++
+		}
+	}
+	if checked == 0 {
+		t.Error("no weight counts")
+	}
+}
+
+// TestFitK fits K to scores whose predicted results are known.
+func TestFitK(t *testing.T) {
+	const k = 1.7
+	tuner := &Tuner{K: 1, values: []float64{1}}
+	for score := -600; score <= 600; score += 50 {
+		tuner.entries = append(tuner.entries, entry{
+			terms:  []term{{weight: 0, coef: int32(score)}},
+			result: 1 / (1 + math.Pow(10, -k*float64(score)/400)),
+		})
+	}
+	tuner.FitK()
+	if math.Abs(tuner.K-k) > 1e-3 {
+		t.Errorf("FitK found %.4f, want %.4f", tuner.K, k)
+	}
+	if loss := tuner.Loss(); loss > 1e-9 {
+		t.Errorf("loss %g at K %.4f", loss, tuner.K)
+	}
+}
+
+// TestStep checks that steps lower the loss and Params rounds the result.
+func TestStep(t *testing.T) {
+	tuner, err := New(engine.DefaultParams(), tuneSamples)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := tuner.Step(1)
+	var last float64
+	for i := 0; i < 20; i++ {
+		last = tuner.Step(1)
+	}
+	if last >= first {
+		t.Errorf("loss %g after 20 steps, %g at first", last, first)
+	}
+	if p := tuner.Params(); *p == *engine.DefaultParams() {
+		t.Error("Params unchanged after tuning")
+	}
+}
@@ -2,32 +2,108 @@ package uci
 
 import (
 	"bufio"
+	"context"
 	"fmt"
+	"go-chess-engine/book"
 	"go-chess-engine/chess"
+	"go-chess-engine/config"
 	"go-chess-engine/engine"
+	"go-chess-engine/game"
 	"go-chess-engine/logging"
-	"os"
+	"go-chess-engine/nnue"
+	"go-chess-engine/syzygy"
+	"io"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
 type Handler struct {
-	board  chess.Board // This is now the INTERFACE, not a concrete type
+	// game is the game the GUI has set up, from its start position through
+	// the moves of the last position command.
+	game   *game.Game
 	engine *engine.Engine
+	// representation is the board implementation every position uses.
+	representation string
+
+	ownBook  bool
+	bookFile string
+	// chess960 is the UCI_Chess960 option: castling is sent and received
+	// as the king capturing its own rook.
+	chess960 bool
+	// startFEN and moves are the last position command's start position
+	// and the moves of it that were played, to spot a command that only
+	// adds moves to the game.
+	startFEN string
+	moves    []string
+
+	ctx context.Context
+	in  io.Reader
+	// out is shared by the command loop and the search goroutine, so every
+	// write holds outMu.
+	out   io.Writer
+	outMu sync.Mutex
+
+	// stopSearch cancels the running search and searchDone is closed when
+	// it has sent its bestmove. Both are nil while no search runs.
+	stopSearch context.CancelFunc
+	searchDone chan struct{}
+	// infinite is set while a "go infinite" search runs, which sends its
+	// bestmove only once stopped.
+	infinite bool
 }
 
-func NewHandler() *Handler {
-	return &Handler{
-		// Use the factory to create the board from the starting position
-		board:  chess.NewBoardFromConfig(chess.StartFEN),
-		engine: engine.New(),
+// NewHandler creates a handler that reads commands from in and writes
+// responses to out, using the board representation and book settings of
+// cfg. Cancelling ctx stops a running search and ends Loop after the next
+// command. It fails if cfg names an unknown board representation.
+func NewHandler(ctx context.Context, cfg config.Config, in io.Reader, out io.Writer) (*Handler, error) {
+	// Use the factory to create the board from the starting position
+	board, err := chess.NewBoard(cfg.BoardRepresentation, chess.StartFEN)
+	if err != nil {
+		return nil, err
+	}
+	h := &Handler{
+		game:           game.New(board),
+		engine:         engine.New(),
+		representation: cfg.BoardRepresentation,
+		ownBook:        cfg.OwnBook,
+		bookFile:       cfg.BookFile,
+		ctx:            ctx,
+		in:             in,
+		out:            out,
+	}
+	h.engine.OnInfo = h.sendInfo
+	h.loadBook()
+	if cfg.EvalParams != "" {
+		params, err := engine.LoadParams(cfg.EvalParams)
+		if err != nil {
+			logging.Log.Error("could not load evaluation weights", "file", cfg.EvalParams, "err", err)
+		} else {
+			h.engine.Params = params
+		}
 	}
+	return h, nil
 }
 
-func (h *Handler) Loop() {
-	scanner := bufio.NewScanner(os.Stdin)
+// Loop processes commands until "quit", the end of the input or the
+// handler's context is done. A search started by "go" runs in the
+// background until it finishes or "stop" arrives; "go infinite" waits for
+// "stop", or the end of the input, even once its search is done. Other
+// commands that change the engine wait for the search. Loop returns the
+// error reading the input, if any.
+func (h *Handler) Loop() error {
+	defer h.waitSearch()
+	scanner := bufio.NewScanner(h.in)
 	for scanner.Scan() {
+		if h.ctx.Err() != nil {
+			h.handleStop()
+			return nil
+		}
 		command := scanner.Text()
-		logging.Log.Printf("Received: %s", command)
+		logging.Log.Debug("uci", "direction", "in", "line", command)
 
 		fields := strings.Fields(command)
 		if len(fields) == 0 {
@@ -39,21 +115,47 @@ func (h *Handler) Loop() {
 			h.handleUci()
 		case "isready":
 			h.handleIsReady()
+		case "setoption":
+			h.waitSearch()
+			h.handleSetOption(fields)
 		case "ucinewgame":
+			h.waitSearch()
 			h.handleUciNewGame()
 		case "position":
+			h.waitSearch()
 			h.handlePosition(fields)
 		case "go":
-			h.handleGo()
+			h.waitSearch()
+			h.handleGo(fields)
+		case "d":
+			h.waitSearch()
+			h.handleDisplay(fields)
+		case "eval":
+			h.waitSearch()
+			h.handleEval()
+		case "stop":
+			h.handleStop()
 		case "quit":
-			return
+			h.handleStop()
+			return nil
 		}
 	}
+	// No stop can arrive any more to end an infinite search.
+	if h.infinite {
+		h.handleStop()
+	}
+	return scanner.Err()
 }
 
 func (h *Handler) handleUci() {
 	h.sendResponse("id name GoNativeRefactored")
 	h.sendResponse("id author Go Developer")
+	h.sendResponse("option name MultiPV type spin default 1 min 1 max 256")
+	h.sendResponse(fmt.Sprintf("option name OwnBook type check default %t", h.ownBook))
+	h.sendResponse(fmt.Sprintf("option name BookFile type string default %s", uciString(h.bookFile)))
+	h.sendResponse("option name SyzygyPath type string default <empty>")
+	h.sendResponse("option name EvalFile type string default <empty>")
+	h.sendResponse("option name UCI_Chess960 type check default false")
 	h.sendResponse("uciok")
 }
 
@@ -61,11 +163,131 @@ func (h *Handler) handleIsReady() {
 	h.sendResponse("readyok")
 }
 
+// handleSetOption parses "setoption name <id> [value <x>]".
+func (h *Handler) handleSetOption(fields []string) {
+	var name, value []string
+	target := &name
+	for _, field := range fields[1:] {
+		switch field {
+		case "name":
+			target = &name
+		case "value":
+			target = &value
+		default:
+			*target = append(*target, field)
+		}
+	}
+
+	switch strings.ToLower(strings.Join(name, " ")) {
+	case "multipv":
+		n, err := strconv.Atoi(strings.Join(value, " "))
+		if err != nil || n < 1 {
+			logging.Log.Warn("ignoring invalid MultiPV value", "value", strings.Join(value, " "))
+			return
+		}
+		h.engine.MultiPV = n
+	case "ownbook":
+		h.ownBook = strings.Join(value, " ") == "true"
+		h.loadBook()
+	case "bookfile":
+		h.bookFile = strings.Join(value, " ")
+		if h.bookFile == "<empty>" {
+			h.bookFile = ""
+		}
+		h.loadBook()
+	case "syzygypath":
+		h.loadTablebase(strings.Join(value, " "))
+	case "evalfile":
+		h.loadNetwork(strings.Join(value, " "))
+	case "uci_chess960":
+		h.chess960 = strings.Join(value, " ") == "true"
+		h.startFEN, h.moves = "", nil // The next position starts a new game
+	default:
+		logging.Log.Warn("ignoring unknown option", "name", strings.Join(name, " "))
+	}
+}
+
+// loadBook (re)opens the opening book according to the OwnBook and BookFile
+// options. A book that cannot be read is logged and the engine plays without one.
+func (h *Handler) loadBook() {
+	h.engine.Book = nil
+	if !h.ownBook || h.bookFile == "" {
+		return
+	}
+	bk, err := book.Open(h.bookFile)
+	if err != nil {
+		logging.Log.Error("could not load book", "file", h.bookFile, "err", err)
+		return
+	}
+	logging.Log.Info("loaded book", "file", h.bookFile, "entries", bk.Len())
+	h.engine.Book = bk
+}
+
+// loadTablebase opens the Syzygy tables found in path ("<empty>" disables them).
+func (h *Handler) loadTablebase(path string) {
+	h.engine.Tablebase = nil
+	if path == "" || path == "<empty>" {
+		return
+	}
+	tables, err := syzygy.Open(path)
+	if err != nil {
+		logging.Log.Error("could not load tablebases", "path", path, "err", err)
+		return
+	}
+	logging.Log.Info("loaded tablebases", "path", path, "pieces", tables.MaxPieces())
+	h.engine.Tablebase = tables
+}
+
+// loadNetwork opens the evaluation network in file ("<empty>" returns to
+// the hand-crafted evaluation). A network that cannot be read is logged and
+// the hand-crafted evaluation is used.
+func (h *Handler) loadNetwork(file string) {
+	h.engine.Net = nil
+	if file == "" || file == "<empty>" {
+		return
+	}
+	net, err := nnue.Open(file)
+	if err != nil {
+		logging.Log.Error("could not load network", "file", file, "err", err)
+		return
+	}
+	logging.Log.Info("loaded network", "file", file, "hidden", net.Hidden())
+	h.engine.Net = net
+}
+
+// uciString formats a string option value, using "<empty>" for "".
+func uciString(s string) string {
+	if s == "" {
+		return "<empty>"
+	}
+	return s
+}
+
 func (h *Handler) handleUciNewGame() {
 	// Re-create the board from the starting position
-	h.board = chess.NewBoardFromConfig(chess.StartFEN)
+	b, err := h.newBoard(chess.StartFEN)
+	if err != nil {
+		logging.Log.Error("could not set up a new game", "err", err)
+		return
+	}
+	h.game = game.New(b)
+	h.startFEN, h.moves = "", nil
+}
+
+// newBoard creates a board for fen, in Chess960 mode if UCI_Chess960 is set.
+func (h *Handler) newBoard(fen string) (chess.Board, error) {
+	p, err := chess.ParseFEN(fen)
+	if err != nil {
+		return nil, err
+	}
+	p.Chess960 = p.Chess960 || h.chess960
+	return chess.NewBoardFromPosition(h.representation, p)
 }
 
+// handlePosition sets up "position [startpos | fen FEN] [moves ...]". A
+// command that repeats the previous one with more moves, as GUIs send
+// during a game, only plays the new moves. Parsing stops at an illegal move;
+// a bad FEN leaves the previous position in place.
 func (h *Handler) handlePosition(fields []string) {
 	var movesIndex = -1
 	var fen = chess.StartFEN
@@ -83,28 +305,208 @@ func (h *Handler) handlePosition(fields []string) {
 		}
 		fen = strings.TrimSpace(fenStr)
 	}
+	var moves []string
+	if movesIndex != -1 && movesIndex+1 < len(fields) {
+		moves = fields[movesIndex+1:]
+	}
+
+	if fen == h.startFEN && len(moves) >= len(h.moves) && slices.Equal(moves[:len(h.moves)], h.moves) {
+		moves = moves[len(h.moves):]
+	} else {
+		// Create the new game from the specified FEN
+		b, err := h.newBoard(fen)
+		if err != nil {
+			logging.Log.Warn("ignoring the position command", "err", err)
+			return
+		}
+		h.game = game.New(b)
+		h.startFEN, h.moves = fen, nil
+	}
+	for _, s := range moves {
+		err := fmt.Errorf("bad move %q", s)
+		if len(s) == 4 || len(s) == 5 {
+			err = h.game.Play(chess.ParseMove(s))
+		}
+		if err != nil {
+			logging.Log.Warn("ignoring the rest of the position command", "err", err)
+			break
+		}
+		h.moves = append(h.moves, s)
+	}
+}
 
-	// Create the new board from the specified FEN
-	h.board = chess.NewBoardFromConfig(fen)
+// board returns the current position.
+func (h *Handler) board() chess.Board {
+	return h.game.Board()
+}
 
-	// Apply moves if they are provided
-	if movesIndex != -1 && movesIndex+1 < len(fields) {
-		for i := movesIndex + 1; i < len(fields); i++ {
-			move := chess.ParseMove(fields[i])
-			h.board.ApplyMove(move)
+// handleDisplay implements the "d" debugging command: it prints the current
+// position as a diagram ("d unicode" uses chess symbols), followed by its
+// FEN, Polyglot key, checkers and side to move.
+func (h *Handler) handleDisplay(fields []string) {
+	unicode := len(fields) > 1 && fields[1] == "unicode"
+	for _, line := range strings.Split(strings.TrimSuffix(chess.Diagram(h.board(), unicode), "\n"), "\n") {
+		h.sendResponse(line)
+	}
+	h.sendResponse("")
+	h.sendResponse("Fen: " + h.board().ToFEN())
+	h.sendResponse(fmt.Sprintf("Key: %016X", book.Key(h.board())))
+	var checkers []string
+	for _, sq := range chess.Checkers(h.board()) {
+		checkers = append(checkers, chess.SquareName(sq))
+	}
+	h.sendResponse("Checkers: " + strings.Join(checkers, " "))
+	side := "white"
+	if h.board().SideToMove() == chess.Black {
+		side = "black"
+	}
+	h.sendResponse("Side to move: " + side)
+}
+
+// handleEval implements the "eval" debugging command: it prints every term
+// of the static evaluation of the current position for each side, in
+// pawns, and the network's evaluation if one is loaded. All scores are
+// from White's side.
+func (h *Handler) handleEval() {
+	params := h.engine.Params
+	if params == nil {
+		params = engine.DefaultParams()
+	}
+	h.sendResponse("          Term |  White |  Black |  Total")
+	h.sendResponse("---------------+--------+--------+-------")
+	var white, black int
+	for _, t := range params.Trace(h.board()) {
+		h.sendResponse(fmt.Sprintf("%14s | %6.2f | %6.2f | %6.2f", t.Name, pawns(t.White), pawns(t.Black), pawns(t.White-t.Black)))
+		white, black = white+t.White, black+t.Black
+	}
+	h.sendResponse("---------------+--------+--------+-------")
+	h.sendResponse(fmt.Sprintf("%14s | %6.2f | %6.2f | %6.2f", "Total", pawns(white), pawns(black), pawns(white-black)))
+	h.sendResponse("")
+	h.sendResponse(fmt.Sprintf("Classical evaluation: %+.2f", pawns(white-black)))
+
+	if net := h.engine.Net; net != nil {
+		var acc nnue.Accumulator
+		net.Refresh(&acc, h.board())
+		score := net.Evaluate(&acc, chess.White)
+		h.sendResponse(fmt.Sprintf("NNUE evaluation: %+.2f", pawns(score)))
+	}
+}
+
+// pawns converts centipawns to pawns.
+func pawns(cp int) float64 {
+	return float64(cp) / 100
+}
+
+// handleGo starts searching the current position in the background.
+// "depth", "nodes" and "movetime" limit this search instead of the
+// engine's settings; with a node or time limit alone the depth is not
+// limited. "go mate N" looks for a mate in at most N moves and stops as soon as one
+// is proven; without one it plays the move of a normal search. "go infinite"
+// searches until "stop".
+func (h *Handler) handleGo(fields []string) {
+	var limits engine.Limits
+	for i := 1; i+1 < len(fields); i++ {
+		var limit *int
+		switch fields[i] {
+		case "depth":
+			limit = &limits.Depth
+		case "nodes":
+			limit = &limits.Nodes
+		case "mate":
+			limit = &limits.Mate
+		case "movetime":
+			ms, err := strconv.Atoi(fields[i+1])
+			if err != nil || ms < 1 {
+				logging.Log.Warn("ignoring invalid go movetime", "value", fields[i+1])
+				continue
+			}
+			limits.MoveTime = time.Duration(ms) * time.Millisecond
+			continue
+		default:
+			continue
+		}
+		n, err := strconv.Atoi(fields[i+1])
+		if err != nil || n < 1 {
+			logging.Log.Warn("ignoring invalid go "+fields[i], "value", fields[i+1])
+			continue
 		}
+		*limit = n
+	}
+	infinite := slices.Contains(fields, "infinite")
+	if infinite {
+		limits = engine.Limits{Depth: 64}
+	}
+	if limits.Depth == 0 && (limits.Nodes > 0 || limits.MoveTime > 0) {
+		limits.Depth = 64 // Limited by nodes or time only
 	}
+
+	ctx, cancel := context.WithCancel(h.ctx)
+	done := make(chan struct{})
+	h.stopSearch, h.searchDone, h.infinite = cancel, done, infinite
+
+	// The search works on its own copy, the board may be replaced meanwhile.
+	board := h.board().Clone()
+	go func() {
+		defer close(done)
+		defer cancel()
+		// The engine needs to receive the board interface
+		result := h.engine.Search(ctx, board, limits)
+		if infinite {
+			<-ctx.Done() // The search may finish first, but the GUI waits for stop
+		}
+		if limits.Mate > 0 && !engine.IsMateScore(result.Score) {
+			logging.Log.Info("no mate found", "moves", limits.Mate)
+		}
+		if len(board.GenerateLegalMoves()) == 0 {
+			h.sendResponse("bestmove 0000") // Checkmate or stalemate
+			return
+		}
+		logging.Log.Info("best move", "san", chess.FormatSAN(board, result.BestMove))
+		response := "bestmove " + chess.FormatMove(result.BestMove)
+		if len(result.PV) > 1 {
+			response += " ponder " + chess.FormatMove(result.Ponder)
+		}
+		h.sendResponse(response)
+	}()
+}
+
+// handleStop ends the running search, which still sends its bestmove.
+func (h *Handler) handleStop() {
+	if h.stopSearch != nil {
+		h.stopSearch()
+	}
+	h.waitSearch()
 }
 
-func (h *Handler) handleGo() {
-	// The engine needs to receive the board interface
-	bestMove := h.engine.FindBestMove(h.board)
-	h.sendResponse(fmt.Sprintf("bestmove %s", chess.FormatMove(bestMove)))
+// waitSearch blocks until the running search, if any, has finished.
+func (h *Handler) waitSearch() {
+	if h.searchDone != nil {
+		<-h.searchDone
+		h.stopSearch, h.searchDone, h.infinite = nil, nil, false
+	}
+}
+
+// sendInfo reports one search line as "info depth .. multipv .. score .. pv ..".
+func (h *Handler) sendInfo(info engine.Info) {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "info depth %d multipv %d", info.Depth, info.MultiPV)
+	if engine.IsMateScore(info.Score) {
+		fmt.Fprintf(&sb, " score mate %d", engine.MateIn(info.Score))
+	} else {
+		fmt.Fprintf(&sb, " score cp %d", info.Score)
+	}
+	fmt.Fprintf(&sb, " nodes %d time %d pv", info.Nodes, info.Time.Milliseconds())
+	for _, m := range info.PV {
+		sb.WriteString(" " + chess.FormatMove(m))
+	}
+	h.sendResponse(sb.String())
 }
 
-// This is the corrected function signature.
-// It now correctly has the (h *Handler) receiver.
+// sendResponse writes one line to the GUI. It is safe to call from the
+// search goroutine while the command loop is writing too.
 func (h *Handler) sendResponse(msg string) {
-	logging.Log.Printf("Sending: %s", msg)
-	fmt.Println(msg)
+	h.outMu.Lock()
+	defer h.outMu.Unlock()
+	logging.Log.Debug("uci", "direction", "out", "line", msg)
+	fmt.Fprintln(h.out, msg)
 }
@@ -0,0 +1,254 @@
+package uci
+
+import (
+	"bytes"
+	"context"
+	"go-chess-engine/config"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// session runs the commands in input on a new handler and returns its
+// output lines.
+func session(t *testing.T, input string) []string {
+	t.Helper()
+	var out bytes.Buffer
+	h, err := NewHandler(context.Background(), config.Default(), strings.NewReader(input), &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Loop(); err != nil {
+		t.Fatal(err)
+	}
+	return strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+}
+
+// find returns the first line with the prefix, or "".
+func find(lines []string, prefix string) string {
+	for _, l := range lines {
+		if strings.HasPrefix(l, prefix) {
+			return l
+		}
+	}
+	return ""
+}
+
+func TestUciIsReady(t *testing.T) {
+	lines := session(t, "uci\nisready\nquit\n")
+	if !strings.HasPrefix(lines[0], "id name ") || find(lines, "option name MultiPV") == "" {
+		t.Errorf("no id or options in %q", lines)
+	}
+	if n := len(lines); n < 2 || lines[n-2] != "uciok" || lines[n-1] != "readyok" {
+		t.Errorf("output %q, want uciok then readyok at the end", lines)
+	}
+}
+
+func TestPosition(t *testing.T) {
+	tests := []struct{ input, fen string }{
+		{"position startpos moves e2e4 e7e5", "rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR w KQkq e6 0 2"},
+		// A GUI repeats the game so far with one more move.
+		{"position startpos moves e2e4\nposition startpos moves e2e4 c7c5\nposition startpos moves e2e4 c7c5 g1f3",
+			"rnbqkbnr/pp1ppppp/8/2p5/4P3/5N2/PPPP1PPP/RNBQKB1R b KQkq - 1 2"},
+		// The game takes back the last move and plays another.
+		{"position startpos moves e2e4 c7c5\nposition startpos moves e2e4 e7e5",
+			"rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR w KQkq e6 0 2"},
+		{"position fen 4k3/8/8/8/8/8/8/4K2R w K - 0 1 moves e1g1 e8d7",
+			"8/3k4/8/8/8/8/8/5RK1 w - - 2 2"},
+		// Parsing stops at the first illegal move.
+		{"position startpos moves e2e4 e2e4 d7d5", "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1"},
+		{"position startpos\nucinewgame", "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"},
+	}
+	for _, tt := range tests {
+		lines := session(t, tt.input+"\nd\nquit\n")
+		if got := find(lines, "Fen: "); got != "Fen: "+tt.fen {
+			t.Errorf("%q: %s, want %s", tt.input, got, tt.fen)
+		}
+	}
+}
+
+// TestMalformedPosition checks that bad position commands are ignored
+// and the handler keeps answering.
+func TestMalformedPosition(t *testing.T) {
+	lines := session(t, `position startpos moves e2e4
+position fen 8/8/8 w
+position fen 8/8/8/8/8/8/8/K6k x - - 0 1
+position fen 4k3/8/8/8/8/8/8/4K3 w Q - 0 1
+position fen 8/8/8/8/8/8/8/K7 w - - 0 1
+position fen 4k3/8/8/8/8/8/8/K3K3 w - - 0 1
+d
+position startpos moves e7e5 xyz
+isready
+d
+go depth 1
+quit
+`)
+	var fens []string
+	for _, l := range lines {
+		if strings.HasPrefix(l, "Fen: ") {
+			fens = append(fens, strings.TrimPrefix(l, "Fen: "))
+		}
+	}
+	want := []string{
+		"rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1",
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+	}
+	if !slices.Equal(fens, want) {
+		t.Errorf("positions %q, want %q", fens, want)
+	}
+	if find(lines, "readyok") == "" {
+		t.Errorf("no readyok in %q", lines)
+	}
+	if find(lines, "bestmove ") == "" {
+		t.Errorf("no bestmove in %q", lines)
+	}
+}
+
+func TestGo(t *testing.T) {
+	tests := []struct{ input, bestmove string }{
+		{"position fen 6k1/5ppp/8/8/8/8/8/R5K1 w - - 0 1\ngo depth 2", "bestmove a1a8"},
+		{"position fen 6k1/5ppp/8/8/8/8/8/R5K1 w - - 0 1\ngo nodes 2000", "bestmove a1a8"},
+		{"position fen 6k1/5ppp/8/8/8/8/8/R5K1 w - - 0 1\ngo mate 1", "bestmove a1a8"},
+		{"position startpos\ngo depth 1", "bestmove "},
+		{"position startpos\ngo nodes 100", "bestmove "},
+		// Checkmated: there is no move to play.
+		{"position fen R5k1/5ppp/8/8/8/8/8/6K1 b - - 0 1\ngo depth 2", "bestmove 0000"},
+	}
+	for _, tt := range tests {
+		lines := session(t, tt.input+"\nquit\n")
+		if got := find(lines, "bestmove"); !strings.HasPrefix(got, tt.bestmove) {
+			t.Errorf("%q: %q, want %q", tt.input, got, tt.bestmove)
+		}
+		if strings.Count(strings.Join(lines, "\n"), "bestmove") != 1 {
+			t.Errorf("%q: not one bestmove in %q", tt.input, lines)
+		}
+	}
+
+	lines := session(t, "position startpos\ngo depth 2\nquit\n")
+	if find(lines, "info depth 1 multipv 1 score cp ") == "" || find(lines, "info depth 2 ") == "" {
+		t.Errorf("no info lines for depths 1 and 2 in %q", lines)
+	}
+}
+
+// syncBuffer is a bytes.Buffer the test can read while the search writes.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestStop(t *testing.T) {
+	in, w := io.Pipe()
+	var out syncBuffer
+	h, err := NewHandler(context.Background(), config.Default(), in, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error)
+	go func() { done <- h.Loop() }()
+
+	io.WriteString(w, "position startpos\ngo infinite\n")
+	waitFor(t, &out, "info depth ")
+	if strings.Contains(out.String(), "bestmove") {
+		t.Fatalf("go infinite ended without stop:\n%s", out.String())
+	}
+	io.WriteString(w, "stop\nisready\n")
+	io.WriteString(w, "quit\n")
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+
+	// The bestmove comes before the answer to the next command.
+	s := out.String()
+	if i, j := strings.Index(s, "bestmove "), strings.Index(s, "readyok"); i < 0 || j < i {
+		t.Errorf("no bestmove before readyok:\n%s", s)
+	}
+}
+
+// TestInfinite checks that go infinite holds back its bestmove until stop
+// even when the search ends by itself, as it does without legal moves.
+func TestInfinite(t *testing.T) {
+	in, w := io.Pipe()
+	var out syncBuffer
+	h, err := NewHandler(context.Background(), config.Default(), in, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error)
+	go func() { done <- h.Loop() }()
+
+	io.WriteString(w, "position fen R5k1/5ppp/8/8/8/8/8/6K1 b - - 0 1\ngo infinite\nisready\n")
+	waitFor(t, &out, "readyok")
+	if strings.Contains(out.String(), "bestmove") {
+		t.Fatalf("go infinite ended without stop:\n%s", out.String())
+	}
+	io.WriteString(w, "stop\n")
+	waitFor(t, &out, "bestmove 0000")
+	w.Close()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	// The end of the input stops it too.
+	lines := session(t, "position startpos\ngo infinite\n")
+	if find(lines, "bestmove ") == "" {
+		t.Errorf("no bestmove in %q", lines)
+	}
+}
+
+// waitFor waits until the output holds s.
+func waitFor(t *testing.T, out *syncBuffer, s string) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for !strings.Contains(out.String(), s) {
+		if time.Now().After(deadline) {
+			t.Fatalf("no %q in:\n%s", s, out.String())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestQuit(t *testing.T) {
+	// Quitting stops the search and ignores the rest of the input.
+	lines := session(t, "position startpos\ngo infinite\nquit\nisready\n")
+	if find(lines, "bestmove ") == "" || find(lines, "readyok") != "" {
+		t.Errorf("output %q, want a bestmove and no readyok", lines)
+	}
+
+	// The end of the input ends the session too, after the search.
+	lines = session(t, "position startpos\ngo depth 2\n")
+	if find(lines, "bestmove ") == "" {
+		t.Errorf("no bestmove in %q", lines)
+	}
+
+	// So does the handler's context.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var out bytes.Buffer
+	h, err := NewHandler(ctx, config.Default(), strings.NewReader("isready\nisready\n"), &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Loop(); err != nil || out.Len() != 0 {
+		t.Errorf("Loop with a cancelled context: %v, output %q", err, out.String())
+	}
+
+	if _, err := NewHandler(context.Background(), config.Config{BoardRepresentation: "list"}, strings.NewReader(""), &out); err == nil {
+		t.Error("NewHandler accepted an unknown board representation")
+	}
+}